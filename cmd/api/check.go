@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+	"github.com/rubenclaes/pulsar-api/internal/secrets"
+)
+
+func newConfigCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Werken met de configuratie",
+	}
+	cmd.AddCommand(newConfigCheckCmd(opts))
+	return cmd
+}
+
+func newConfigCheckCmd(opts *globalOptions) *cobra.Command {
+	var (
+		offline bool
+		timeout time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Valideer de config en test de verbinding met Pulsar en de secrets provider",
+		Long: `Leest de config zoals serve dat doet (bestand, profiel, remote, env), valideert
+ze, compileert de JSON schema's en test de verbinding met de secrets provider
+en Pulsar. Geeft een rapport en eindigt met een fout als er iets faalt, zodat
+een CI/CD pipeline een deploy kan tegenhouden.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.Flags().BoolVar(&offline, "offline", false, "geen verbinding maken met Pulsar of de secrets provider")
+	cmd.Flags().DurationVar(&timeout, "timeout", 10*time.Second, "timeout per verbindingstest")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		c := &checker{out: cmd.OutOrStdout()}
+		c.run(opts, cmd, offline, timeout)
+		if c.failed > 0 {
+			return fmt.Errorf("config check failed: %d check(s) failed", c.failed)
+		}
+		fmt.Fprintln(c.out, "config ok")
+		return nil
+	}
+	return cmd
+}
+
+// checker schrijft één regel per check naar out en telt de fouten.
+type checker struct {
+	out    io.Writer
+	failed int
+}
+
+func (c *checker) ok(name, format string, args ...interface{}) {
+	fmt.Fprintf(c.out, "ok    %-8s %s\n", name, fmt.Sprintf(format, args...))
+}
+
+func (c *checker) skip(name, reason string) {
+	fmt.Fprintf(c.out, "skip  %-8s %s\n", name, reason)
+}
+
+func (c *checker) fail(name string, err error) {
+	c.failed++
+	for _, p := range config.Problems(err) {
+		fmt.Fprintf(c.out, "FAIL  %-8s %s\n", name, strings.ReplaceAll(p, "\n", "; "))
+	}
+}
+
+func (c *checker) run(opts *globalOptions, cmd *cobra.Command, offline bool, timeout time.Duration) {
+	v, cfg, err := loadConfig(opts, cmd, nil)
+	if err != nil {
+		// zonder geldige config hebben de andere checks geen zin
+		c.fail("config", err)
+		return
+	}
+	file := v.ConfigFileUsed()
+	if file == "" {
+		file = "(geen bestand, enkel env en defaults)"
+	}
+	if opts.env != "" {
+		file += ", profiel " + opts.env
+	}
+	c.ok("config", "%s", file)
+
+	schemas, err := schema.Load(cfg.SchemaDir, cfg.Schemas, nil)
+	if err != nil {
+		c.fail("schemas", err)
+	} else {
+		c.ok("schemas", "%d eventType(s) %v", len(schemas.EventTypes()), schemas.EventTypes())
+	}
+
+	if tlsFiles := cfg.API.TLS; tlsFiles.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(tlsFiles.CertFile, tlsFiles.KeyFile); err != nil {
+			c.fail("tls", err)
+		} else {
+			c.ok("tls", "%s", tlsFiles.CertFile)
+		}
+	}
+
+	if offline {
+		c.skip("secrets", "--offline")
+		c.skip("registry", "--offline")
+		c.skip("pulsar", "--offline")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	source, err := newSecretSource(ctx, cfg, zap.NewNop())
+	if err != nil {
+		c.fail("secrets", err)
+		return
+	}
+	resolver := secrets.NewResolver(source)
+	if source == nil {
+		c.skip("secrets", "geen secrets.provider")
+	} else if err := cfg.ResolveSecrets(ctx, resolver); err != nil {
+		c.fail("secrets", err)
+	} else if cfg.API.TLS.Secret != "" {
+		if _, err := resolver.Read(ctx, cfg.API.TLS.Secret); err != nil {
+			c.fail("secrets", fmt.Errorf("api.tls.secret: %w", err))
+		} else {
+			c.ok("secrets", "%s", cfg.Secrets.Provider)
+		}
+	} else {
+		c.ok("secrets", "%s", cfg.Secrets.Provider)
+	}
+
+	if external, err := newExternalSchemas(cfg); err != nil {
+		c.fail("registry", err)
+	} else if external == nil {
+		c.skip("registry", "geen registry.provider")
+	} else if _, err := loadSchemas(ctx, external, cfg); err != nil {
+		c.fail("registry", err)
+	} else {
+		c.ok("registry", "%s, %d subject(s)", cfg.Registry.URL, len(cfg.Registry.Subjects))
+	}
+
+	topics := checkTopics(cfg)
+	if len(topics) == 0 {
+		c.skip("pulsar", "api.dryRun zonder audit topic")
+		return
+	}
+	for _, cluster := range cfg.Clusters() {
+		name := "pulsar"
+		if cluster != pulsar.DefaultCluster {
+			name += "." + cluster
+		}
+		if len(topics[cluster]) == 0 {
+			c.skip(name, "geen routes op deze cluster")
+			continue
+		}
+		brokerURL, authToken := cfg.ClusterURL(cluster)
+		var token func() (string, error)
+		if authToken != "" {
+			token = resolver.Supplier(ctx, authToken)
+		}
+		if err := pulsar.Ping(brokerURL, token, topics[cluster], timeout); err != nil {
+			c.fail(name, fmt.Errorf("%s: %w", brokerURL, err))
+		} else {
+			c.ok(name, "%s, %d topic(s)", brokerURL, len(topics[cluster]))
+		}
+	}
+}
+
+// checkTopics geeft per cluster de topics waar serve naar zou schrijven. In
+// een topic met {tenant} komt tenants.default; zonder default wordt hij
+// overgeslagen.
+func checkTopics(cfg *config.Config) map[string][]string {
+	sets := map[string]map[string]bool{}
+	add := func(cluster, topic string) {
+		if cluster == "" {
+			cluster = pulsar.DefaultCluster
+		}
+		if strings.Contains(topic, "{tenant}") {
+			if cfg.Tenants.Default == "" {
+				return
+			}
+			topic = strings.ReplaceAll(topic, "{tenant}", cfg.Tenants.Default)
+		}
+		if sets[cluster] == nil {
+			sets[cluster] = map[string]bool{}
+		}
+		sets[cluster][topic] = true
+	}
+	if !cfg.API.DryRun {
+		add(pulsar.DefaultCluster, cfg.Pulsar.DefaultTopic)
+		for _, r := range cfg.Routes {
+			add(strings.ToLower(r.Cluster), r.Topic)
+			if r.FallbackTopic != "" {
+				add(strings.ToLower(r.Cluster), r.FallbackTopic)
+			}
+		}
+		// de kopieën van shadow mode
+		if sh := cfg.ShadowOptions(); sh.Enabled {
+			shadowTopics := cfg.ShadowTopics()
+			var mirrored []string
+			for cluster, set := range sets {
+				if cluster == sh.Cluster {
+					continue
+				}
+				for t := range set {
+					if st, ok := shadowTopics[t]; ok {
+						t = st
+					}
+					mirrored = append(mirrored, t)
+				}
+			}
+			for _, t := range mirrored {
+				add(sh.Cluster, t)
+			}
+		}
+	}
+	if cfg.Audit.Sink == "topic" {
+		add(pulsar.DefaultCluster, cfg.Audit.Topic)
+	}
+	out := make(map[string][]string, len(sets))
+	for cluster, set := range sets {
+		for t := range set {
+			out[cluster] = append(out[cluster], t)
+		}
+		sort.Strings(out[cluster])
+	}
+	return out
+}
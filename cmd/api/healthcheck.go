@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+)
+
+func newHealthCheckCmd(opts *globalOptions) *cobra.Command {
+	var (
+		url      string
+		timeout  time.Duration
+		certFile string
+		keyFile  string
+	)
+	cmd := &cobra.Command{
+		Use:   "healthcheck",
+		Short: "Test of de lokale API ready is (voor een Docker HEALTHCHECK of exec probe)",
+		Long: `Doet GET /ready op de lokale instance en eindigt met 0 als die 200 geeft, anders
+met 1. Zonder --url komen de poort en http/https uit de lokale config (bestand,
+profiel, env; geen remote config en geen validatie). Het certificaat van de API
+wordt niet gecontroleerd: het is voor de hostnaam, niet voor 127.0.0.1.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.Flags().StringVar(&url, "url", "", "URL van de probe (standaard http(s)://127.0.0.1:<api.port>/ready)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 3*time.Second, "timeout van de probe")
+	cmd.Flags().StringVar(&certFile, "cert", "", "client certificaat, als de API mTLS vraagt")
+	cmd.Flags().StringVar(&keyFile, "key", "", "private key van --cert")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		if url == "" {
+			v, err := localConfig(opts)
+			if err != nil {
+				return err
+			}
+			url = readyURL(v)
+		}
+		tlsConfig := &tls.Config{InsecureSkipVerify: true}
+		if certFile != "" || keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return fmt.Errorf("--cert/--key: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		client := &http.Client{Timeout: timeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+		resp, err := client.Get(url)
+		if err != nil {
+			return fmt.Errorf("healthcheck: %w", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("healthcheck: %s gave %s", url, resp.Status)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "ready")
+		return nil
+	}
+	return cmd
+}
+
+// localConfig leest enkel het config bestand met het profiel en de env
+// overrides: de probe draait vaak en mag niet van Consul of etcd afhangen.
+func localConfig(opts *globalOptions) (*viper.Viper, error) {
+	v := config.New()
+	if opts.configFile != "" {
+		v.SetConfigFile(opts.configFile)
+	}
+	if err := v.ReadInConfig(); err != nil {
+		if !errors.As(err, new(viper.ConfigFileNotFoundError)) {
+			return nil, fmt.Errorf("load config: %w", err)
+		}
+		return v, nil
+	}
+	if opts.env != "" {
+		f, err := os.Open(config.ProfileFile(v.ConfigFileUsed(), opts.env))
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", opts.env, err)
+		}
+		defer f.Close()
+		if err := v.MergeConfig(f); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", opts.env, err)
+		}
+	}
+	return v, nil
+}
+
+// readyURL is /ready op de poort van de API, met https als TLS aanstaat.
+func readyURL(v *viper.Viper) string {
+	scheme := "http"
+	if v.GetString("api.tls.certFile") != "" || v.GetString("api.tls.secret") != "" || v.GetBool("api.tls.acme.enabled") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://127.0.0.1:%d/ready", scheme, v.GetInt("api.port"))
+}
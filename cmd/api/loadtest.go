@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/rubenclaes/pulsar-api/internal/api"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// loadTestOptions zijn de flags van loadtest.
+type loadTestOptions struct {
+	url          string
+	apiKey       string
+	eventType    string
+	sourceSystem string
+	payload      string
+	count        int
+	rate         float64
+	concurrency  int
+	timeout      time.Duration
+	insecure     bool
+}
+
+func newLoadTestCmd() *cobra.Command {
+	o := &loadTestOptions{}
+	cmd := &cobra.Command{
+		Use:   "loadtest",
+		Short: "Stuur synthetische events naar een draaiende API en meet throughput en latency",
+		Long: `Stuurt --count events van --event-type via POST /api/v1/events naar een
+draaiende instance, aan --rate events per seconde. Ze gaan door de volledige
+pipeline (API key, schema, routes, quota, Pulsar), dus gebruik een eigen
+eventType of topic en een API key met een quotum dat de test toelaat. Geeft
+het aantal events per status en de latency percentielen, en eindigt met een
+fout als er events mislukt zijn.`,
+		Args: cobra.NoArgs,
+	}
+	cmd.Flags().StringVar(&o.url, "url", "http://localhost:8969", "basis URL van de API")
+	cmd.Flags().StringVar(&o.apiKey, "api-key", os.Getenv("PULSAR_API_KEY"), "X-API-Key (standaard $PULSAR_API_KEY)")
+	cmd.Flags().StringVar(&o.eventType, "event-type", "", "eventType van de events (verplicht)")
+	cmd.Flags().StringVar(&o.sourceSystem, "source-system", "loadtest", "sourceSystem van de events")
+	cmd.Flags().StringVar(&o.payload, "payload", "{}", "payload als JSON, of @bestand; moet aan het schema van het eventType voldoen")
+	cmd.Flags().IntVarP(&o.count, "count", "n", 1000, "aantal events")
+	cmd.Flags().Float64Var(&o.rate, "rate", 100, "events per seconde, 0 = zo snel als --concurrency toelaat")
+	cmd.Flags().IntVar(&o.concurrency, "concurrency", 16, "max. requests tegelijk")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 30*time.Second, "timeout per request")
+	cmd.Flags().BoolVar(&o.insecure, "insecure", false, "het certificaat van de API niet controleren")
+	_ = cmd.MarkFlagRequired("event-type")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		body, err := o.body()
+		if err != nil {
+			return err
+		}
+		if o.count <= 0 || o.concurrency <= 0 || o.rate < 0 {
+			return errors.New("--count and --concurrency must be positive, --rate must not be negative")
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "%d events van %s naar %s, %s, %d tegelijk\n",
+			o.count, o.eventType, o.url, rateString(o.rate), o.concurrency)
+		res := runLoadTest(ctx, o, body)
+		res.report(out)
+		if failed := res.failed(); failed > 0 {
+			return fmt.Errorf("loadtest: %d of %d event(s) failed", failed, len(res.latencies))
+		}
+		return nil
+	}
+	return cmd
+}
+
+func rateString(rate float64) string {
+	if rate == 0 {
+		return "onbeperkte rate"
+	}
+	return fmt.Sprintf("%g/s", rate)
+}
+
+// body is de request body van elk event.
+func (o *loadTestOptions) body() ([]byte, error) {
+	raw := []byte(o.payload)
+	if file, ok := strings.CutPrefix(o.payload, "@"); ok {
+		var err error
+		if raw, err = os.ReadFile(file); err != nil {
+			return nil, fmt.Errorf("--payload: %w", err)
+		}
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("--payload must be a JSON object: %w", err)
+	}
+	return json.Marshal(api.EventRequest{
+		EventType:    o.eventType,
+		SourceSystem: o.sourceSystem,
+		Payload:      payload,
+	})
+}
+
+// loadTestResult houdt per verstuurd event de status en latency bij.
+type loadTestResult struct {
+	mu        sync.Mutex
+	elapsed   time.Duration
+	latencies []time.Duration
+	statuses  map[string]int // HTTP status, of "error" als er geen response was
+	lastError string
+}
+
+func (r *loadTestResult) add(status string, latency time.Duration, errMsg string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latencies = append(r.latencies, latency)
+	r.statuses[status]++
+	if errMsg != "" {
+		r.lastError = errMsg
+	}
+}
+
+// failed telt alles behalve 2xx (verstuurd, gespooled of dry-run).
+func (r *loadTestResult) failed() int {
+	failed := 0
+	for s, n := range r.statuses {
+		if !strings.HasPrefix(s, "2") {
+			failed += n
+		}
+	}
+	return failed
+}
+
+// runLoadTest stuurt de events volgens het schema van --rate; zijn alle
+// workers bezig, dan loopt het schema achter en ligt de gehaalde rate lager.
+func runLoadTest(ctx context.Context, o *loadTestOptions, body []byte) *loadTestResult {
+	client := &http.Client{
+		Timeout: o.timeout,
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: o.concurrency,
+			TLSClientConfig:     &tls.Config{InsecureSkipVerify: o.insecure},
+		},
+	}
+	endpoint := strings.TrimSuffix(o.url, "/") + "/api/v1/events"
+	res := &loadTestResult{statuses: map[string]int{}}
+
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for range o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				res.add(postLoadTestEvent(ctx, client, endpoint, o.apiKey, body))
+			}
+		}()
+	}
+
+	start := time.Now()
+schedule:
+	for i := range o.count {
+		if o.rate > 0 {
+			due := start.Add(time.Duration(float64(i) / o.rate * float64(time.Second)))
+			select {
+			case <-time.After(time.Until(due)):
+			case <-ctx.Done():
+				break schedule
+			}
+		}
+		select {
+		case jobs <- struct{}{}:
+		case <-ctx.Done():
+			break schedule
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	return res
+}
+
+func postLoadTestEvent(ctx context.Context, client *http.Client, endpoint, apiKey string, body []byte) (string, time.Duration, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "error", 0, err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set(middleware.APIKeyHeader, apiKey)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "error", time.Since(start), err.Error()
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	latency := time.Since(start)
+
+	status := fmt.Sprint(resp.StatusCode)
+	if resp.StatusCode < 300 {
+		return status, latency, ""
+	}
+	var e struct {
+		Error   string `json:"error"`
+		Details string `json:"details"`
+	}
+	if json.Unmarshal(respBody, &e) != nil || e.Error == "" {
+		return status, latency, strings.TrimSpace(string(respBody))
+	}
+	if e.Details != "" {
+		return status, latency, e.Error + ": " + e.Details
+	}
+	return status, latency, e.Error
+}
+
+func (r *loadTestResult) report(out io.Writer) {
+	n := len(r.latencies)
+	fmt.Fprintf(out, "\n%d events in %s: %.1f events/s\n", n, r.elapsed.Round(time.Millisecond), float64(n)/r.elapsed.Seconds())
+
+	statuses := make([]string, 0, len(r.statuses))
+	for s := range r.statuses {
+		statuses = append(statuses, s)
+	}
+	sort.Strings(statuses)
+	for _, s := range statuses {
+		fmt.Fprintf(out, "  %-6s %d\n", s, r.statuses[s])
+	}
+	if n == 0 {
+		return
+	}
+
+	sorted := append([]time.Duration(nil), r.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) time.Duration {
+		return sorted[min(int(p*float64(n)), n-1)].Round(10 * time.Microsecond)
+	}
+	fmt.Fprintf(out, "latency p50 %s  p90 %s  p99 %s  max %s\n",
+		percentile(0.50), percentile(0.90), percentile(0.99), sorted[n-1].Round(10*time.Microsecond))
+	if r.lastError != "" {
+		fmt.Fprintf(out, "laatste fout: %s\n", r.lastError)
+	}
+}
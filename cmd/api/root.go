@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+)
+
+// globalOptions zijn de flags die elk subcommand deelt.
+type globalOptions struct {
+	configFile string
+	env        string
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &globalOptions{}
+
+	root := &cobra.Command{
+		Use:          "pulsar-api",
+		Short:        "REST gateway die JSON events naar Apache Pulsar publiceert",
+		SilenceUsage: true,
+	}
+	root.PersistentFlags().StringVarP(&opts.configFile, "config", "c", "",
+		"pad naar het config bestand (standaard config.yml in ./config, ., /etc/pulsar-api, ...)")
+	root.PersistentFlags().StringVarP(&opts.env, "env", "e", os.Getenv(config.EnvVar),
+		"profiel: config.<env>.yml wordt over het config bestand gelegd (standaard $"+config.EnvVar+")")
+
+	serveCmd := newServeCmd(opts)
+	root.AddCommand(serveCmd, newConfigCmd(opts), newLoadTestCmd(), newHealthCheckCmd(opts))
+
+	// zonder subcommand starten we de API, zodat dubbelklikken op de exe blijft werken
+	root.RunE = serveCmd.RunE
+	return root
+}
+
+// loadConfig leest de config voor elk subcommand op dezelfde manier: bestand
+// (--config of de standaard paden) met het profiel van --env, env overrides,
+// en de flags van cmd die in flags op een config key gemapt zijn. De config
+// wordt ook gevalideerd.
+func loadConfig(opts *globalOptions, cmd *cobra.Command, flags map[string]string) (*viper.Viper, *config.Config, error) {
+	v := config.New()
+	if opts.configFile != "" {
+		v.SetConfigFile(opts.configFile)
+	}
+	for key, name := range flags {
+		if f := cmd.Flags().Lookup(name); f != nil {
+			if err := v.BindPFlag(key, f); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	sources, err := config.Read(v, opts.env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load config: %w", err)
+	}
+	cfg, err := config.Load(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.SetSources(sources)
+	for key, name := range flags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			cfg.MarkFlag(key)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return v, cfg, nil
+}
@@ -0,0 +1,939 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/api"
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/authz"
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/deadletter"
+	"github.com/rubenclaes/pulsar-api/internal/enrich"
+	"github.com/rubenclaes/pulsar-api/internal/health"
+	"github.com/rubenclaes/pulsar-api/internal/idempotency"
+	"github.com/rubenclaes/pulsar-api/internal/logging"
+	"github.com/rubenclaes/pulsar-api/internal/metrics"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/quota"
+	"github.com/rubenclaes/pulsar-api/internal/recent"
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+	"github.com/rubenclaes/pulsar-api/internal/routing"
+	"github.com/rubenclaes/pulsar-api/internal/rules"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+	"github.com/rubenclaes/pulsar-api/internal/secrets"
+	"github.com/rubenclaes/pulsar-api/internal/sentry"
+	"github.com/rubenclaes/pulsar-api/internal/server"
+	"github.com/rubenclaes/pulsar-api/internal/spool"
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
+)
+
+func newServeCmd(opts *globalOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Start de REST API",
+		Args:  cobra.NoArgs,
+	}
+	// flags winnen van config en env
+	flags := map[string]string{
+		"api.port":   "port",
+		"api.dryRun": "dry-run",
+	}
+	cmd.Flags().Int("port", 0, "poort van de API (api.port)")
+	cmd.Flags().Bool("dry-run", false, "events niet naar Pulsar sturen (api.dryRun)")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		// de logger hangt van de config af; een ongeldige config loggen we met de defaults
+		v, cfg, err := loadConfig(opts, cmd, flags)
+		if err != nil {
+			_ = logging.Init(logging.Options{})
+			logging.Logger.Fatal("Invalid configuration", zap.Strings("problems", config.Problems(err)))
+		}
+		if err := logging.Init(logging.Options{
+			Mode:     cfg.Logging.Mode,
+			Encoding: cfg.Logging.Encoding,
+			Level:    cfg.Logging.Level,
+			Sampling: logging.Sampling{
+				Enabled:    cfg.Logging.Sampling.Enabled,
+				Initial:    cfg.Logging.Sampling.Initial,
+				Thereafter: cfg.Logging.Sampling.Thereafter,
+				Tick:       cfg.Logging.Sampling.Tick,
+			},
+			OTLP: logOTLP(cfg),
+			File: logging.File{
+				Path:       cfg.Logging.File.Path,
+				MaxSizeMB:  cfg.Logging.File.MaxSizeMB,
+				MaxAgeDays: cfg.Logging.File.MaxAgeDays,
+				MaxBackups: cfg.Logging.File.MaxBackups,
+				Compress:   cfg.Logging.File.Compress,
+			},
+		}); err != nil {
+			return fmt.Errorf("logging: %w", err)
+		}
+		defer logging.Sync()
+		log := logging.Logger
+		serve(v, cfg, opts.env, log)
+		return nil
+	}
+	return cmd
+}
+
+func serve(v *viper.Viper, cfg *config.Config, env string, log *zap.Logger) {
+	log.Info("Config loaded", zap.String("file", v.ConfigFileUsed()), zap.String("env", env))
+
+	// SECRETS: secret:<path>#<field> referenties uit Vault of AWS (secrets.provider)
+	ctx := context.Background()
+	source, err := newSecretSource(ctx, cfg, log)
+	if err != nil {
+		log.Fatal("Failed to set up secrets provider", zap.Error(err))
+	}
+	resolver := secrets.NewResolver(source)
+	if err := cfg.ResolveSecrets(ctx, resolver); err != nil {
+		log.Fatal("Failed to resolve secrets", zap.Strings("problems", config.Problems(err)))
+	}
+	var pulsarToken func() (string, error)
+	if cfg.Pulsar.AuthToken != "" {
+		pulsarToken = resolver.Supplier(ctx, cfg.Pulsar.AuthToken)
+	}
+
+	port := cfg.API.Port
+	tlsOpts := server.TLSOptions{
+		CertFile:     cfg.API.TLS.CertFile,
+		KeyFile:      cfg.API.TLS.KeyFile,
+		ClientCAFile: cfg.API.TLS.ClientCAFile,
+	}
+	sigOpts := middleware.SignatureOptions{
+		Required: cfg.Signature.Required,
+		Secrets:  cfg.Signature.Secrets,
+		Window:   cfg.Signature.Window,
+		NonceTTL: cfg.Signature.NonceTTL,
+
+		AllowUnsignedSessions: cfg.Signature.AllowUnsignedSessions,
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Failed to initialise tracing", zap.Error(err))
+	}
+	defer shutdownTracing(context.Background())
+
+	// SENTRY: panics en 5xx responses, enkel met sentry.dsn
+	environment := cfg.Sentry.Environment
+	if environment == "" {
+		environment = env
+	}
+	if err := sentry.Init(sentry.Options{
+		DSN:         cfg.Sentry.DSN,
+		Environment: environment,
+		Release:     cfg.Sentry.Release,
+		SampleRate:  cfg.Sentry.SampleRate,
+	}); err != nil {
+		log.Fatal("Failed to initialise Sentry", zap.Error(err))
+	}
+	defer sentry.Flush(2 * time.Second)
+
+	// METRICS: op /metrics en/of via OTLP, dezelfde registry
+	var appMetrics *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		appMetrics = metrics.New(metrics.Options{
+			ClientLabel: cfg.Metrics.ClientLabel,
+			MaxClients:  cfg.Metrics.MaxClients,
+		})
+		if cfg.Metrics.OTLP.Enabled {
+			stopPush, err := appMetrics.PushOTLP(context.Background(), metricsOTLP(cfg))
+			if err != nil {
+				log.Fatal("Failed to initialise OTLP metrics", zap.Error(err))
+			}
+			defer stopPush(context.Background())
+		}
+	}
+
+	// per cluster een client met één producer per topic, met de opties uit
+	// routes; de verbinding op de achtergrond, zodat de service ook start als
+	// Pulsar (even) plat ligt
+	var (
+		clusters  []*pulsarCluster
+		publisher pulsar.Publisher // blijft nil in dry-run
+		inFlight  *pulsar.InFlight // begrenst de sends van de handlers, niet die van de spool
+		shadow    *pulsar.Shadow
+	)
+	if !cfg.API.DryRun {
+		connectCtx, stopConnect := context.WithCancel(context.Background())
+		byName := pulsar.Clusters{}
+		for _, name := range cfg.Clusters() {
+			brokerURL, authToken := cfg.ClusterURL(name)
+			var token func() (string, error)
+			if authToken != "" {
+				token = resolver.Supplier(ctx, authToken)
+			}
+			clientMetrics := pulsar.ClientMetrics{Cluster: name}
+			if appMetrics != nil {
+				clientMetrics.Registerer = appMetrics.Registry()
+			}
+			producers, err := pulsar.NewPool(brokerURL, token, cfg.ProducerOptions(name), cfg.Pulsar.Retry, clientMetrics)
+			if err != nil {
+				log.Fatal("Failed to create Pulsar client", zap.String("cluster", name), zap.Error(err))
+			}
+			cl := &pulsarCluster{name: name, producers: producers}
+			if cfg.Pulsar.Provision.Enabled {
+				cl.producers.SetProvisioner(pulsar.NewProvisioner(cfg.ClusterAdminURL(name), token, cfg.Pulsar.Provision, log.Named("provision").With(zap.String("cluster", name))))
+			}
+			cl.shadowOnly = name == cfg.ShadowOptions().Cluster && name != pulsar.DefaultCluster && !slices.Contains(slices.Collect(maps.Values(cfg.RouteClusters())), name)
+			defer cl.producers.Close()
+			if topic := cfg.ConnectTopic(name); topic != "" {
+				go cl.producers.KeepConnecting(connectCtx, topic, log.Named("pulsar").With(zap.String("cluster", name)))
+			}
+			cl.breaker = pulsar.NewBreaker(cl.producers, cfg.Pulsar.CircuitBreaker)
+			byName[name] = cl.breaker
+			clusters = append(clusters, cl)
+		}
+		defer stopConnect() // vóór de producers sluiten
+		// SHADOW: elk bericht ook naar pulsar.shadow.cluster (best-effort)
+		shadow = pulsar.NewShadow(byName, cfg.ShadowOptions(), cfg.ShadowTopics(), log.Named("shadow"))
+		defer shadow.Close() // vóór de producers sluiten
+		publisher = shadow
+		inFlight = pulsar.NewInFlight(shadow, cfg.Pulsar.InFlight)
+	}
+
+	auditLog := newAuditLogger(cfg, pulsarToken, log)
+	defer auditLog.Close()
+	if appMetrics != nil {
+		appMetrics.AuditDropped(auditLog.Dropped)
+	}
+
+	// JSON Schema's uit schemaDir, schemas en eventueel een centrale registry
+	external, err := newExternalSchemas(cfg)
+	if err != nil {
+		log.Fatal("Invalid schema registry", zap.Error(err))
+	}
+	schemas, err := loadSchemas(ctx, external, cfg)
+	if err != nil {
+		log.Fatal("Invalid JSON schemas", zap.Strings("problems", config.Problems(err)))
+	}
+	log.Info("JSON schemas loaded", zap.Strings("eventTypes", schemas.EventTypes()))
+
+	redactor := redact.New(cfg.Redaction, []byte(cfg.RedactSecret))
+	quotas := quota.New(cfg.Quotas.Default, cfg.Quotas.Clients)
+	policy := authz.New(cfg.Authorization.Enabled, cfg.Authorization.Clients, cfg.Authorization.Scopes)
+
+	var handlerPublisher pulsar.Publisher
+	if inFlight != nil {
+		handlerPublisher = inFlight
+	}
+	handler := api.NewEventHandler(handlerPublisher, cfg.Pulsar.DefaultTopic, cfg.RouteTopics(), cfg.API.DryRun, schemas, auditLog, redactor, quotas, policy)
+	handler.SetFallbackTopics(cfg.FallbackTopics())
+	handler.SetRouteClusters(cfg.RouteClusters())
+	handler.SetRoutePatterns(cfg.RoutePatterns())
+	handler.SetRouting(routing.New(cfg.Routing))
+	handler.SetStrict(cfg.Strict.Enabled, cfg.Strict.EventTypes)
+	handler.SetPayloadLimits(cfg.PayloadLimits())
+	handler.SetRules(rules.New(cfg.Rules))
+	handler.SetEnrichment(enrich.New(cfg.Enrichment))
+	handler.SetDefaults(cfg.Defaults)
+	handler.SetVersions(cfg.Versions)
+	handler.SetSourceSystems(cfg.SourceSystems.Known, cfg.SourceSystems.Clients)
+	handler.SetSourceSystemRoutes(cfg.SourceSystems.Routes)
+	handler.SetTenants(cfg.Tenants.Header, cfg.Tenants.Default, cfg.Tenants.Known, cfg.Tenants.Clients, cfg.Tenants.Isolate)
+	handler.SetEnvelope(cfg.Envelope.Enabled, cfg.Envelope.RequireOccurredAt, cfg.Envelope.Gateway)
+	handler.SetCloudEvents(cfg.CloudEvents.Enabled, cfg.CloudEvents.TypePrefix, cfg.CloudEvents.Extensions)
+	redactor.SetCloudEventsTypePrefix(cfg.CloudEvents.TypePrefix)
+	handler.SetBatchParallelism(cfg.API.Batch.Parallelism)
+	handler.SetBatchStreamThreshold(cfg.API.Batch.StreamThreshold)
+	handler.SetMediaTypeVendor(cfg.API.MediaTypeVendor)
+	handler.Metrics = appMetrics
+	// SCHEMA REGISTRY: payloads ook tegen het schema van de topic in Pulsar
+	if sr := cfg.Pulsar.SchemaRegistry; sr.Enabled {
+		admins := map[string]schema.PulsarAdmin{}
+		for _, name := range cfg.Clusters() {
+			admin := schema.PulsarAdmin{URL: cfg.ClusterAdminURL(name)}
+			if _, authToken := cfg.ClusterURL(name); authToken != "" {
+				admin.Token = resolver.Supplier(ctx, authToken)
+			}
+			admins[name] = admin
+		}
+		handler.TopicSchemas = schema.NewTopicSchemas(admins, sr.CacheTTL, sr.Timeout)
+	}
+	if cfg.Recent.Size > 0 {
+		handler.Recent = recent.New(cfg.Recent.Size)
+	}
+
+	// HEALTH: toestand per component, zie health.Checker
+	checks := health.New()
+	if len(clusters) == 0 {
+		checks.Register("pulsar", func() health.Report {
+			return health.Report{Status: health.StatusOK, Info: map[string]interface{}{"state": "dry-run"}}
+		})
+	}
+	for _, cl := range clusters {
+		checks.Register(cl.healthName(), func() health.Report {
+			return cl.health(cfg.Spool.Enabled)
+		})
+	}
+	// TOPICS: bestaat elke topic en mag er geproduceerd worden
+	var verifier *topicVerifier
+	if len(clusters) > 0 && cfg.Pulsar.VerifyTopics.Enabled {
+		verifier = newTopicVerifier(cfg, clusters, log.Named("topics"))
+		verifyCtx, stopVerify := context.WithCancel(context.Background())
+		defer stopVerify()
+		go verifier.run(verifyCtx)
+		checks.Register("topics", verifier.health)
+	}
+
+	// DEAD LETTERS: events die definitief niet verstuurd konden worden
+	var deadLetters *deadletter.Store
+	if cfg.DeadLetter.Enabled {
+		deadLetters, err = deadletter.New(cfg.DeadLetter.Dir, cfg.DeadLetter.MaxBytes)
+		if err != nil {
+			log.Fatal("Failed to open dead-letter store", zap.String("dir", cfg.DeadLetter.Dir), zap.Error(err))
+		}
+		handler.DeadLetter = deadLetters
+	}
+
+	// SPOOL: events bewaren als Pulsar onbereikbaar is, en later versturen
+	if cfg.Spool.Enabled && publisher != nil {
+		outbox, err := spool.New(cfg.Spool.Dir, cfg.Spool.MaxBytes)
+		if err != nil {
+			log.Fatal("Failed to open spool", zap.String("dir", cfg.Spool.Dir), zap.Error(err))
+		}
+		outbox.DeadLetter = deadLetters
+		if n := outbox.Len(); n > 0 {
+			log.Info("Spool has events from a previous run", zap.Int("events", n))
+		}
+		relayCtx, stopRelay := context.WithCancel(context.Background())
+		relayDone := make(chan struct{})
+		go func() {
+			defer close(relayDone)
+			outbox.Run(relayCtx, publisher, cfg.Spool.RetryInterval, log.Named("spool"))
+		}()
+		defer func() { // vóór producers.Close
+			stopRelay()
+			<-relayDone
+		}()
+		handler.Spool = outbox
+		checks.Register("spool", func() health.Report {
+			return spoolHealth(outbox)
+		})
+	}
+
+	// IDEMPOTENCY: retries niet opnieuw publiceren; met meerdere replicas via Redis
+	var idemStore idempotency.Store = idempotency.NewMemoryStore()
+	if cfg.Idempotency.Store == "redis" {
+		rc := cfg.Idempotency.Redis
+		redisStore := idempotency.NewRedisStore(idempotency.RedisOptions{
+			Addr:      rc.Addr,
+			Username:  rc.Username,
+			Password:  rc.Password,
+			DB:        rc.DB,
+			TLS:       rc.TLS,
+			KeyPrefix: rc.KeyPrefix,
+		})
+		defer redisStore.Close()
+		idemStore = redisStore
+		checks.Register("idempotency", func() health.Report {
+			return redisHealth(redisStore, rc.Addr)
+		})
+	}
+	idem := middleware.NewIdempotency(idemStore, cfg.Idempotency.Enabled, cfg.Idempotency.TTL, cfg.Idempotency.DedupWindow)
+
+	// DRAIN: readiness uit voor een rolling deployment, zie /admin/drain
+	drain := middleware.NewDrain()
+	if inFlight != nil {
+		drain.AddPending("sends", inFlight.Count)
+	}
+	if handler.Spool != nil {
+		drain.AddPending("spool", handler.Spool.Len)
+	}
+	if shadow != nil {
+		drain.AddPending("shadow", shadow.Queued)
+	}
+	drain.AddPending("audit", auditLog.Queued)
+
+	maintenance := middleware.NewMaintenance(cfg.API.Maintenance.Enabled, cfg.API.Maintenance.Message)
+
+	// enkel de publish endpoints tellen mee voor de concurrency limiet
+	priority := middleware.NewPriorityLane(cfg.API.Priority.Enabled, cfg.API.Priority.Header, cfg.API.Priority.Values)
+	handler.SetPriority(priority, cfg.API.Priority.TopicSuffix)
+	limiter := middleware.NewConcurrencyLimiter(
+		cfg.API.Concurrency.MaxInFlight,
+		cfg.API.Concurrency.Reserved,
+		cfg.API.Concurrency.QueueWait,
+		cfg.API.Concurrency.RetryAfter,
+	)
+
+	bodyLog := middleware.NewBodyLogger(cfg.Logging.Bodies.Enabled, cfg.Logging.Bodies.MaxBytes, redactor)
+
+	// HOT RELOAD: dryRun, routes, schemas en limieten volgen config.yaml zonder herstart
+	bus := config.NewBus(cfg)
+	checks.Register("config", func() health.Report {
+		return configHealth(bus.Status())
+	})
+	adminHandler := api.NewAdminHandler(auditLog, maintenance, bus, logging.Level)
+	adminHandler.DeadLetter = deadLetters
+	adminHandler.Drain = drain
+	adminHandler.Shadow = shadow
+	adminHandler.Health = checks
+	adminHandler.Events = handler
+	adminHandler.ReloadSchemas = func() error {
+		updated, err := loadSchemas(ctx, external, bus.Current())
+		if err != nil {
+			return err
+		}
+		handler.SetSchemas(updated)
+		return nil
+	}
+	bus.Prepare(func(next *config.Config) error {
+		return next.ResolveSecrets(ctx, resolver)
+	})
+	bus.Validate(func(next *config.Config) error {
+		if !next.API.DryRun && len(clusters) == 0 {
+			return errors.New("api.dryRun cannot be disabled at runtime: started without a Pulsar producer, restart required")
+		}
+		if !maps.Equal(next.Pulsar.Clusters, cfg.Pulsar.Clusters) {
+			return errors.New("pulsar.clusters cannot change at runtime, restart required")
+		}
+		return nil
+	})
+	// schema's worden in de validator gecompileerd, zodat een fout schema de reload weigert
+	nextSchemas := schemas
+	bus.Validate(func(next *config.Config) (err error) {
+		nextSchemas, err = loadSchemas(ctx, external, next)
+		return err
+	})
+	if external != nil {
+		go external.Refresh(ctx, func() map[string]string { return bus.Current().Registry.Subjects }, func() {
+			updated, err := loadSchemas(ctx, external, bus.Current())
+			if err != nil {
+				log.Error("Schema from registry does not compile, keeping previous schemas", zap.Strings("problems", config.Problems(err)))
+				return
+			}
+			handler.SetSchemas(updated)
+			log.Info("JSON schemas updated from registry", zap.Strings("eventTypes", updated.EventTypes()))
+		}, func(err error) {
+			log.Warn("Schema registry refresh failed, keeping previous schemas", zap.Error(err))
+		})
+	}
+	logLevel := cfg.Logging.Level
+	bus.Subscribe(func(next *config.Config) {
+		handler.ApplyConfig(next.API.DryRun, next.RouteTopics(), nextSchemas)
+		handler.SetFallbackTopics(next.FallbackTopics())
+		handler.SetRouteClusters(next.RouteClusters())
+		handler.SetRoutePatterns(next.RoutePatterns())
+		handler.SetRouting(routing.New(next.Routing))
+		handler.SetStrict(next.Strict.Enabled, next.Strict.EventTypes)
+		handler.SetPayloadLimits(next.PayloadLimits())
+		handler.SetRules(rules.New(next.Rules))
+		handler.SetEnrichment(enrich.New(next.Enrichment))
+		handler.SetDefaults(next.Defaults)
+		handler.SetVersions(next.Versions)
+		handler.SetSourceSystems(next.SourceSystems.Known, next.SourceSystems.Clients)
+		handler.SetSourceSystemRoutes(next.SourceSystems.Routes)
+		handler.SetTenants(next.Tenants.Header, next.Tenants.Default, next.Tenants.Known, next.Tenants.Clients, next.Tenants.Isolate)
+		handler.SetEnvelope(next.Envelope.Enabled, next.Envelope.RequireOccurredAt, next.Envelope.Gateway)
+		handler.SetCloudEvents(next.CloudEvents.Enabled, next.CloudEvents.TypePrefix, next.CloudEvents.Extensions)
+		redactor.SetCloudEventsTypePrefix(next.CloudEvents.TypePrefix)
+		handler.SetBatchParallelism(next.API.Batch.Parallelism)
+		handler.SetBatchStreamThreshold(next.API.Batch.StreamThreshold)
+		handler.SetMediaTypeVendor(next.API.MediaTypeVendor)
+		for _, cl := range clusters {
+			cl.producers.SetOptions(next.ProducerOptions(cl.name))
+			cl.producers.SetRetry(next.Pulsar.Retry)
+			cl.breaker.Update(next.Pulsar.CircuitBreaker)
+		}
+		if handler.Recent != nil && next.Recent.Size > 0 {
+			handler.Recent.Resize(next.Recent.Size)
+		}
+		if inFlight != nil {
+			inFlight.Update(next.Pulsar.InFlight)
+			shadow.Update(next.ShadowOptions(), next.ShadowTopics())
+		}
+		quotas.SetLimits(next.Quotas.Default, next.Quotas.Clients)
+		priority.Update(next.API.Priority.Enabled, next.API.Priority.Header, next.API.Priority.Values)
+		handler.SetPriority(priority, next.API.Priority.TopicSuffix)
+		limiter.Update(
+			next.API.Concurrency.MaxInFlight,
+			next.API.Concurrency.Reserved,
+			next.API.Concurrency.QueueWait,
+			next.API.Concurrency.RetryAfter,
+		)
+		bodyLog.Update(next.Logging.Bodies.Enabled, next.Logging.Bodies.MaxBytes)
+		if verifier != nil {
+			verifier.update(next)
+		}
+		idem.Update(next.Idempotency.Enabled, next.Idempotency.TTL, next.Idempotency.DedupWindow)
+		// enkel bij een gewijzigd logging.level, zodat een reload een level
+		// van PUT /admin/log-level niet terugzet
+		if next.Logging.Level != logLevel {
+			logLevel = next.Logging.Level
+			_ = logging.SetLevel(cfg.Logging.Mode, logLevel) // gevalideerd door de bus
+		}
+	})
+	if err := config.Watch(v, env, bus, log); err != nil {
+		log.Error("Config watcher failed, hot reload disabled", zap.Error(err))
+	}
+
+	// mode moet voor gin.New gezet zijn, anders print gin toch debug output
+	if mode := cfg.API.Gin.Mode; mode != "" {
+		gin.SetMode(mode)
+	}
+	r := gin.New()
+	r.MaxMultipartMemory = cfg.API.Gin.MaxMultipartMemory
+	r.HandleMethodNotAllowed = cfg.API.Gin.HandleMethodNotAllowed
+	// client IP (ipFilter, rate limits, audit) enkel uit headers van gekende
+	// proxies; zonder trustedProxies telt het adres van de verbinding
+	if err := r.SetTrustedProxies(cfg.API.TrustedProxies); err != nil {
+		log.Fatal("Invalid api.trustedProxies", zap.Error(err))
+	}
+	r.RemoteIPHeaders = cfg.API.RemoteIPHeaders
+	r.Use(gin.Recovery())
+	r.Use(sentry.Middleware())
+	r.Use(tracing.Middleware())
+	r.Use(middleware.CorrelationID())
+	r.Use(middleware.RequestLogger(log))
+	r.Use(middleware.AccessLog(log.Named("access")))
+	r.Use(middleware.ClientCertIdentity(cfg.API.TLS.ClientIdentities))
+	if appMetrics != nil {
+		r.Use(appMetrics.HTTP())
+	}
+	r.NoMethod(func(c *gin.Context) {
+		problem.Abort(c, http.StatusMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path, middleware.GetCorrelationID(c))
+	})
+
+	// IP allow/deny lijsten per route group (ipFilter.<group>.allow/deny). Het
+	// client IP volgt SetTrustedProxies hierboven: X-Forwarded-For van een niet
+	// vertrouwde bron omzeilt de filter niet.
+	ipFilter := func(group string) gin.HandlerFunc {
+		rules := cfg.IPFilter[strings.ToLower(group)]
+		f, err := middleware.IPFilter(rules.Allow, rules.Deny)
+		if err != nil {
+			log.Fatal("Invalid ipFilter configuration", zap.String("group", group), zap.Error(err))
+		}
+		return f
+	}
+
+	// HEALTH
+	r.GET("/health", checks.Handler())
+	if verifier != nil {
+		r.GET("/ready", verifier.ready(), drain.Ready())
+	} else {
+		r.GET("/ready", drain.Ready())
+	}
+
+	// METRICS
+	if appMetrics != nil && cfg.Metrics.Prometheus {
+		r.GET("/metrics", ipFilter("metrics"), appMetrics.Handler())
+	}
+
+	// OPENAPI
+	r.GET("/openapi.yaml", func(c *gin.Context) {
+		c.Header("Content-Type", "application/yaml")
+		c.String(http.StatusOK, openAPISpec)
+	})
+
+	// ----------------------------------------
+	// UI — ONLY ON /ui  (NO REDIRECTS ANYWHERE)
+	// ----------------------------------------
+	// met ui.login een sessie via een API key, zie middleware.Sessions
+	sessions := middleware.NewSessions(cfg.UI.Login, cfg.APIKeys, cfg.UI.SessionTTL, cfg.UI.SecureCookie)
+	tester, err := newUI(sessions.Enabled())
+	if err != nil {
+		log.Fatal("Failed to load the UI", zap.Error(err))
+	}
+	r.GET("/ui", ipFilter("ui"), sessions.Identity(), tester.page)
+	r.GET("/ui/*filepath", ipFilter("ui"), sessions.Identity(), tester.asset)
+	if sessions.Enabled() {
+		r.POST("/ui/login", ipFilter("ui"), sessions.Login)
+		r.POST("/ui/logout", ipFilter("ui"), sessions.Logout)
+	}
+
+	// ----------------------------------------
+	// API
+	// ----------------------------------------
+	v1 := r.Group("/api/v1",
+		middleware.Timeout(cfg.API.RequestTimeout),
+		bodyLog.Handler(),
+		ipFilter("api"),
+		sessions.Identity(),
+		middleware.APIKeyIdentity(cfg.APIKeys),
+		middleware.VerifySignature(sigOpts),
+	)
+	{
+		v1.POST("/events", drain.Track(), maintenance.Guard(), idem.Handler(true), priority.Handler(), limiter.Handler(), handler.PostEvent)
+		v1.POST("/events/batch", drain.Track(), maintenance.Guard(), idem.Handler(false), priority.Handler(), limiter.Handler(), handler.PostBatch)
+		v1.GET("/usage", handler.GetUsage)
+		v1.GET("/recent", handler.GetRecent)
+		v1.GET("/history", handler.GetHistory)
+		v1.GET("/event-types", handler.GetEventTypes)
+		v1.GET("/event-types/:eventType", handler.GetEventType)
+		v1.GET("/event-types/:eventType/template", handler.GetEventTemplate)
+	}
+
+	// live tail: zonder request timeout en body log, de stream blijft open
+	stream := r.Group("/api/v1",
+		ipFilter("api"),
+		sessions.Identity(),
+		middleware.APIKeyIdentity(cfg.APIKeys),
+		middleware.VerifySignature(sigOpts),
+	)
+	stream.GET("/recent/stream", handler.GetRecentStream)
+
+	// ----------------------------------------
+	// ADMIN
+	// ----------------------------------------
+	if len(cfg.Admin.Clients) == 0 {
+		log.Warn("admin.clients is empty, the admin API refuses every request")
+	}
+	admin := r.Group("/admin",
+		ipFilter("admin"),
+		middleware.APIKeyIdentity(cfg.APIKeys),
+		middleware.RequireClient(cfg.Admin.Clients),
+	)
+	{
+		admin.GET("/maintenance", adminHandler.GetMaintenance)
+		admin.PUT("/maintenance", adminHandler.PutMaintenance)
+		admin.GET("/config", adminHandler.GetConfig)
+		admin.GET("/diagnostics", adminHandler.GetDiagnostics)
+		admin.GET("/log-level", adminHandler.GetLogLevel)
+		admin.PUT("/log-level", adminHandler.PutLogLevel)
+		admin.GET("/failures", adminHandler.ListFailures)
+		admin.GET("/failures/:id", adminHandler.GetFailure)
+		admin.DELETE("/failures/:id", adminHandler.DeleteFailure)
+		admin.GET("/drain", adminHandler.GetDrain)
+		admin.POST("/drain", adminHandler.PostDrain)
+		admin.DELETE("/drain", adminHandler.DeleteDrain)
+		admin.GET("/shadow", adminHandler.GetShadow)
+		admin.GET("/schemas/:eventType", adminHandler.GetSchema)
+		admin.PUT("/schemas/:eventType", adminHandler.PutSchema)
+	}
+
+	// START SERVER
+	addr := fmt.Sprintf("0.0.0.0:%d", port)
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           r,
+		ReadTimeout:       cfg.API.ReadTimeout,
+		ReadHeaderTimeout: cfg.API.ReadHeaderTimeout,
+		WriteTimeout:      cfg.API.WriteTimeout,
+		IdleTimeout:       cfg.API.IdleTimeout,
+	}
+
+	// plain HTTP listener: redirect naar HTTPS, en in ACME mode ook de HTTP-01 challenges
+	var redirect http.Handler
+	if cfg.API.TLS.ACME.Enabled {
+		manager, err := server.NewACMEManager(server.ACMEOptions{
+			Hosts:        cfg.API.TLS.ACME.Hosts,
+			CacheDir:     cfg.API.TLS.ACME.CacheDir,
+			Email:        cfg.API.TLS.ACME.Email,
+			DirectoryURL: cfg.API.TLS.ACME.DirectoryURL,
+		})
+		if err != nil {
+			log.Fatal("Invalid ACME configuration", zap.Error(err))
+		}
+		if srv.TLSConfig, err = server.ACMETLSConfig(manager, tlsOpts.ClientCAFile); err != nil {
+			log.Fatal("Invalid TLS configuration", zap.Error(err))
+		}
+		redirect = manager.HTTPHandler(server.RedirectHandler(port))
+	} else if tlsOpts.Enabled() {
+		reloader, err := server.NewCertReloader(tlsOpts, log)
+		if err != nil {
+			log.Fatal("Invalid TLS configuration", zap.Error(err))
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+		go func() {
+			if err := reloader.Watch(context.Background()); err != nil {
+				log.Error("TLS certificate watcher stopped, hot reload disabled", zap.Error(err))
+			}
+		}()
+		redirect = server.RedirectHandler(port)
+	} else if cfg.API.TLS.Secret != "" {
+		reloader, err := server.NewSecretCertReloader(func() ([]byte, []byte, error) {
+			data, err := resolver.Read(ctx, cfg.API.TLS.Secret)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []byte(data["certificate"]), []byte(data["private_key"]), nil
+		}, tlsOpts.ClientCAFile, log)
+		if err != nil {
+			log.Fatal("Invalid TLS configuration", zap.Error(err))
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+		go reloader.Refresh(ctx, cfg.Secrets.RefreshInterval)
+		redirect = server.RedirectHandler(port)
+	}
+
+	// SIGTERM/SIGINT: geen nieuwe requests meer, lopende publishes afwerken
+	// (max. api.shutdownTimeout), daarna sluiten de defers producers, audit
+	// log, tracing en Sentry netjes af
+	stop, cancelStop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancelStop()
+	servers := []*http.Server{srv}
+	serveErr := make(chan error, 2)
+
+	if srv.TLSConfig != nil {
+		if redirectPort := cfg.API.TLS.RedirectPort; redirectPort > 0 {
+			redirectSrv := &http.Server{
+				Addr:              fmt.Sprintf("0.0.0.0:%d", redirectPort),
+				Handler:           redirect,
+				ReadHeaderTimeout: cfg.API.ReadHeaderTimeout,
+			}
+			servers = append(servers, redirectSrv)
+			go func() {
+				log.Info("Starting HTTP→HTTPS redirect", zap.String("address", redirectSrv.Addr))
+				if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					log.Error("Redirect listener stopped", zap.Error(err))
+				}
+			}()
+		}
+
+		log.Info("Starting API (TLS)",
+			zap.String("address", addr),
+			zap.Bool("mtls", tlsOpts.ClientCAFile != ""),
+		)
+		go func() { serveErr <- srv.ListenAndServeTLS("", "") }()
+	} else {
+		log.Info("Starting API", zap.String("address", addr))
+		go func() { serveErr <- srv.ListenAndServe() }()
+	}
+
+	select {
+	case err := <-serveErr:
+		if err != http.ErrServerClosed {
+			log.Fatal("Server stopped", zap.Error(err))
+		}
+	case <-stop.Done():
+	}
+	cancelStop() // een tweede signaal stopt meteen
+
+	log.Info("Shutting down, waiting for in-flight requests", zap.Duration("timeout", cfg.API.ShutdownTimeout))
+	if handler.Recent != nil {
+		handler.Recent.Close() // open live tails houden Shutdown anders op
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.API.ShutdownTimeout)
+	defer cancel()
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Warn("Shutdown timeout exceeded, closing remaining connections", zap.String("address", s.Addr), zap.Error(err))
+			_ = s.Close()
+		}
+	}
+	log.Info("Server stopped, flushing producers")
+}
+
+// logOTLP gebruikt de collector van de traces als logging.otlp geen eigen endpoint heeft.
+func logOTLP(cfg *config.Config) logging.OTLP {
+	o := logging.OTLP{
+		Enabled:     cfg.Logging.OTLP.Enabled,
+		Endpoint:    cfg.Logging.OTLP.Endpoint,
+		Insecure:    cfg.Logging.OTLP.Insecure,
+		ServiceName: cfg.Tracing.ServiceName,
+	}
+	if o.Endpoint == "" {
+		o.Endpoint, o.Insecure = cfg.Tracing.Endpoint, cfg.Tracing.Insecure
+	}
+	return o
+}
+
+// metricsOTLP gebruikt de collector van de traces als metrics.otlp geen eigen endpoint heeft.
+func metricsOTLP(cfg *config.Config) metrics.OTLP {
+	o := metrics.OTLP{
+		Endpoint:    cfg.Metrics.OTLP.Endpoint,
+		Insecure:    cfg.Metrics.OTLP.Insecure,
+		Interval:    cfg.Metrics.OTLP.Interval,
+		ServiceName: cfg.Tracing.ServiceName,
+	}
+	if o.Endpoint == "" {
+		o.Endpoint, o.Insecure = cfg.Tracing.Endpoint, cfg.Tracing.Insecure
+	}
+	return o
+}
+
+// newExternalSchemas maakt de client voor registry.provider (nil bij none).
+func newExternalSchemas(cfg *config.Config) (*schema.External, error) {
+	rc := cfg.Registry
+	if rc.Provider == "none" {
+		return nil, nil
+	}
+	return schema.NewExternal(schema.ExternalOptions{
+		Provider: rc.Provider,
+		URL:      rc.URL,
+		Group:    rc.Group,
+		Username: rc.Username,
+		Password: rc.Password,
+		Token:    rc.Token,
+		CacheTTL: rc.CacheTTL,
+		Timeout:  rc.Timeout,
+	})
+}
+
+// loadSchemas compileert de JSON Schema's van cfg, met die uit de registry
+// als external niet nil is.
+func loadSchemas(ctx context.Context, external *schema.External, cfg *config.Config) (*schema.Registry, error) {
+	var docs map[string]schema.Document
+	if external != nil {
+		var err error
+		if docs, err = external.Schemas(ctx, cfg.Registry.Subjects); err != nil {
+			return nil, err
+		}
+	}
+	return schema.Load(cfg.SchemaDir, cfg.Schemas, docs)
+}
+
+// newSecretSource maakt de provider uit secrets.provider (nil bij none).
+func newSecretSource(ctx context.Context, cfg *config.Config, log *zap.Logger) (secrets.Source, error) {
+	switch cfg.Secrets.Provider {
+	case "vault":
+		vc := cfg.Secrets.Vault
+		vault, err := secrets.NewVault(ctx, secrets.VaultOptions{
+			Address:   vc.Address,
+			Namespace: vc.Namespace,
+			Auth:      vc.Auth,
+			Token:     vc.Token,
+			Role:      vc.Role,
+			Mount:     vc.Mount,
+			JWTFile:   vc.JWTFile,
+			CAFile:    vc.CAFile,
+		}, log.Named("vault"))
+		if err != nil {
+			return nil, fmt.Errorf("connect to Vault: %w", err)
+		}
+		go vault.Run(ctx)
+		return vault, nil
+	case "aws":
+		aws, err := secrets.NewAWS(ctx, secrets.AWSOptions{
+			Region:   cfg.Secrets.AWS.Region,
+			Endpoint: cfg.Secrets.AWS.Endpoint,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("set up AWS secrets: %w", err)
+		}
+		return aws, nil
+	default:
+		return nil, nil
+	}
+}
+
+// newAuditLogger kiest de audit sink op basis van audit.sink (none/file/topic).
+func newAuditLogger(cfg *config.Config, pulsarToken func() (string, error), log *zap.Logger) *audit.Logger {
+	switch sink := cfg.Audit.Sink; sink {
+	case "", "none":
+		return audit.New(nil, log)
+	case "file":
+		fs, err := audit.NewFileSink(cfg.Audit.File)
+		if err != nil {
+			log.Fatal("Failed to open audit file", zap.Error(err))
+		}
+		return audit.New(fs, log)
+	case "topic":
+		ts, err := audit.NewTopicSink(cfg.Pulsar.URL, cfg.Audit.Topic, pulsarToken)
+		if err != nil {
+			log.Fatal("Failed to create the audit topic sink", zap.Error(err))
+		}
+		return audit.New(ts, log)
+	default:
+		log.Fatal("Unknown audit sink", zap.String("sink", sink))
+		return nil
+	}
+}
+
+// pulsarCluster is de client (producers) en circuit breaker van één cluster.
+type pulsarCluster struct {
+	name       string
+	producers  *pulsar.Pool
+	breaker    *pulsar.Breaker
+	shadowOnly bool // enkel de kopieën van shadow mode, geen routes
+}
+
+// healthName: "pulsar" voor de default cluster, anders "pulsar.<naam>".
+func (cl *pulsarCluster) healthName() string {
+	if cl.name == pulsar.DefaultCluster {
+		return "pulsar"
+	}
+	return "pulsar." + cl.name
+}
+
+// health: down als er niet gepubliceerd kan worden, degraded als de events
+// in de spool terechtkomen.
+func (cl *pulsarCluster) health(spooling bool) health.Report {
+	state := cl.producers.State()
+	circuit, retryAt := cl.breaker.State()
+	sent, failed := cl.producers.Stats()
+	info := map[string]interface{}{"state": state, "circuit": circuit, "sent": sent, "failed": failed}
+	if !retryAt.IsZero() {
+		info["retryAt"] = retryAt.UTC()
+	}
+	switch {
+	case state == pulsar.PoolConnected && circuit == pulsar.CircuitClosed:
+		return health.Report{Status: health.StatusOK, Info: info}
+	case circuit == pulsar.CircuitOpen:
+		info["state"] = "circuit-open"
+	}
+	if cl.shadowOnly {
+		info["role"] = "shadow" // best-effort, de events staan op de andere clusters
+	}
+	if spooling || circuit == pulsar.CircuitHalfOpen || cl.shadowOnly {
+		return health.Report{Status: health.StatusDegraded, Info: info}
+	}
+	return health.Report{Status: health.StatusDown, Info: info}
+}
+
+// spoolHealth: degraded zolang er events wachten, down als de spool vol is.
+func spoolHealth(outbox *spool.Spool) health.Report {
+	depth := outbox.Len()
+	size, maxBytes := outbox.Size()
+	info := map[string]interface{}{"depth": depth, "bytes": size, "maxBytes": maxBytes}
+	switch {
+	case maxBytes > 0 && size >= maxBytes*9/10:
+		return health.Report{Status: health.StatusDown, Info: info}
+	case depth > 0:
+		return health.Report{Status: health.StatusDegraded, Info: info}
+	}
+	return health.Report{Status: health.StatusOK, Info: info}
+}
+
+// redisHealth: degraded als Redis onbereikbaar is; requests gaan dan door
+// zonder deduplicatie.
+func redisHealth(store *idempotency.RedisStore, addr string) health.Report {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	info := map[string]interface{}{"store": "redis", "addr": addr}
+	if err := store.Ping(ctx); err != nil {
+		info["error"] = err.Error()
+		return health.Report{Status: health.StatusDegraded, Info: info}
+	}
+	return health.Report{Status: health.StatusOK, Info: info}
+}
+
+// configHealth: degraded als de laatste reload geweigerd werd; de service
+// draait dan verder op de vorige config.
+func configHealth(st config.BusStatus) health.Report {
+	info := map[string]interface{}{
+		"loadedAt": st.LoadedAt.UTC(),
+		"age":      time.Since(st.LoadedAt).Round(time.Second).String(),
+	}
+	if st.Failure == nil {
+		return health.Report{Status: health.StatusOK, Info: info}
+	}
+	info["reloadFailedAt"] = st.FailedAt.UTC()
+	info["reloadError"] = config.Problems(st.Failure)
+	return health.Report{Status: health.StatusDegraded, Info: info}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// uiFiles is de tester UI op /ui: HTML, CSS en JS zonder CDN, zodat hij ook
+// in een air-gapped omgeving werkt.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// ui serveert index.html op /ui en de assets op /ui/<pad>. index.html
+// verwijst naar de assets met ?v=<version>, een hash van alle bestanden: een
+// nieuwe build krijgt zo nieuwe URL's en de assets mogen voor altijd
+// gecachet worden.
+//
+// Met login krijgt een request zonder client identity (een UI sessie of een
+// client certificaat) op /ui de loginpagina in plaats van index.html.
+type ui struct {
+	files   fs.FS
+	index   []byte // met {{version}} ingevuld
+	login   []byte // idem, nil zonder login
+	version string
+}
+
+func newUI(login bool) (*ui, error) {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	err = fs.WalkDir(files, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(files, p)
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(p))
+		h.Write(b)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:12]
+	u := &ui{files: files, version: version}
+	if u.index, err = u.render("index.html"); err != nil {
+		return nil, err
+	}
+	if login {
+		if u.login, err = u.render("login.html"); err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+func (u *ui) render(name string) ([]byte, error) {
+	b, err := fs.ReadFile(u.files, name)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.ReplaceAll(string(b), "{{version}}", u.version)), nil
+}
+
+// page serveert index.html (of de loginpagina); no-cache, zodat een nieuwe
+// versie of een login meteen geldt.
+func (u *ui) page(c *gin.Context) {
+	c.Header("Vary", "Cookie")
+	if u.login != nil && middleware.GetClientID(c) == "" {
+		u.serve(c, "text/html; charset=utf-8", u.login, "no-cache", u.version+"-login")
+		return
+	}
+	u.serve(c, "text/html; charset=utf-8", u.index, "no-cache", u.version)
+}
+
+// asset serveert /ui/*filepath. Met de ?v= van deze build een jaar
+// cacheable, anders (een oude of geen versie) enkel met revalidatie.
+func (u *ui) asset(c *gin.Context) {
+	name := strings.TrimPrefix(path.Clean(c.Param("filepath")), "/")
+	if name == "" || name == "index.html" || name == "login.html" {
+		u.page(c)
+		return
+	}
+	b, err := fs.ReadFile(u.files, name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	cache := "no-cache"
+	if c.Query("v") == u.version {
+		cache = "public, max-age=31536000, immutable"
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	u.serve(c, contentType, b, cache, u.version)
+}
+
+// serve met ETag tag; de loginpagina heeft een eigen tag, anders krijgt de
+// browser na het inloggen een 304 op de gecachete loginpagina.
+func (u *ui) serve(c *gin.Context, contentType string, body []byte, cache, tag string) {
+	etag := `"` + tag + `"`
+	c.Header("Cache-Control", cache)
+	c.Header("ETag", etag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,225 @@
+"use strict";
+
+const $ = (id) => document.getElementById(id);
+
+// template is de body van het gekozen eventType, uit
+// GET /api/v1/event-types/:eventType/template.
+let template = { eventType: "", sourceSystem: "", payload: {} };
+
+// csrfToken is de CSRF cookie van een UI sessie (ui.login), "" zonder.
+function csrfToken() {
+  const cookie = document.cookie.split("; ").find((c) => c.startsWith("pulsar_csrf="));
+  return cookie ? cookie.slice("pulsar_csrf=".length) : "";
+}
+
+const session = csrfToken() !== "";
+
+// api is fetch met de X-CSRF-Token van de sessie. Is de sessie intussen
+// verlopen, dan toont herladen de loginpagina.
+async function api(url, options = {}) {
+  if (session && csrfToken() === "") {
+    location.reload();
+  }
+  const headers = { ...options.headers };
+  if (session) {
+    headers["X-CSRF-Token"] = csrfToken();
+  }
+  return fetch(url, { ...options, headers });
+}
+
+async function logout() {
+  await api("/ui/logout", { method: "POST" });
+  location.reload();
+}
+
+async function loadEventTypes() {
+  const select = $("eventType");
+  try {
+    const res = await api("/api/v1/event-types");
+    if (!res.ok) {
+      throw new Error(res.status);
+    }
+    const data = await res.json();
+    select.replaceChildren();
+    for (const info of data.eventTypes) {
+      const option = document.createElement("option");
+      option.value = info.eventType;
+      option.textContent = info.eventType + (info.schema ? "" : " (zonder schema)");
+      select.appendChild(option);
+    }
+    if (data.eventTypes.length === 0) {
+      $("required").textContent = "Geen eventTypes in de config.";
+    }
+  } catch (e) {
+    $("required").textContent = "eventTypes niet beschikbaar (" + e.message + ").";
+  }
+  await loadTemplate();
+}
+
+async function loadTemplate() {
+  const eventType = $("eventType").value;
+  const required = $("required");
+  if (eventType) {
+    try {
+      const res = await api("/api/v1/event-types/" + encodeURIComponent(eventType) + "/template");
+      if (!res.ok) {
+        throw new Error(res.status);
+      }
+      const data = await res.json();
+      template = data.body;
+      required.textContent = data.schema
+        ? "Verplicht: " + (data.required.map((p) => p.replace(/^\/payload\//, "")).join(", ") || "geen")
+        : "Geen schema: de payload wordt niet gevalideerd.";
+    } catch (e) {
+      required.textContent = "Template niet beschikbaar (" + e.message + ").";
+    }
+  }
+  if ($("endpoint").value.endsWith("/batch")) {
+    setBatch();
+  } else {
+    setSingle();
+  }
+}
+
+function setSingle() {
+  $("endpoint").value = "/api/v1/events";
+  $("body").value = JSON.stringify(template, null, 2);
+}
+
+function setBatch() {
+  $("endpoint").value = "/api/v1/events/batch";
+  $("body").value = JSON.stringify([template, template], null, 2);
+}
+
+async function send() {
+  const resp = $("response");
+  resp.textContent = "⏳ Versturen...";
+
+  try {
+    const res = await api($("endpoint").value, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: $("body").value,
+    });
+    const text = await res.text();
+    try {
+      resp.textContent = JSON.stringify(JSON.parse(text), null, 2);
+    } catch {
+      resp.textContent = text;
+    }
+  } catch (e) {
+    resp.textContent = "❌ Error: " + e;
+  }
+  loadHistory();
+}
+
+function message(tbody, text) {
+  const tr = document.createElement("tr");
+  const td = document.createElement("td");
+  td.colSpan = 6;
+  td.className = "muted";
+  td.textContent = text;
+  tr.appendChild(td);
+  tbody.replaceChildren(tr);
+}
+
+// next is de before van de volgende pagina history, 0 als er geen is.
+let next = 0;
+
+// loadHistory toont de laatste 50 events van deze client
+// (GET /api/v1/history); more voegt de volgende pagina toe.
+async function loadHistory(more = false) {
+  const tbody = $("recent");
+  let url = "/api/v1/history?limit=50";
+  if (more) {
+    url += "&before=" + next;
+  }
+  try {
+    const res = await api(url);
+    if (!res.ok) {
+      message(tbody, "Niet beschikbaar (" + res.status + ")");
+      next = 0;
+      return;
+    }
+    const data = await res.json();
+    if (!more) {
+      tbody.replaceChildren();
+    }
+    for (const e of data.events) {
+      tbody.appendChild(row(e));
+    }
+    next = data.next || 0;
+  } catch {
+    tbody.replaceChildren();
+    next = 0;
+  } finally {
+    $("more").hidden = next === 0;
+  }
+}
+
+function row(e) {
+  const tr = document.createElement("tr");
+  tr.title = JSON.stringify(e.payload, null, 2);
+  for (const v of [new Date(e.time).toLocaleTimeString(), e.eventType, e.sourceSystem, e.status, e.topic, e.messageId]) {
+    const td = document.createElement("td");
+    td.textContent = v;
+    tr.appendChild(td);
+  }
+  tr.addEventListener("click", () => {
+    $("response").textContent = JSON.stringify(e, null, 2);
+  });
+  return tr;
+}
+
+// maxLive: zoveel rijen houdt de live tail bij, de oudste vallen weg.
+const maxLive = 100;
+let live = null;
+
+// toggleLive start of stopt de live tail (GET /api/v1/recent/stream). De
+// EventSource herverbindt zelf en gaat dan verder vanaf het laatste event.
+function toggleLive() {
+  const tbody = $("live");
+  const status = $("liveStatus");
+  if (live) {
+    live.close();
+    live = null;
+    $("liveToggle").textContent = "Start";
+    status.textContent = "Gestopt.";
+    return;
+  }
+
+  tbody.replaceChildren();
+  live = new EventSource("/api/v1/recent/stream?backlog=10");
+  $("liveToggle").textContent = "Stop";
+  status.textContent = "Verbinden...";
+  live.addEventListener("open", () => {
+    status.textContent = "Live.";
+  });
+  live.addEventListener("error", () => {
+    // CLOSED na een fout status (bv. 404 zonder recent events), anders herverbindt hij
+    status.textContent =
+      live.readyState === EventSource.CLOSED ? "Niet beschikbaar." : "Verbinding verbroken, opnieuw verbinden...";
+  });
+  live.addEventListener("event", (msg) => {
+    tbody.prepend(row(JSON.parse(msg.data)));
+    while (tbody.children.length > maxLive) {
+      tbody.lastChild.remove();
+    }
+  });
+  live.addEventListener("missed", (msg) => {
+    status.textContent = "Live, " + msg.data + " events gemist (te traag).";
+  });
+}
+
+$("logout").hidden = !session;
+$("logout").addEventListener("click", logout);
+$("eventType").addEventListener("change", loadTemplate);
+$("single").addEventListener("click", setSingle);
+$("batch").addEventListener("click", setBatch);
+$("send").addEventListener("click", send);
+$("refresh").addEventListener("click", () => loadHistory());
+$("more").addEventListener("click", () => loadHistory(true));
+$("liveToggle").addEventListener("click", toggleLive);
+
+loadEventTypes();
+loadHistory();
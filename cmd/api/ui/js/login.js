@@ -0,0 +1,23 @@
+"use strict";
+
+// login ruilt de API key voor een session cookie; daarna geeft /ui de tester.
+document.getElementById("login").addEventListener("submit", async (ev) => {
+  ev.preventDefault();
+  const error = document.getElementById("error");
+  error.textContent = "";
+
+  try {
+    const res = await fetch("/ui/login", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ apiKey: document.getElementById("apiKey").value }),
+    });
+    if (!res.ok) {
+      const problem = await res.json().catch(() => ({}));
+      throw new Error(problem.detail || res.status);
+    }
+    location.reload();
+  } catch (e) {
+    error.textContent = "Inloggen mislukt: " + e.message;
+  }
+});
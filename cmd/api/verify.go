@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/health"
+)
+
+// topicVerifier controleert of elke topic waar serve naar schrijft (zie
+// checkTopics) bestaat en of de credentials erop mogen produceren, door er
+// de producer voor te maken; de eerste send gebruikt die dan. Bij het
+// opstarten, na een reload en elke pulsar.verifyTopics.interval.
+type topicVerifier struct {
+	clusters []*pulsarCluster
+	log      *zap.Logger
+	trigger  chan struct{} // een reload: opnieuw controleren
+
+	mu        sync.RWMutex
+	cfg       *config.Config
+	checked   int // aantal topics van de laatste controle
+	failed    []topicFailure
+	checkedAt time.Time // zero = nog niet gecontroleerd
+}
+
+// topicFailure is een topic die niet bestaat of waar niet op geproduceerd
+// mag worden.
+type topicFailure struct {
+	Cluster string `json:"cluster"`
+	Topic   string `json:"topic"`
+	Error   string `json:"error"`
+}
+
+func newTopicVerifier(cfg *config.Config, clusters []*pulsarCluster, log *zap.Logger) *topicVerifier {
+	return &topicVerifier{
+		clusters: clusters,
+		log:      log,
+		trigger:  make(chan struct{}, 1),
+		cfg:      cfg,
+	}
+}
+
+// update zet de config na een reload en controleert de topics opnieuw.
+func (v *topicVerifier) update(cfg *config.Config) {
+	v.mu.Lock()
+	v.cfg = cfg
+	v.mu.Unlock()
+	select {
+	case v.trigger <- struct{}{}:
+	default: // er staat al een controle klaar
+	}
+}
+
+// run controleert de topics tot ctx afloopt.
+func (v *topicVerifier) run(ctx context.Context) {
+	for {
+		v.verify(ctx)
+		v.mu.RLock()
+		interval := v.cfg.Pulsar.VerifyTopics.Interval
+		v.mu.RUnlock()
+		var timer *time.Timer
+		var tick <-chan time.Time
+		if interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-v.trigger:
+		case <-tick:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (v *topicVerifier) verify(ctx context.Context) {
+	v.mu.RLock()
+	cfg := v.cfg
+	v.mu.RUnlock()
+	topics := checkTopics(cfg)
+	checked := 0
+	var failed []topicFailure
+	for _, cl := range v.clusters {
+		for _, topic := range topics[cl.name] {
+			checked++
+			cctx, cancel := context.WithTimeout(ctx, cfg.Pulsar.VerifyTopics.Timeout)
+			err := cl.producers.Connect(cctx, topic)
+			cancel()
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				v.log.Warn("Topic check failed", zap.String("cluster", cl.name), zap.String("topic", topic), zap.Error(err))
+				failed = append(failed, topicFailure{Cluster: cl.name, Topic: topic, Error: err.Error()})
+			}
+		}
+	}
+	if len(failed) == 0 {
+		v.log.Info("Topic check passed", zap.Int("topics", checked))
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checked, v.failed, v.checkedAt = checked, failed, time.Now()
+}
+
+// health: degraded zolang een topic faalt; events naar de andere topics
+// gaan gewoon door.
+func (v *topicVerifier) health() health.Report {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.checkedAt.IsZero() {
+		return health.Report{Status: health.StatusOK, Info: map[string]interface{}{"state": "checking"}}
+	}
+	info := map[string]interface{}{"checked": v.checked, "checkedAt": v.checkedAt.UTC()}
+	if len(v.failed) > 0 {
+		info["failed"] = v.failed
+		return health.Report{Status: health.StatusDegraded, Info: info}
+	}
+	return health.Report{Status: health.StatusOK, Info: info}
+}
+
+// ready geeft 503 op /ready zolang een topic faalt, als
+// pulsar.verifyTopics.failReadiness aan staat. Vóór de eerste controle is
+// de replica ready, zodat een trage broker het opstarten niet tegenhoudt.
+func (v *topicVerifier) ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v.mu.RLock()
+		fail, failed := v.cfg.Pulsar.VerifyTopics.FailReadiness, v.failed
+		v.mu.RUnlock()
+		if fail && len(failed) > 0 {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"status": "topics unavailable", "topics": failed})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,380 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/deadletter"
+	"github.com/rubenclaes/pulsar-api/internal/health"
+	"github.com/rubenclaes/pulsar-api/internal/logging"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+)
+
+type AdminHandler struct {
+	Audit       *audit.Logger
+	Maintenance *middleware.Maintenance
+	Config      *config.Bus
+	LogLevel    zap.AtomicLevel
+	DeadLetter  *deadletter.Store // nil = deadLetter staat uit
+	Drain       *middleware.Drain
+	Shadow      *pulsar.Shadow // nil in dry-run
+	Health      *health.Checker
+	Events      *EventHandler // voor de geladen schema's
+	StartedAt   time.Time
+
+	ReloadSchemas func() error // compileert de schema's opnieuw na PUT /admin/schemas
+	schemaMu      sync.Mutex   // één schema wijziging tegelijk
+}
+
+func NewAdminHandler(auditLog *audit.Logger, maintenance *middleware.Maintenance, bus *config.Bus, logLevel zap.AtomicLevel) *AdminHandler {
+	return &AdminHandler{
+		Audit:       auditLog,
+		Maintenance: maintenance,
+		Config:      bus,
+		LogLevel:    logLevel,
+		StartedAt:   time.Now().UTC(),
+	}
+}
+
+// auditAdmin legt een admin actie vast in de audit log.
+func (h *AdminHandler) auditAdmin(c *gin.Context, action, result string, err error) {
+	e := audit.Entry{
+		Actor:         middleware.GetClientID(c),
+		ClientIP:      c.ClientIP(),
+		Action:        "admin." + action,
+		Result:        result,
+		CorrelationID: middleware.GetCorrelationID(c),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	h.Audit.Record(e)
+}
+
+// GET /admin/maintenance
+func (h *AdminHandler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Maintenance.Status())
+}
+
+// GET /admin/config
+// De effectieve config (file + env + defaults + flags) met de herkomst per key;
+// secrets zijn gemaskeerd.
+func (h *AdminHandler) GetConfig(c *gin.Context) {
+	h.auditAdmin(c, "config.read", audit.ResultOK, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"config": h.Config.Current().Effective(),
+	})
+}
+
+// PUT /admin/maintenance
+func (h *AdminHandler) PutMaintenance(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+
+	var req struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.auditAdmin(c, "maintenance", audit.ResultRejected, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid request body",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	h.Maintenance.Set(*req.Enabled, req.Message)
+	st := h.Maintenance.Status()
+
+	middleware.Logger(c).Warn("Maintenance mode changed",
+		zap.Bool("enabled", st.Enabled),
+		zap.String("message", st.Message),
+	)
+	h.auditAdmin(c, "maintenance", audit.ResultOK, nil)
+
+	c.JSON(http.StatusOK, st)
+}
+
+// GET /admin/log-level
+func (h *AdminHandler) GetLogLevel(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"level": h.LogLevel.Level().String()})
+}
+
+// PUT /admin/log-level
+// Geldt tot de volgende herstart, of tot logging.level in de config wijzigt.
+func (h *AdminHandler) PutLogLevel(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+
+	var req struct {
+		Level string `json:"level" binding:"required"`
+	}
+	err := c.ShouldBindJSON(&req)
+	var level zapcore.Level
+	if err == nil {
+		level, err = logging.ParseLevel(req.Level)
+	}
+	if err != nil {
+		h.auditAdmin(c, "log-level", audit.ResultRejected, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid request body",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	previous := h.LogLevel.Level()
+	h.LogLevel.SetLevel(level)
+
+	middleware.Logger(c).Warn("Log level changed",
+		zap.Stringer("from", previous),
+		zap.Stringer("to", level),
+	)
+	h.auditAdmin(c, "log-level", audit.ResultOK, nil)
+
+	c.JSON(http.StatusOK, gin.H{"level": level.String()})
+}
+
+// GET /admin/drain
+func (h *AdminHandler) GetDrain(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Drain.Status())
+}
+
+// POST /admin/drain?wait=30s
+// Zet /ready op 503. Met wait wacht het tot de replica gedrained is (200) of
+// wait verstreken is (202, nog niet gedrained), bv. vanuit een preStop hook.
+func (h *AdminHandler) PostDrain(c *gin.Context) {
+	var wait time.Duration
+	if w := c.Query("wait"); w != "" {
+		var err error
+		if wait, err = time.ParseDuration(w); err != nil || wait < 0 {
+			if err == nil {
+				err = errors.New("wait must not be negative")
+			}
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":        "error",
+				"error":         "invalid query",
+				"details":       err.Error(),
+				"correlationId": middleware.GetCorrelationID(c),
+			})
+			return
+		}
+	}
+
+	if h.Drain.Start() {
+		middleware.Logger(c).Warn("Draining, readiness is now false")
+		h.auditAdmin(c, "drain", audit.ResultOK, nil)
+	}
+
+	st := h.Drain.Status()
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		st = h.Drain.Wait(ctx)
+	}
+	if st.Drained {
+		c.JSON(http.StatusOK, st)
+		return
+	}
+	c.JSON(http.StatusAccepted, st)
+}
+
+// DELETE /admin/drain
+// Maakt de replica terug ready, bv. als de deployment afgebroken werd.
+func (h *AdminHandler) DeleteDrain(c *gin.Context) {
+	h.Drain.Cancel()
+	middleware.Logger(c).Warn("Drain cancelled, readiness is true again")
+	h.auditAdmin(c, "drain.cancel", audit.ResultOK, nil)
+	c.JSON(http.StatusOK, h.Drain.Status())
+}
+
+// GET /admin/shadow
+// Hoeveel kopieën de shadow cluster kreeg, en hoeveel niet (divergentie).
+func (h *AdminHandler) GetShadow(c *gin.Context) {
+	if h.Shadow == nil {
+		c.JSON(http.StatusOK, pulsar.ShadowStats{})
+		return
+	}
+	c.JSON(http.StatusOK, h.Shadow.Stats())
+}
+
+// GET /admin/diagnostics
+// Alles voor een supportticket in één document: waar de config vandaan komt,
+// de schema's, de routes, de toestand van de componenten (Pulsar, spool, ...)
+// en de uptime. Geen secrets.
+func (h *AdminHandler) GetDiagnostics(c *gin.Context) {
+	cfg := h.Config.Current()
+	bus := h.Config.Status()
+	h.auditAdmin(c, "diagnostics.read", audit.ResultOK, nil)
+
+	configInfo := gin.H{"sources": cfg.Sources(), "loadedAt": bus.LoadedAt.UTC()}
+	if bus.Failure != nil {
+		configInfo["reloadError"] = bus.Failure.Error()
+		configInfo["failedAt"] = bus.FailedAt.UTC()
+	}
+
+	clusters := cfg.RouteClusters()
+	fallbacks := cfg.FallbackTopics()
+	shadowTopics := cfg.ShadowTopics()
+	patterns := cfg.RoutePatterns()
+	routes := make(map[string]gin.H, len(cfg.Routes))
+	for et, r := range cfg.Routes {
+		route := gin.H{"topic": r.Topic, "cluster": pulsar.DefaultCluster}
+		if cl, ok := clusters[et]; ok {
+			route["cluster"] = cl
+		}
+		if fb := fallbacks[et]; fb != "" {
+			route["fallbackTopic"] = fb
+		}
+		if st := shadowTopics[r.Topic]; st != "" {
+			route["shadowTopic"] = st
+		}
+		if p := patterns[et]; p != "" {
+			route["pattern"] = p
+		}
+		routes[et] = route
+	}
+
+	status, components := h.Health.Check()
+	c.JSON(http.StatusOK, gin.H{
+		"startedAt":    h.StartedAt,
+		"uptime":       time.Since(h.StartedAt).Round(time.Second).String(),
+		"goVersion":    runtime.Version(),
+		"dryRun":       cfg.API.DryRun,
+		"config":       configInfo,
+		"schemas":      h.Events.SchemaEventTypes(),
+		"defaultTopic": cfg.Pulsar.DefaultTopic,
+		"routes":       routes,
+		"health":       gin.H{"status": status, "components": components},
+		"maintenance":  h.Maintenance.Status(),
+		"drain":        h.Drain.Status(),
+	})
+}
+
+// deadLetterStore geeft de store, of schrijft een 404 als deadLetter uit staat.
+func (h *AdminHandler) deadLetterStore(c *gin.Context) *deadletter.Store {
+	if h.DeadLetter == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "dead-letter store not enabled",
+			"correlationId": middleware.GetCorrelationID(c),
+		})
+	}
+	return h.DeadLetter
+}
+
+// GET /admin/failures?eventType=&correlationId=&since=&limit=
+// Nieuwste eerst, standaard de laatste 100. De requests zijn niet
+// geredacteerd, dus het lezen komt in de audit log.
+func (h *AdminHandler) ListFailures(c *gin.Context) {
+	store := h.deadLetterStore(c)
+	if store == nil {
+		return
+	}
+	corrID := middleware.GetCorrelationID(c)
+
+	f := deadletter.Filter{
+		EventType:     c.Query("eventType"),
+		CorrelationID: c.Query("correlationId"),
+		Limit:         100,
+	}
+	var err error
+	if since := c.Query("since"); since != "" {
+		f.Since, err = time.Parse(time.RFC3339, since)
+	}
+	if limit := c.Query("limit"); err == nil && limit != "" {
+		f.Limit, err = strconv.Atoi(limit)
+		if err == nil && f.Limit < 1 {
+			err = errors.New("limit must be at least 1")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid query",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	failures, err := store.List(f)
+	if err != nil {
+		middleware.Logger(c).Error("failed to list dead letters", zap.Error(err))
+		h.auditAdmin(c, "failures.read", audit.ResultFailed, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":        "error",
+			"error":         "failed to read dead-letter store",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+	h.auditAdmin(c, "failures.read", audit.ResultOK, nil)
+	c.JSON(http.StatusOK, gin.H{"count": len(failures), "failures": failures})
+}
+
+// GET /admin/failures/:id
+func (h *AdminHandler) GetFailure(c *gin.Context) {
+	store := h.deadLetterStore(c)
+	if store == nil {
+		return
+	}
+	e, err := store.Get(c.Param("id"))
+	if err != nil {
+		h.failureError(c, "failures.read", err)
+		return
+	}
+	h.auditAdmin(c, "failures.read", audit.ResultOK, nil)
+	c.JSON(http.StatusOK, e)
+}
+
+// DELETE /admin/failures/:id
+// Bv. nadat het event opnieuw ingestuurd is.
+func (h *AdminHandler) DeleteFailure(c *gin.Context) {
+	store := h.deadLetterStore(c)
+	if store == nil {
+		return
+	}
+	if err := store.Delete(c.Param("id")); err != nil {
+		h.failureError(c, "failures.delete", err)
+		return
+	}
+	h.auditAdmin(c, "failures.delete", audit.ResultOK, nil)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AdminHandler) failureError(c *gin.Context, action string, err error) {
+	corrID := middleware.GetCorrelationID(c)
+	if errors.Is(err, deadletter.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "dead letter not found",
+			"correlationId": corrID,
+		})
+		return
+	}
+	middleware.Logger(c).Error("dead-letter store error", zap.Error(err))
+	h.auditAdmin(c, action, audit.ResultFailed, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"status":        "error",
+		"error":         "failed to read dead-letter store",
+		"details":       err.Error(),
+		"correlationId": corrID,
+	})
+}
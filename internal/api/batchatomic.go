@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/quota"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+)
+
+// batchModeAtomic (?mode=atomic): eerst alle items controleren en niets
+// publiceren als één item faalt.
+const batchModeAtomic = "atomic"
+
+// inFlightWait is de pauze van sendAtomicItem tussen twee pogingen.
+const inFlightWait = 50 * time.Millisecond
+
+// errAtomicBatchRejected: het item is geldig, maar een ander item van de
+// atomic batch niet.
+var errAtomicBatchRejected = errors.New("not published, another item of the atomic batch failed")
+
+// checkBatchItem doet de controles van publishBatchItem zonder te
+// publiceren: versie, validate, enrichment, autorisatie en de schema
+// registry. Een geweigerd item staat al in de audit log.
+func (h *EventHandler) checkBatchItem(c *gin.Context, i int, req EventRequest, corrID string) (BatchItemResult, bool) {
+	deprecation, err := h.resolveVersion(&req)
+	r := BatchItemResult{
+		Index:         i,
+		Status:        "valid",
+		CorrelationID: corrID,
+		Event:         h.echo(req),
+	}
+	if err != nil {
+		r.Status, r.Error = "error", err.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	if deprecation != "" {
+		r.Warnings = []string{deprecation}
+	}
+	h.applyDefaults(req)
+	if err := h.validate(c, req); err != nil {
+		r.Status, r.Error, r.Errors = "error", err.Error(), validationErrors(err)
+		if _, msg := validateStatus(err); msg == "schema validation failed" {
+			r.Error = msg + ": " + err.Error()
+		}
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	// een lookup die faalt moet de hele batch tegenhouden; publishBatchItem
+	// voert de ketting opnieuw uit
+	if err := h.enrichPayload(c, req); err != nil {
+		_, msg := enrichmentStatus(err)
+		r.Status, r.Error = "error", msg+": "+err.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	// de envelope en de grootte zoals in publishBatchItem, voor de echo en
+	// de quota van de hele batch
+	buf, payload, err := h.marshal(c, &req)
+	if err != nil {
+		r.Status, r.Error = "error", "marshal error: "+err.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	r.Bytes = len(payload)
+	buf.release()
+	r.Event = h.echo(req)
+
+	topic, err := h.enrich(c, req)
+	if err == nil {
+		_, err = h.fanOutTopics(c, req, topic)
+	}
+	r.Topic = topic
+	r.Cluster = h.resolveCluster(req)
+	if err != nil {
+		_, msg := enrichStatus(err)
+		r.Status, r.Error = "error", msg+": "+err.Error()
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	if err := h.checkRegistry(c, req, topic); err != nil {
+		r.Status, r.Error, r.Errors = "error", "schema validation failed: "+err.Error(), validationErrors(err)
+		if errors.Is(err, schema.ErrRegistryUnavailable) {
+			r.Error = err.Error()
+		}
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, 0, err)
+		return r, false
+	}
+	return r, true
+}
+
+// checkAtomicBatch controleert alle items van een atomic batch en geeft hun
+// grootte terug. Faalt er één, dan antwoordt het met 400 en de resultaten,
+// en worden de geldige items als niet gepubliceerd in de audit log gezet;
+// false = stoppen.
+func (h *EventHandler) checkAtomicBatch(c *gin.Context, reqs []EventRequest, corrID string) ([]int, bool) {
+	results := make([]BatchItemResult, len(reqs))
+	valid := make([]bool, len(reqs))
+	h.forEachItem(len(reqs), func(i int) {
+		results[i], valid[i] = h.checkBatchItem(c, i, reqs[i], corrID)
+	})
+
+	failed := 0
+	for _, ok := range valid {
+		if !ok {
+			failed++
+		}
+	}
+	if failed == 0 {
+		sizes := make([]int, len(results))
+		for i, r := range results {
+			sizes[i] = r.Bytes
+		}
+		return sizes, true
+	}
+	for i, ok := range valid {
+		if ok {
+			results[i].Status, results[i].Error = "skipped", errAtomicBatchRejected.Error()
+			h.recordPublish(c, reqs[i], results[i].Topic, "", audit.ResultRejected, 0, errAtomicBatchRejected)
+		}
+	}
+
+	middleware.Logger(c).Warn("atomic batch rejected",
+		zap.Int("batch.size", len(reqs)),
+		zap.Int("failed", failed),
+	)
+	c.JSON(http.StatusBadRequest, gin.H{
+		"status":        "error",
+		"error":         "batch rejected",
+		"details":       fmt.Sprintf("%d of %d items failed, nothing was published", failed, len(reqs)),
+		"count":         len(reqs),
+		"dryRun":        h.isDryRun(),
+		"results":       results,
+		"correlationId": corrID,
+	})
+	return nil, false
+}
+
+// reserveAtomicBatch boekt de quota van alle items vóór de eerste send, zodat
+// een atomic batch niet halverwege op de quota stuit. Past de batch niet,
+// dan antwoordt het met 429 en wordt niets gepubliceerd; false = stoppen.
+func (h *EventHandler) reserveAtomicBatch(c *gin.Context, reqs []EventRequest, sizes []int, client, corrID string) bool {
+	total := 0
+	for _, n := range sizes {
+		total += n
+	}
+	err := h.Quotas.ReserveBatch(client, len(reqs), total)
+	if err == nil {
+		return true
+	}
+	for i, req := range reqs {
+		h.recordPublish(c, req, "", "", audit.ResultRejected, sizes[i], err)
+	}
+	middleware.Logger(c).Warn("quota exceeded", zap.Error(err), zap.Int("batch.size", len(reqs)))
+	var qe *quota.ExceededError
+	if errors.As(err, &qe) {
+		c.Header("Retry-After", strconv.Itoa(int(qe.RetryAfter.Seconds())+1))
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"status":        "error",
+		"error":         "quota exceeded",
+		"details":       fmt.Sprintf("%d events of %d bytes: %v, nothing was published", len(reqs), total, err),
+		"correlationId": corrID,
+	})
+	return false
+}
+
+// sendAtomicItem is send voor een item van een atomic batch: bij
+// ErrTooManyInFlight wordt gewacht op een vrije plaats (tot de request
+// afloopt) in plaats van het item te weigeren terwijl de rest verstuurd is.
+func (h *EventHandler) sendAtomicItem(c *gin.Context, req EventRequest, topic string, payload []byte, corrID string) (pulsar.MessageID, string, error) {
+	for {
+		id, sentTo, err := h.send(c, req, topic, payload, corrID)
+		if !errors.Is(err, pulsar.ErrTooManyInFlight) {
+			return id, sentTo, err
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return id, sentTo, err
+		case <-time.After(inFlightWait):
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+)
+
+// SetBatchStreamThreshold zet vanaf hoeveel bytes een batch gestreamd
+// verwerkt wordt (0 = nooit), ook bij een config reload.
+func (h *EventHandler) SetBatchStreamThreshold(n int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.BatchStreamThreshold = n
+}
+
+// streamBatch: de body is groter dan de drempel, of de grootte is niet
+// gekend (chunked).
+func (h *EventHandler) streamBatch(c *gin.Context) bool {
+	h.mu.RLock()
+	threshold := h.BatchStreamThreshold
+	h.mu.RUnlock()
+	return threshold > 0 && (c.Request.ContentLength < 0 || c.Request.ContentLength > threshold)
+}
+
+type batchItem struct {
+	i   int
+	req EventRequest
+	err error // ongeldig item, niet publiceren
+}
+
+// postBatchStream verwerkt een grote batch item per item: de array wordt
+// gestreamd gedecodeerd en de resultaten in volgorde gestreamd geschreven,
+// zodat het geheugen niet met de grootte van de batch groeit. Het antwoord
+// is pas na de '[' vastgelegd (200); een fout in de JSON daarna komt als
+// "status": "error" aan het einde van de response.
+func (h *EventHandler) postBatchStream(c *gin.Context) {
+	log := middleware.Logger(c)
+	corrID := middleware.GetCorrelationID(c)
+	dryRun := h.isDryRun()
+	client := quotaClient(c)
+
+	ce, ceBatch := h.cloudEventsFor(c)
+	mt, err := h.mediaType(c)
+	if err == nil && ce != nil && !ceBatch {
+		err = fmt.Errorf("content type %s is for /api/v1/events, use %s", cloudEventsContentType, cloudEventsBatchContentType)
+	}
+	if err != nil {
+		log.Warn("invalid batch body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid batch body",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+	dec := json.NewDecoder(c.Request.Body)
+	if h.isStrict("") {
+		dec.DisallowUnknownFields()
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		if err == nil {
+			err = errors.New("expected a JSON array")
+		}
+		log.Warn("invalid batch body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid batch body",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, `{"dryRun":%t,"results":[`, dryRun)
+
+	// workers publiceren; de writer schrijft de resultaten in volgorde. Een
+	// item wordt pas gedecodeerd als er een slot vrij is, zodat een traag
+	// item de wachtende resultaten niet onbeperkt laat groeien.
+	parallelism := h.batchParallelism()
+	slots := make(chan struct{}, 2*parallelism)
+	items := make(chan batchItem)
+	results := make(chan BatchItemResult, parallelism)
+
+	var workers sync.WaitGroup
+	for range parallelism {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for it := range items {
+				if it.err != nil {
+					h.recordPublish(c, it.req, "", "", audit.ResultRejected, 0, it.err)
+					results <- BatchItemResult{Index: it.i, Status: "error", Error: "invalid item: " + it.err.Error(), Errors: validationErrors(it.err), CorrelationID: corrID}
+					continue
+				}
+				results <- h.publishBatchItem(c, it.i, it.req, corrID, client, dryRun, 0)
+			}
+		}()
+	}
+
+	written := make(chan int)
+	go func() {
+		pending := map[int]BatchItemResult{}
+		next := 0
+		for r := range results {
+			pending[r.Index] = r
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				if next > 0 {
+					_, _ = io.WriteString(c.Writer, ",")
+				}
+				line, _ := json.Marshal(r)
+				_, _ = c.Writer.Write(line)
+				next++
+				<-slots
+			}
+		}
+		written <- next
+	}()
+
+	count, decodeErr := 0, error(nil)
+	for dec.More() {
+		var req EventRequest
+		var err error
+		if ce != nil {
+			req, err = ce.decode(dec, schema.Pointer("", count), h.isStrict)
+		} else {
+			err = dec.Decode(&req)
+		}
+		var verr *schema.ValidationError
+		var typeErr *json.UnmarshalTypeError
+		// een type-, (strict) onbekend veld of CloudEvents fout geldt enkel voor dit item
+		if err != nil && !errors.As(err, &verr) && !errors.As(err, &typeErr) && !strings.HasPrefix(err.Error(), "json: unknown field") {
+			decodeErr = err // syntaxfout: de rest van de body is onleesbaar
+			break
+		}
+		if err == nil && mt != nil {
+			err = mt.apply(&req)
+		}
+		if err == nil {
+			err = binding.Validator.ValidateStruct(req)
+		}
+		slots <- struct{}{}
+		items <- batchItem{i: count, req: req, err: err}
+		count++
+	}
+	if decodeErr == nil {
+		if _, err := dec.Token(); err != nil { // de afsluitende ']'
+			decodeErr = err
+		}
+	}
+	close(items)
+	workers.Wait()
+	close(results)
+	<-written
+
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(
+		attribute.Int("batch.size", count),
+		attribute.String("correlation_id", corrID),
+	)
+
+	status := "sent"
+	if dryRun {
+		status = "dry-run"
+	}
+	tail := gin.H{"count": count, "status": status}
+	if decodeErr != nil {
+		log.Warn("invalid batch body", zap.Error(decodeErr), zap.Int("itemsProcessed", count))
+		tail = gin.H{
+			"count":         count,
+			"status":        "error",
+			"error":         "invalid batch body",
+			"details":       decodeErr.Error(),
+			"correlationId": corrID,
+		}
+	}
+	line, _ := json.Marshal(tail)
+	_, _ = io.WriteString(c.Writer, "],"+string(line[1:]))
+}
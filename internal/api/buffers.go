@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledBuffer: grotere buffers (uitzonderlijk grote events) niet
+// bijhouden, anders blijft dat geheugen voor altijd in de pool.
+const maxPooledBuffer = 1 << 20
+
+// eventBuffer is een herbruikbare buffer met zijn encoder, om bij hoge
+// event rates niet voor elk event een nieuwe byte slice te alloceren.
+type eventBuffer struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+var eventBuffers = sync.Pool{
+	New: func() interface{} {
+		b := &eventBuffer{}
+		b.enc = json.NewEncoder(&b.buf)
+		return b
+	},
+}
+
+// marshalEvent serialiseert req zoals json.Marshal, in een buffer uit de
+// pool. De bytes blijven geldig tot release.
+func marshalEvent(req EventRequest) (*eventBuffer, []byte, error) {
+	b := eventBuffers.Get().(*eventBuffer)
+	b.buf.Reset()
+	if err := b.enc.Encode(req); err != nil {
+		b.release()
+		return nil, nil, err
+	}
+	return b, bytes.TrimSuffix(b.buf.Bytes(), []byte("\n")), nil
+}
+
+// payloadSize is de grootte van payload zoals marshalEvent hem schrijft,
+// zonder de bytes bij te houden.
+func payloadSize(payload map[string]interface{}) (int64, error) {
+	var n countingWriter
+	if err := json.NewEncoder(&n).Encode(payload); err != nil {
+		return 0, err
+	}
+	return int64(n) - 1, nil // zonder de newline van Encode
+}
+
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// release geeft de buffer terug aan de pool. Enkel als niemand de bytes nog
+// gebruikt: na een mislukte send kan de Pulsar client ze nog vasthouden (de
+// request context liep af terwijl het bericht in de send queue zat).
+func (b *eventBuffer) release() {
+	if b.buf.Cap() > maxPooledBuffer {
+		return
+	}
+	eventBuffers.Put(b)
+}
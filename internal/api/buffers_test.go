@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func benchEvent() EventRequest {
+	return EventRequest{
+		EventType:    "WAGE_ERROR",
+		SourceSystem: "EverESSt",
+		Payload: map[string]interface{}{
+			"dossierId": "ABC-123",
+			"employer":  "0123456789",
+			"message":   "Loonberekening mislukt voor periode 2024-05",
+			"amount":    1234.56,
+		},
+	}
+}
+
+func TestMarshalEventReusedBuffer(t *testing.T) {
+	large := benchEvent()
+	large.Payload["message"] = strings.Repeat("x", 4096)
+	b, _, err := marshalEvent(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.release()
+
+	// een kleiner event in een (mogelijk) hergebruikte buffer mag niets van
+	// het vorige event bevatten
+	small := benchEvent()
+	for i := 0; i < 10; i++ {
+		b, got, err := marshalEvent(small)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(small)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("marshalEvent = %s, want %s", got, want)
+		}
+		b.release()
+	}
+}
+
+func BenchmarkMarshalEvent(b *testing.B) {
+	req := benchEvent()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf, _, err := marshalEvent(req)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf.release()
+	}
+}
+
+func BenchmarkJSONMarshal(b *testing.B) {
+	req := benchEvent()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
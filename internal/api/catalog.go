@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// EventTypeInfo beschrijft een eventType in de catalogus: waar het naartoe
+// gaat, of er een schema is, de versies en de standaardwaarden, zodat een
+// client weet hoe het gepubliceerde bericht eruit zal zien.
+type EventTypeInfo struct {
+	EventType      string                     `json:"eventType"`
+	Topic          string                     `json:"topic"`
+	Schema         bool                       `json:"schema"`
+	DefaultVersion int                        `json:"defaultVersion,omitempty"`
+	Versions       []int                      `json:"versions,omitempty"`   // ondersteunde versies, leeg = elke
+	Deprecated     []int                      `json:"deprecated,omitempty"` // deprecated versies
+	Defaults       map[string]json.RawMessage `json:"defaults,omitempty"`   // veld in de payload → waarde als het ontbreekt
+}
+
+// catalogEventTypes geeft alle eventTypes die de config kent (routes zonder
+// wildcard of pattern, schema's, versies en standaardwaarden), lowercase en
+// gesorteerd.
+func (h *EventHandler) catalogEventTypes() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	seen := map[string]bool{}
+	add := func(key string) {
+		if et, _, _ := strings.Cut(key, "@"); et != "" && et != allEventTypes {
+			seen[strings.ToLower(et)] = true
+		}
+	}
+	for key := range h.Routes {
+		if !h.isRoutePattern(key) {
+			add(key)
+		}
+	}
+	for _, key := range h.Schemas.EventTypes() {
+		add(key)
+	}
+	for key := range h.Versions {
+		add(key)
+	}
+	for key := range h.Defaults {
+		add(key)
+	}
+	out := make([]string, 0, len(seen))
+	for et := range seen {
+		out = append(out, et)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// eventTypeInfo geeft de catalogus entry van eventType (lowercase).
+func (h *EventHandler) eventTypeInfo(eventType string) EventTypeInfo {
+	req := EventRequest{EventType: strings.ToUpper(eventType)}
+	h.mu.RLock()
+	v := h.Versions[eventType]
+	hasSchema := h.Schemas.Has(eventType)
+	h.mu.RUnlock()
+	info := EventTypeInfo{
+		EventType:      req.EventType,
+		Topic:          h.resolveTopic(req),
+		Schema:         hasSchema,
+		DefaultVersion: v.Default,
+		Versions:       slices.Clone(v.Supported),
+		Defaults:       h.defaultsOf(eventType),
+	}
+	for version := range v.Deprecated {
+		info.Deprecated = append(info.Deprecated, version)
+	}
+	slices.Sort(info.Deprecated)
+	return info
+}
+
+// GET /api/v1/event-types
+func (h *EventHandler) GetEventTypes(c *gin.Context) {
+	eventTypes := h.catalogEventTypes()
+	infos := make([]EventTypeInfo, len(eventTypes))
+	for i, et := range eventTypes {
+		infos[i] = h.eventTypeInfo(et)
+	}
+	c.JSON(http.StatusOK, gin.H{"count": len(infos), "eventTypes": infos})
+}
+
+// GET /api/v1/event-types/:eventType
+func (h *EventHandler) GetEventType(c *gin.Context) {
+	et, ok := h.catalogEventType(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, h.eventTypeInfo(et))
+}
+
+// catalogEventType geeft het eventType van de URL (lowercase); false met
+// een 404 als de catalogus het niet kent.
+func (h *EventHandler) catalogEventType(c *gin.Context) (string, bool) {
+	et := strings.ToLower(c.Param("eventType"))
+	if !slices.Contains(h.catalogEventTypes(), et) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "unknown eventType",
+			"details":       "no route, schema, versions or defaults for " + c.Param("eventType"),
+			"correlationId": middleware.GetCorrelationID(c),
+		})
+		return "", false
+	}
+	return et, true
+}
+
+// EventTemplate is een voorbeeld request voor een eventType, afgeleid van
+// zijn schema (zie schema.Registry.Template), zodat de tester UI nieuwe
+// eventTypes meteen kent.
+type EventTemplate struct {
+	EventType    string       `json:"eventType"`
+	EventVersion int          `json:"eventVersion,omitempty"`
+	Schema       bool         `json:"schema"`   // false = geen schema, een lege payload
+	Required     []string     `json:"required"` // JSON Pointers van de verplichte velden in Body
+	Body         EventRequest `json:"body"`
+}
+
+// GET /api/v1/event-types/:eventType/template?version=
+// Zonder version de default versie van het eventType. sourceSystem is het
+// eerste dat de client mag gebruiken, of leeg als elk mag.
+func (h *EventHandler) GetEventTemplate(c *gin.Context) {
+	et, ok := h.catalogEventType(c)
+	if !ok {
+		return
+	}
+	req := EventRequest{EventType: strings.ToUpper(et), Payload: map[string]interface{}{}}
+	if v := c.Query("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":        "error",
+				"error":         "invalid version",
+				"details":       fmt.Sprintf("version %q must be a positive number", v),
+				"correlationId": middleware.GetCorrelationID(c),
+			})
+			return
+		}
+		req.EventVersion = n
+	}
+	if _, err := h.resolveVersion(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "unsupported event version",
+			"details":       err.Error(),
+			"correlationId": middleware.GetCorrelationID(c),
+		})
+		return
+	}
+	req.SourceSystem = h.exampleSourceSystem(c)
+
+	h.mu.RLock()
+	schemas := h.Schemas
+	h.mu.RUnlock()
+	key := et
+	if req.EventVersion > 0 && schemas.Has(versionKey(et, req.EventVersion)) {
+		key = versionKey(et, req.EventVersion)
+	}
+	t := EventTemplate{EventType: req.EventType, EventVersion: req.EventVersion, Required: []string{}}
+	if tmpl, ok := schemas.Template(key); ok {
+		t.Schema, t.Required, req.Payload = true, tmpl.Required, tmpl.Payload
+	}
+	t.Body = req
+	c.JSON(http.StatusOK, t)
+}
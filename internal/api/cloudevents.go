@@ -0,0 +1,290 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"mime"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+)
+
+// content types van CloudEvents in structured mode (één event of een batch)
+const (
+	cloudEventsContentType      = "application/cloudevents+json"
+	cloudEventsBatchContentType = "application/cloudevents-batch+json"
+)
+
+// cloudEventsCore zijn de attributen van CloudEvents 1.0 die geen extension zijn.
+var cloudEventsCore = []string{"id", "source", "specversion", "type", "datacontenttype", "dataschema", "subject", "time", "data", "data_base64"}
+
+// ceAttributeName: een attribuut naam volgens de spec.
+var ceAttributeName = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// cloudEvents zet CloudEvents om naar een EventRequest: type → eventType
+// (zonder typePrefix), source → sourceSystem, time → occurredAt, data →
+// payload.
+type cloudEvents struct {
+	typePrefix string
+	extensions map[string]cloudEventExtension
+}
+
+type cloudEventExtension struct {
+	required bool
+	typ      string // string, integer, boolean, uri of timestamp
+	pattern  *regexp.Regexp
+}
+
+// SetCloudEvents zet de CloudEvents ingestion aan of uit, met de extension
+// attributen die gecontroleerd worden (ook bij een config reload).
+func (h *EventHandler) SetCloudEvents(enabled bool, typePrefix string, extensions map[string]config.CloudEventExtension) {
+	var ce *cloudEvents
+	if enabled {
+		ce = &cloudEvents{typePrefix: typePrefix, extensions: make(map[string]cloudEventExtension, len(extensions))}
+		for name, ext := range extensions {
+			e := cloudEventExtension{required: ext.Required, typ: ext.Type}
+			if e.typ == "" {
+				e.typ = "string"
+			}
+			if ext.Pattern != "" {
+				e.pattern = regexp.MustCompile(ext.Pattern) // gecontroleerd in config.Validate
+			}
+			ce.extensions[strings.ToLower(name)] = e
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.CloudEvents = ce
+}
+
+// cloudEventsFor geeft de CloudEvents instellingen als de request een
+// CloudEvent (of een batch ervan) is, anders nil.
+func (h *EventHandler) cloudEventsFor(c *gin.Context) (ce *cloudEvents, batch bool) {
+	h.mu.RLock()
+	ce = h.CloudEvents
+	h.mu.RUnlock()
+	if ce == nil {
+		return nil, false
+	}
+	full, _, err := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch {
+	case err != nil:
+		return nil, false
+	case full == cloudEventsContentType:
+		return ce, false
+	case full == cloudEventsBatchContentType:
+		return ce, true
+	}
+	return nil, false
+}
+
+// bindCloudEvents decodeert een CloudEvent in een *EventRequest, of een
+// batch in een *[]EventRequest. Alle problemen met de attributen komen
+// samen in een *schema.ValidationError.
+func (h *EventHandler) bindCloudEvents(c *gin.Context, ce *cloudEvents, batch bool, obj any) error {
+	dec := json.NewDecoder(c.Request.Body)
+	var problems []schema.Problem
+	switch obj := obj.(type) {
+	case *EventRequest:
+		if batch {
+			return fmt.Errorf("content type %s is for /api/v1/events/batch, use %s", cloudEventsBatchContentType, cloudEventsContentType)
+		}
+		req, err := ce.decode(dec, "", h.isStrict)
+		*obj = req
+		return err
+	case *[]EventRequest:
+		if !batch {
+			return fmt.Errorf("content type %s is for /api/v1/events, use %s", cloudEventsContentType, cloudEventsBatchContentType)
+		}
+		var ms []map[string]interface{}
+		if err := dec.Decode(&ms); err != nil {
+			return err
+		}
+		*obj = make([]EventRequest, len(ms))
+		for i, m := range ms {
+			var p []schema.Problem
+			(*obj)[i], p = ce.request(m, schema.Pointer("", i), h.isStrict)
+			problems = append(problems, p...)
+		}
+	}
+	if len(problems) > 0 {
+		return &schema.ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// decode leest het volgende CloudEvent uit dec en zet het om, zie request.
+func (ce *cloudEvents) decode(dec *json.Decoder, loc string, strict func(eventType string) bool) (EventRequest, error) {
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return EventRequest{}, err
+	}
+	req, problems := ce.request(m, loc, strict)
+	if len(problems) > 0 {
+		return req, &schema.ValidationError{Problems: problems}
+	}
+	return req, nil
+}
+
+// request controleert de attributen van het CloudEvent m (op JSON Pointer
+// loc in de body) en zet het om. Met strict voor het eventType is een
+// attribuut dat geen core attribuut of geconfigureerde extension is een fout.
+func (ce *cloudEvents) request(m map[string]interface{}, loc string, strict func(eventType string) bool) (EventRequest, []schema.Problem) {
+	var req EventRequest
+	var out []schema.Problem
+	fail := func(name, rule, format string, args ...interface{}) {
+		out = append(out, schema.Problem{Path: schema.Pointer(loc, name), Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, name := range []string{"id", "source", "specversion", "type"} {
+		v, ok := m[name]
+		s, isString := v.(string)
+		switch {
+		case !ok:
+			fail(name, "required", "missing property '%s'", name)
+		case !isString:
+			fail(name, "type", "got %s, want string", jsonKind(v))
+		case s == "":
+			fail(name, "minLength", "must not be empty")
+		}
+	}
+	if v, ok := m["specversion"].(string); ok && v != "" && v != "1.0" {
+		fail("specversion", "const", "specversion %q is not supported, want 1.0", v)
+	}
+	if t, ok := m["type"].(string); ok && t != "" {
+		eventType, ok := redact.CloudEventType(t, ce.typePrefix)
+		if !ok {
+			fail("type", "pattern", "'%s' does not start with '%s'", t, ce.typePrefix)
+		} else {
+			req.EventType = eventType
+		}
+	}
+	req.SourceSystem, _ = m["source"].(string)
+
+	for _, name := range []string{"subject", "datacontenttype", "dataschema", "time"} {
+		if v, ok := m[name]; ok {
+			if _, isString := v.(string); !isString {
+				fail(name, "type", "got %s, want string", jsonKind(v))
+			}
+		}
+	}
+	if ct, ok := m["datacontenttype"].(string); ok {
+		if full, _, err := mime.ParseMediaType(ct); err != nil || (full != "application/json" && !strings.HasSuffix(full, "+json")) {
+			fail("datacontenttype", "pattern", "'%s' is not JSON, want application/json", ct)
+		}
+	}
+	if s, ok := m["dataschema"].(string); ok {
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			fail("dataschema", "format", "'%s' is not valid 'uri'", s)
+		}
+	}
+	if s, ok := m["time"].(string); ok {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			fail("time", "format", "'%s' is not valid 'date-time'", s)
+		} else {
+			req.OccurredAt = &t
+		}
+	}
+
+	if _, ok := m["data_base64"]; ok {
+		fail("data_base64", "additionalProperties", "binary data is not supported, send the payload as JSON in data")
+	}
+	switch data := m["data"].(type) {
+	case map[string]interface{}:
+		req.Payload = data
+	case nil:
+		if _, ok := m["data"]; !ok {
+			fail("data", "required", "missing property 'data'")
+		} else {
+			fail("data", "type", "got null, want object")
+		}
+	default:
+		fail("data", "type", "got %s, want object", jsonKind(data))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(ce.extensions)) {
+		if _, ok := m[name]; !ok && ce.extensions[name].required {
+			fail(name, "required", "missing property '%s'", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		if slices.Contains(cloudEventsCore, name) {
+			continue
+		}
+		ext, known := ce.extensions[name]
+		switch {
+		case !ceAttributeName.MatchString(name):
+			fail(name, "propertyNames", "attribute name '%s' must be 1-20 lowercase letters or digits", name)
+		case known:
+			ext.check(m[name], func(rule, format string, args ...interface{}) { fail(name, rule, format, args...) })
+		case strict(req.EventType):
+			fail(name, "additionalProperties", "additional properties '%s' not allowed (strict)", name)
+		}
+	}
+	return req, out
+}
+
+// check controleert de waarde van een extension attribuut.
+func (e cloudEventExtension) check(v interface{}, fail func(rule, format string, args ...interface{})) {
+	switch e.typ {
+	case "integer":
+		if n, ok := v.(float64); !ok || n != math.Trunc(n) || math.Abs(n) > math.MaxInt32 {
+			fail("type", "got %s, want integer", jsonKind(v))
+		}
+		return
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("type", "got %s, want boolean", jsonKind(v))
+		}
+		return
+	}
+	s, ok := v.(string)
+	if !ok {
+		fail("type", "got %s, want string", jsonKind(v))
+		return
+	}
+	switch e.typ {
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			fail("format", "'%s' is not valid 'uri'", s)
+		}
+	case "timestamp":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			fail("format", "'%s' is not valid 'date-time'", s)
+		}
+	}
+	if e.pattern != nil && !e.pattern.MatchString(s) {
+		fail("pattern", "'%s' does not match pattern '%s'", s, e.pattern)
+	}
+}
+
+// jsonKind is het JSON type van een gedecodeerde waarde, voor de meldingen.
+func jsonKind(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	}
+	return "object"
+}
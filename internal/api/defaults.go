@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+)
+
+// allEventTypes is de config key voor standaardwaarden van elk eventType.
+const allEventTypes = "*"
+
+// fieldDefault is een standaardwaarde, als JSON zodat elk event een eigen
+// kopie krijgt (een latere stap mag de payload aanpassen).
+type fieldDefault struct {
+	field string
+	path  []string
+	value json.RawMessage
+}
+
+// SetDefaults zet de standaardwaarden per eventType ("*" = alle), ook bij
+// een config reload. Een waarde die geen JSON kan zijn (zie config.Validate)
+// wordt overgeslagen.
+func (h *EventHandler) SetDefaults(defaults map[string][]config.Default) {
+	compiled := make(map[string][]fieldDefault, len(defaults))
+	for eventType, list := range defaults {
+		key := strings.ToLower(eventType)
+		for _, d := range list {
+			raw, err := json.Marshal(d.Value)
+			if err != nil || strings.TrimSpace(d.Field) == "" {
+				continue
+			}
+			path := strings.Split(strings.TrimSpace(d.Field), ".")
+			compiled[key] = append(compiled[key], fieldDefault{field: d.Field, path: path, value: raw})
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Defaults = compiled
+}
+
+// applyDefaults zet de standaardwaarden van het eventType in de velden die
+// de caller wegliet, vóór validate; die van het eventType winnen van "*".
+// Een veld dat er staat (ook met null) blijft ongewijzigd.
+func (h *EventHandler) applyDefaults(req EventRequest) {
+	h.mu.RLock()
+	own, all := h.Defaults[strings.ToLower(req.EventType)], h.Defaults[allEventTypes]
+	h.mu.RUnlock()
+	if req.Payload == nil {
+		return
+	}
+	for _, list := range [][]fieldDefault{own, all} {
+		for _, d := range list {
+			setDefault(req.Payload, d.path, d.value)
+		}
+	}
+}
+
+// setDefault zet path op value als het ontbreekt; ontbrekende objecten
+// onderweg worden aangemaakt, een ander type onderweg laat het veld weg.
+func setDefault(payload map[string]interface{}, path []string, value json.RawMessage) {
+	for _, key := range path[:len(path)-1] {
+		v, ok := payload[key]
+		if !ok {
+			v = map[string]interface{}{}
+			payload[key] = v
+		}
+		child, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		payload = child
+	}
+	last := path[len(path)-1]
+	if _, ok := payload[last]; ok {
+		return
+	}
+	var v interface{}
+	if json.Unmarshal(value, &v) == nil {
+		payload[last] = v
+	}
+}
+
+// defaultsOf geeft de standaardwaarden die voor eventType gelden, veld →
+// waarde, voor de catalogus.
+func (h *EventHandler) defaultsOf(eventType string) map[string]json.RawMessage {
+	h.mu.RLock()
+	own, all := h.Defaults[strings.ToLower(eventType)], h.Defaults[allEventTypes]
+	h.mu.RUnlock()
+	if len(own)+len(all) == 0 {
+		return nil
+	}
+	out := make(map[string]json.RawMessage, len(own)+len(all))
+	for _, list := range [][]fieldDefault{all, own} { // die van het eventType winnen
+		for _, d := range list {
+			out[d.field] = d.value
+		}
+	}
+	return out
+}
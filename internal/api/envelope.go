@@ -0,0 +1,55 @@
+package api
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// errMissingOccurredAt: envelope.requireOccurredAt staat aan en de request
+// heeft geen occurredAt (400).
+var errMissingOccurredAt = errors.New("missing occurredAt")
+
+// SetEnvelope zet de envelope (ook bij een config reload): enabled stempelt
+// eventId, ingestedAt, gateway en schemaVersion in elk bericht,
+// requireOccurredAt weigert events zonder occurredAt. gateway "" = hostname.
+func (h *EventHandler) SetEnvelope(enabled, requireOccurredAt bool, gateway string) {
+	if gateway == "" {
+		gateway, _ = os.Hostname()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Envelope = enabled
+	h.RequireOccurredAt = requireOccurredAt
+	h.Gateway = gateway
+}
+
+// checkOccurredAt weigert een event zonder occurredAt als dat verplicht is.
+func (h *EventHandler) checkOccurredAt(req EventRequest) error {
+	h.mu.RLock()
+	required := h.RequireOccurredAt
+	h.mu.RUnlock()
+	if required && req.OccurredAt == nil {
+		return errMissingOccurredAt
+	}
+	return nil
+}
+
+// stamp vult de envelope van req in. Wat de caller zelf in eventId,
+// ingestedAt, gateway of schemaVersion zette, komt nooit op Pulsar.
+func (h *EventHandler) stamp(req *EventRequest) {
+	req.EventID, req.IngestedAt, req.Gateway, req.SchemaVersion = "", nil, "", 0
+	h.mu.RLock()
+	enabled, gateway := h.Envelope, h.Gateway
+	h.mu.RUnlock()
+	if !enabled {
+		return
+	}
+	now := time.Now().UTC()
+	req.EventID = uuid.NewString()
+	req.IngestedAt = &now
+	req.Gateway = gateway
+	req.SchemaVersion = req.EventVersion // na resolveVersion: de versie waarmee gevalideerd is
+}
@@ -1,42 +1,91 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/authz"
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/deadletter"
+	"github.com/rubenclaes/pulsar-api/internal/enrich"
+	"github.com/rubenclaes/pulsar-api/internal/metrics"
 	"github.com/rubenclaes/pulsar-api/internal/middleware"
 	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/quota"
+	"github.com/rubenclaes/pulsar-api/internal/recent"
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+	"github.com/rubenclaes/pulsar-api/internal/routing"
+	"github.com/rubenclaes/pulsar-api/internal/rules"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+	"github.com/rubenclaes/pulsar-api/internal/sentry"
+	"github.com/rubenclaes/pulsar-api/internal/spool"
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
 )
 
 type EventRequest struct {
 	EventType    string                 `json:"eventType" binding:"required"`
+	EventVersion int                    `json:"eventVersion,omitempty" binding:"min=0"` // 0 = versions.<eventType>.default
 	SourceSystem string                 `json:"sourceSystem" binding:"required"`
 	Payload      map[string]interface{} `json:"payload" binding:"required"`
+	OccurredAt   *time.Time             `json:"occurredAt,omitempty"` // wanneer het event gebeurde (RFC 3339), door de caller
+	Priority     string                 `json:"priority,omitempty"`   // bv. high: de priority lane, zie isPriority
+
+	// de envelope: door de API ingevuld als envelope.enabled, zie stamp
+	EventID       string     `json:"eventId,omitempty"`
+	IngestedAt    *time.Time `json:"ingestedAt,omitempty"`
+	Gateway       string     `json:"gateway,omitempty"`       // instance die het event aannam
+	SchemaVersion int        `json:"schemaVersion,omitempty"` // eventVersion waartegen gevalideerd is
 }
 
 type EventResponse struct {
 	Status        string        `json:"status"`
 	Topic         string        `json:"topic"`
+	Cluster       string        `json:"cluster,omitempty"` // leeg = default
 	Bytes         int           `json:"bytes"`
 	DryRun        bool          `json:"dryRun"`
 	CorrelationID string        `json:"correlationId"`
 	MessageID     string        `json:"messageId,omitempty"`
+	EventID       string        `json:"eventId,omitempty"`  // envelope.enabled
+	Fallback      bool          `json:"fallback,omitempty"` // verstuurd naar de fallback topic (Topic)
+	Warnings      []string      `json:"warnings,omitempty"` // bv. een deprecated eventVersion
 	Event         *EventRequest `json:"event,omitempty"`
+
+	Destinations []Destination `json:"destinations,omitempty"` // bij fan-out: Topic en de kopieën
 }
 
 type BatchItemResult struct {
 	Index         int           `json:"index"`
 	Status        string        `json:"status"`
 	Topic         string        `json:"topic,omitempty"`
+	Cluster       string        `json:"cluster,omitempty"`
 	Bytes         int           `json:"bytes,omitempty"`
 	MessageID     string        `json:"messageId,omitempty"`
+	EventID       string        `json:"eventId,omitempty"`
 	Error         string        `json:"error,omitempty"`
+	Fallback      bool          `json:"fallback,omitempty"`
+	DeadLetterID  string        `json:"deadLetterId,omitempty"`
+	Warnings      []string      `json:"warnings,omitempty"`
 	CorrelationID string        `json:"correlationId"`
 	Event         *EventRequest `json:"event,omitempty"`
+
+	Errors       []schema.Problem `json:"errors,omitempty"`       // bij een validatiefout, per veld
+	Destinations []Destination    `json:"destinations,omitempty"` // bij fan-out
 }
 
 type BatchResponse struct {
@@ -46,235 +95,1280 @@ type BatchResponse struct {
 	Results []BatchItemResult `json:"results"`
 }
 
-// simpele mapping eventType -> Pulsar topic
-var eventTypeTopicMap = map[string]string{
-	"SIGNALITIEK_ERROR": "persistent://tenant/ns/signalitiek-errors",
-	"WAGE_ERROR":        "persistent://tenant/ns/wage-errors",
-	// default: valt terug op main topic uit env
+// echo geeft het event terug zoals het in de response komt, met de payload
+// geredacteerd; req zelf (wat naar Pulsar gaat) blijft ongewijzigd.
+func (h *EventHandler) echo(req EventRequest) *EventRequest {
+	req.Payload = h.Redactor.Payload(req.EventType, req.Payload)
+	return &req
+}
+
+// validate, marshal en enrich zijn de stappen van de pipeline met elk een
+// eigen span (zie tracing.Stage); send heeft er ook een.
+func (h *EventHandler) validate(c *gin.Context, req EventRequest) (err error) {
+	_, span := tracing.Stage(c.Request.Context(), "validate", attribute.String("event.type", req.EventType))
+	defer func() { tracing.End(span, err) }()
+	if err := h.checkSourceSystem(c, req); err != nil {
+		return err
+	}
+	if err := h.checkOccurredAt(req); err != nil {
+		return err
+	}
+	if err := h.checkPayloadSize(req); err != nil {
+		return err
+	}
+	return h.validateEventSchema(req)
+}
+
+// validateStatus geeft de HTTP status en de fout van een mislukte validate.
+func validateStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errPayloadTooLarge):
+		return http.StatusRequestEntityTooLarge, "payload too large"
+	case errors.Is(err, errUnknownSourceSystem):
+		return http.StatusBadRequest, "unknown sourceSystem"
+	case errors.Is(err, errMissingOccurredAt):
+		return http.StatusBadRequest, "missing occurredAt"
+	case errors.Is(err, errSourceSystemNotAllowed):
+		return http.StatusForbidden, "not authorized"
+	}
+	return http.StatusBadRequest, "schema validation failed"
+}
+
+// errorBody is de JSON van een mislukte publish; bij een validatiefout
+// komen de problemen per veld erbij in "errors".
+func errorBody(msg string, err error, corrID string) gin.H {
+	body := gin.H{
+		"status":        "error",
+		"error":         msg,
+		"details":       err.Error(),
+		"correlationId": corrID,
+	}
+	if problems := validationErrors(err); problems != nil {
+		body["errors"] = problems
+	}
+	return body
+}
+
+// validationErrors geeft de problemen van een schema.ValidationError in err.
+func validationErrors(err error) []schema.Problem {
+	var verr *schema.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Problems
+	}
+	return nil
+}
+
+// errPayloadTooLarge: de payload is groter dan routes.<eventType>.maxPayloadBytes (413).
+var errPayloadTooLarge = errors.New("payload too large")
+
+func (h *EventHandler) checkPayloadSize(req EventRequest) error {
+	h.mu.RLock()
+	limit := h.MaxBytes[h.routeKey(req)]
+	h.mu.RUnlock()
+	if limit <= 0 {
+		return nil
+	}
+	size, err := payloadSize(req.Payload)
+	if err != nil {
+		return nil // marshal geeft dezelfde fout
+	}
+	if size > limit {
+		return fmt.Errorf("%w: payload of %s is %d bytes, the limit is %d bytes", errPayloadTooLarge, req.EventType, size, limit)
+	}
+	return nil
+}
+
+// enrichPayload voert de enrichment ketting van het eventType uit, tussen
+// validate en marshal; req.Payload wordt aangevuld.
+func (h *EventHandler) enrichPayload(c *gin.Context, req EventRequest) (err error) {
+	h.mu.RLock()
+	chains := h.Enrichment
+	h.mu.RUnlock()
+	if !chains.Has(req.EventType) {
+		return nil
+	}
+	ctx, span := tracing.Stage(c.Request.Context(), "enrichment", attribute.String("event.type", req.EventType))
+	defer func() { tracing.End(span, err) }()
+	return chains.Apply(ctx, req.EventType, req.Payload)
+}
+
+// enrichmentStatus geeft de HTTP status en de fout van een mislukte enrichPayload.
+func enrichmentStatus(err error) (int, string) {
+	if errors.Is(err, enrich.ErrUnavailable) {
+		return http.StatusServiceUnavailable, "enrichment unavailable"
+	}
+	return http.StatusBadRequest, "enrichment failed"
+}
+
+// marshal stempelt de envelope in req (zie stamp) en serialiseert het.
+func (h *EventHandler) marshal(c *gin.Context, req *EventRequest) (_ *eventBuffer, payload []byte, err error) {
+	_, span := tracing.Stage(c.Request.Context(), "marshal")
+	defer func() {
+		span.SetAttributes(attribute.Int("messaging.message.body.size", len(payload)))
+		tracing.End(span, err)
+	}()
+	h.stamp(req)
+	return marshalEvent(*req)
+}
+
+// enrich bepaalt de topic (van een routing regel of de route, met de
+// tenant, zie tenantTopic, en de suffix van de priority lane) en cluster van
+// het event en of de client er mag publiceren; de topic ook als dat niet mag.
+func (h *EventHandler) enrich(c *gin.Context, req EventRequest) (topic string, err error) {
+	_, span := tracing.Stage(c.Request.Context(), "enrich")
+	defer func() { tracing.End(span, err) }()
+	topic = h.resolveTopic(req)
+	if d, ok := h.route(c, req); ok {
+		span.SetAttributes(attribute.String("pulsar.routing_rule", d.Rule))
+		if d.Topic != "" {
+			topic = d.Topic
+		}
+	}
+	if topic, err = h.tenantTopic(c, topic); err != nil {
+		return topic, err
+	}
+	if h.isPriority(c, req) {
+		topic = h.priorityTopic(topic)
+		span.SetAttributes(attribute.Bool("pulsar.priority", true))
+	}
+	if err = h.checkIsolation(c, topic); err != nil {
+		return topic, err
+	}
+	span.SetAttributes(attribute.String("messaging.destination.name", topic))
+	if cluster := h.resolveCluster(req); cluster != "" {
+		span.SetAttributes(attribute.String("pulsar.cluster", cluster))
+	}
+	return topic, h.authorize(c, req, topic)
+}
+
+// checkRegistry controleert de payload tegen het schema van de topic in de
+// schema registry van Pulsar (TopicSchemas).
+func (h *EventHandler) checkRegistry(c *gin.Context, req EventRequest, topic string) (err error) {
+	if h.TopicSchemas == nil {
+		return nil
+	}
+	ctx, span := tracing.Stage(c.Request.Context(), "registry", attribute.String("messaging.destination.name", topic))
+	defer func() { tracing.End(span, err) }()
+	cluster := h.resolveCluster(req)
+	if cluster == "" {
+		cluster = pulsar.DefaultCluster
+	}
+	return h.TopicSchemas.Validate(ctx, cluster, topic, req.Payload)
 }
 
-// super simpele “schema”-checks per eventType
-func validateEventSchema(req EventRequest) error {
-	switch req.EventType {
-	case "SIGNALITIEK_ERROR":
-		if _, ok := req.Payload["errorCode"]; !ok {
-			return errors.New("payload.errorCode is required for SIGNALITIEK_ERROR")
+// validateEventSchema controleert de payload tegen het JSON Schema en de
+// regels van het eventType; de problemen van beide komen samen.
+func (h *EventHandler) validateEventSchema(req EventRequest) error {
+	h.mu.RLock()
+	schemas, checks := h.Schemas, h.Rules
+	h.mu.RUnlock()
+	key := req.EventType
+	if req.EventVersion > 0 && schemas.Has(versionKey(req.EventType, req.EventVersion)) {
+		key = versionKey(req.EventType, req.EventVersion)
+	}
+	err := schemas.Validate(key, req.Payload, h.isStrict(req.EventType))
+	problems := checks.Check(req.EventType, req.Payload)
+	if len(problems) == 0 {
+		return err
+	}
+	var ve *schema.ValidationError
+	if errors.As(err, &ve) {
+		problems = append(slices.Clone(ve.Problems), problems...)
+	} else if err != nil {
+		return err
+	}
+	return &schema.ValidationError{Problems: problems}
+}
+
+// bindJSON decodeert de body in obj; in strict mode (voor alle eventTypes)
+// is een onbekend veld in de envelope een fout. Een vendor media type vult
+// eventType en eventVersion in, zie mediaType; een CloudEvent wordt
+// omgezet, zie bindCloudEvents.
+func (h *EventHandler) bindJSON(c *gin.Context, obj any) error {
+	if ce, batch := h.cloudEventsFor(c); ce != nil {
+		if err := h.bindCloudEvents(c, ce, batch, obj); err != nil {
+			return err
 		}
-		if _, ok := req.Payload["employerId"]; !ok {
-			return errors.New("payload.employerId is required for SIGNALITIEK_ERROR")
+		return binding.Validator.ValidateStruct(obj)
+	}
+	mt, err := h.mediaType(c)
+	if err != nil {
+		return err
+	}
+	if !h.isStrict("") && mt == nil {
+		return c.ShouldBindJSON(obj)
+	}
+	dec := json.NewDecoder(c.Request.Body)
+	if h.isStrict("") {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(obj); err != nil {
+		return err
+	}
+	if mt != nil {
+		switch obj := obj.(type) {
+		case *EventRequest:
+			err = mt.apply(obj)
+		case *[]EventRequest:
+			for i := range *obj {
+				if err = mt.apply(&(*obj)[i]); err != nil {
+					err = fmt.Errorf("[%d]: %w", i, err)
+					break
+				}
+			}
 		}
-	case "WAGE_ERROR":
-		if _, ok := req.Payload["dossierId"]; !ok {
-			return errors.New("payload.dossierId is required for WAGE_ERROR")
+		if err != nil {
+			return err
 		}
 	}
-	return nil
+	return binding.Validator.ValidateStruct(obj)
 }
 
 type EventHandler struct {
-	Logger    *zap.Logger
-	Producer  *pulsar.Producer
-	Topic     string // default topic
-	SchemaMap map[string]string
-	DryRun    bool
+	Publisher  pulsar.Publisher  // nil als de service in dry-run gestart is
+	Topic      string            // default topic
+	Routes     map[string]string // eventType (lowercase) -> topic, uit config "routes"
+	Fallbacks  map[string]string // eventType (lowercase) -> fallback topic
+	Clusters   map[string]string // eventType (lowercase) -> cluster, ontbrekend = default
+	MaxBytes   map[string]int64  // eventType (lowercase) -> max. bytes van de payload
+	Schemas    *schema.Registry
+	DryRun     bool
+	Strict     bool            // onbekende velden weigeren, voor alle eventTypes
+	StrictFor  map[string]bool // eventType (lowercase) → strict
+	Rules      *rules.Set      // validatieregels uit de config, nil = geen
+	Enrichment *enrich.Chains  // enrichment kettingen uit de config, nil = geen
+	Routing    *routing.Rules  // routing regels vóór Routes, nil = geen
+	mu         sync.RWMutex    // beschermt DryRun, Routes, RoutePatterns, Fallbacks, Clusters, MaxBytes, Schemas, Strict, Rules, Enrichment, Routing, Versions, Defaults, SourceSystems, SourceSystemRoutes, MediaTypeVendor, CloudEvents, de tenants, de priority lane, de envelope en de Batch velden bij een config reload
+	Audit      *audit.Logger
+	Redactor   *redact.Redactor
+	Quotas     *quota.Tracker
+	Authz      *authz.Policy
+
+	BatchParallelism     int   // aantal items van een batch dat tegelijk gepubliceerd wordt
+	BatchStreamThreshold int64 // batches boven zoveel bytes gestreamd verwerken, 0 = nooit
+
+	Spool      *spool.Spool      // nil = geen spool, een onbereikbare Pulsar geeft een fout
+	DeadLetter *deadletter.Store // nil = mislukte events enkel in de response en de logs
+	Metrics    *metrics.Metrics  // nil = geen metrics
+	Recent     *recent.Buffer    // nil = recent.size 0
+
+	TopicSchemas *schema.TopicSchemas // nil = pulsar.schemaRegistry uit
+
+	RoutePatterns []routePattern // routes op een familie van eventTypes, zie routeKey
+
+	Versions map[string]config.VersionConfig // eventType (lowercase) → versies, zie resolveVersion
+	Defaults map[string][]fieldDefault       // eventType (lowercase, "*" = alle) → standaardwaarden, zie applyDefaults
+
+	SourceSystems       []string            // gekende sourceSystems, leeg = elke
+	ClientSourceSystems map[string][]string // client (lowercase) → sourceSystems, ontbrekend = alle gekende
+
+	SourceSystemRoutes map[string]map[string]string // sourceSystem → eventType ("*" = alle) → topic, lowercase; wint van Routes
+
+	MediaTypeVendor string       // application/vnd.<vendor>.<eventType>[.v<n>]+json, "" = uit
+	CloudEvents     *cloudEvents // nil = CloudEvents ingestion uit
+
+	TenantHeader   string            // header met de tenant, zie tenant
+	DefaultTenant  string            // tenant zonder header, "" = verplicht voor topics met {tenant}
+	KnownTenants   []string          // gekende tenants, leeg = elke
+	ClientTenants  map[string]string // client (lowercase) → tenant of tenant/namespace
+	IsolateTenants bool              // clients uit ClientTenants enkel naar hun eigen tenant
+
+	PriorityLane        *middleware.PriorityLane // nil = geen priority lane
+	PriorityTopicSuffix string                   // achter de topic van een priority event, "" = dezelfde topic
+
+	Envelope          bool   // eventId, ingestedAt, gateway en schemaVersion in elk bericht, zie stamp
+	RequireOccurredAt bool   // events zonder occurredAt weigeren
+	Gateway           string // instance naam in de envelope
 }
 
-func NewEventHandler(logger *zap.Logger, producer *pulsar.Producer, topic string, dryRun bool, schemaMap map[string]string) *EventHandler {
+func NewEventHandler(publisher pulsar.Publisher, topic string, routes map[string]string, dryRun bool, schemas *schema.Registry, auditLog *audit.Logger, redactor *redact.Redactor, quotas *quota.Tracker, policy *authz.Policy) *EventHandler {
 	return &EventHandler{
-		Logger:    logger,
-		Producer:  producer,
+		Publisher: publisher,
 		Topic:     topic,
+		Routes:    lowerKeys(routes),
 		DryRun:    dryRun,
-		SchemaMap: schemaMap,
+		Schemas:   schemas,
+		Audit:     auditLog,
+		Redactor:  redactor,
+		Quotas:    quotas,
+		Authz:     policy,
 	}
 }
 
+func (h *EventHandler) authorize(c *gin.Context, req EventRequest, topic string) error {
+	return h.Authz.Authorize(middleware.GetClientID(c), middleware.GetClientScopes(c), req.EventType, topic)
+}
+
+// ApplyConfig zet de instellingen die bij een config reload kunnen wijzigen.
+func (h *EventHandler) ApplyConfig(dryRun bool, routes map[string]string, schemas *schema.Registry) {
+	routes = lowerKeys(routes)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.DryRun = dryRun
+	h.Routes = routes
+	h.Schemas = schemas
+}
+
+// SetSchemas zet de JSON Schema's, bv. na een wijziging in de schema registry.
+func (h *EventHandler) SetSchemas(schemas *schema.Registry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Schemas = schemas
+}
+
+// SetStrict zet strict mode voor alle eventTypes (all) of enkel voor
+// eventTypes (ook bij een config reload).
+func (h *EventHandler) SetStrict(all bool, eventTypes []string) {
+	strictFor := make(map[string]bool, len(eventTypes))
+	for _, et := range eventTypes {
+		strictFor[strings.ToLower(et)] = true
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Strict = all
+	h.StrictFor = strictFor
+}
+
+// SetEnrichment zet de enrichment kettingen (ook bij een config reload).
+func (h *EventHandler) SetEnrichment(chains *enrich.Chains) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Enrichment = chains
+}
+
+// SetRouting zet de routing regels (ook bij een config reload).
+func (h *EventHandler) SetRouting(rs *routing.Rules) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Routing = rs
+}
+
+// SetRules zet de validatieregels (ook bij een config reload).
+func (h *EventHandler) SetRules(set *rules.Set) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Rules = set
+}
+
+// isStrict meldt of eventType in strict mode gevalideerd wordt; "" vraagt
+// naar de globale instelling.
+func (h *EventHandler) isStrict(eventType string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.Strict || h.StrictFor[strings.ToLower(eventType)]
+}
+
+// SchemaEventTypes geeft de eventTypes met een JSON Schema.
+func (h *EventHandler) SchemaEventTypes() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.Schemas.EventTypes()
+}
+
+// viper lowercased map keys; eventTypes worden case-insensitief opgezocht
+func lowerKeys(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// SetFallbackTopics zet eventType → fallback topic (ook bij een config reload).
+func (h *EventHandler) SetFallbackTopics(fallbacks map[string]string) {
+	fallbacks = lowerKeys(fallbacks)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Fallbacks = fallbacks
+}
+
+// SetPayloadLimits zet eventType → max. grootte van de payload (ook bij een config reload).
+func (h *EventHandler) SetPayloadLimits(limits map[string]int64) {
+	lower := make(map[string]int64, len(limits))
+	for et, n := range limits {
+		lower[strings.ToLower(et)] = n
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.MaxBytes = lower
+}
+
+// SetRouteClusters zet eventType → Pulsar cluster (ook bij een config reload).
+func (h *EventHandler) SetRouteClusters(clusters map[string]string) {
+	clusters = lowerKeys(clusters)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Clusters = clusters
+}
+
+// SetBatchParallelism zet api.batch.parallelism (ook bij een config reload).
+func (h *EventHandler) SetBatchParallelism(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.BatchParallelism = n
+}
+
+func (h *EventHandler) batchParallelism() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return max(h.BatchParallelism, 1)
+}
+
+func (h *EventHandler) isDryRun() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.DryRun
+}
+
+// quotaClient is de sleutel waarop quota geteld worden.
+func quotaClient(c *gin.Context) string {
+	if id := middleware.GetClientID(c); id != "" {
+		return id
+	}
+	return "anonymous"
+}
+
+// recordPublish legt de uitkomst van één publish vast in de audit log en de
+// metrics, en een aanvaard event in Recent; bytes is de grootte van het
+// event, 0 als het niet zo ver kwam.
+func (h *EventHandler) recordPublish(c *gin.Context, req EventRequest, topic, msgID, result string, bytes int, err error) {
+	h.Metrics.Event(middleware.GetClientID(c), req.EventType, req.SourceSystem, topic, metricResult(result, topic, err), bytes)
+
+	e := audit.Entry{
+		Actor:         middleware.GetClientID(c),
+		ClientIP:      c.ClientIP(),
+		Action:        "publish",
+		EventType:     req.EventType,
+		SourceSystem:  req.SourceSystem,
+		Payload:       h.Redactor.Payload(req.EventType, req.Payload),
+		Topic:         topic,
+		MessageID:     msgID,
+		Result:        result,
+		CorrelationID: middleware.GetCorrelationID(c),
+	}
+	if topic != "" {
+		e.Cluster = h.resolveCluster(req)
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	h.Audit.Record(e)
+
+	switch result {
+	case audit.ResultSent, audit.ResultFallback, audit.ResultDryRun, audit.ResultSpooled:
+		if h.Recent != nil {
+			h.Recent.Add(recent.Event{
+				Time:          time.Now().UTC(),
+				CorrelationID: e.CorrelationID,
+				ClientID:      e.Actor,
+				EventType:     req.EventType,
+				SourceSystem:  req.SourceSystem,
+				Topic:         topic,
+				Status:        result,
+				MessageID:     msgID,
+				EventID:       req.EventID,
+				Bytes:         bytes,
+				Payload:       e.Payload,
+			})
+		}
+	}
+}
+
+// metricResult vertaalt een audit result naar dat van de metrics. Body en
+// JSON Schema worden gevalideerd vóór de routing, dus zonder topic is een
+// weigering een validatiefout; met topic enkel die van de schema registry.
+func metricResult(result, topic string, err error) string {
+	switch result {
+	case audit.ResultRejected:
+		if topic == "" || errors.As(err, new(*schema.ValidationError)) {
+			return metrics.ResultValidationFailed
+		}
+		return metrics.ResultRejected
+	case audit.ResultFailed:
+		return metrics.ResultSendFailed
+	}
+	return result // sent, fallback, dry-run en spooled heten hetzelfde
+}
+
+// resolveCluster geeft de cluster van de route, "" voor de default cluster.
+func (h *EventHandler) resolveCluster(req EventRequest) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.Clusters[h.routeKey(req)]
+}
+
+// route geeft de beslissing van de eerste routing regel die op het event
+// past; false als er geen past.
+func (h *EventHandler) route(c *gin.Context, req EventRequest) (routing.Decision, bool) {
+	h.mu.RLock()
+	rs := h.Routing
+	h.mu.RUnlock()
+	return rs.Route(routing.Event{
+		EventType:    req.EventType,
+		SourceSystem: req.SourceSystem,
+		Payload:      req.Payload,
+		Header:       c.Request.Header,
+	})
+}
+
+// resolveTopic geeft de topic van het sourceSystem voor het eventType (of
+// "*"), anders die van de route, anders de default topic.
 func (h *EventHandler) resolveTopic(req EventRequest) string {
-	if t, ok := eventTypeTopicMap[req.EventType]; ok {
+	h.mu.RLock()
+	bySource := h.SourceSystemRoutes[strings.ToLower(req.SourceSystem)]
+	t, ok := h.Routes[h.routeKey(req)]
+	h.mu.RUnlock()
+	if topic := bySource[strings.ToLower(req.EventType)]; topic != "" {
+		return topic
+	}
+	if topic := bySource[allEventTypes]; topic != "" {
+		return topic
+	}
+	if ok {
 		return t
 	}
 	// fallback naar default topic
 	return h.Topic
 }
 
+// send publiceert op topic en, als die onbereikbaar is en de route een
+// fallback topic heeft, daarna op de fallback topic. Geeft de topic terug
+// waarop het event staat; mislukken beide, dan de fout van de eerste.
+func (h *EventHandler) send(c *gin.Context, req EventRequest, topic string, payload []byte, corrID string) (id pulsar.MessageID, sentTo string, err error) {
+	opts := h.sendOptions(c, req, corrID)
+	ctx, span := tracing.Stage(c.Request.Context(), "send",
+		attribute.String("messaging.destination.name", topic),
+		attribute.Int("messaging.message.body.size", len(payload)),
+	)
+	if opts.Cluster != "" {
+		span.SetAttributes(attribute.String("pulsar.cluster", opts.Cluster))
+	}
+	start := time.Now()
+	defer func() {
+		result := metrics.ResultSent
+		if err != nil {
+			result = metrics.ResultSendFailed
+		} else if sentTo != topic {
+			span.SetAttributes(attribute.String("pulsar.fallback_topic", sentTo))
+			result = metrics.ResultFallback
+		}
+		if !errors.Is(err, pulsar.ErrTooManyInFlight) { // niet geprobeerd, geen latency
+			h.Metrics.Send(ctx, topic, result, time.Since(start))
+		}
+		tracing.End(span, err)
+	}()
+
+	id, err = h.Publisher.Send(ctx, topic, payload, opts)
+	if err == nil || !pulsar.IsUnavailable(err) {
+		return id, topic, err
+	}
+	h.mu.RLock()
+	fallback := h.Fallbacks[h.routeKey(req)]
+	h.mu.RUnlock()
+	if fallback == "" {
+		return id, topic, err
+	}
+	fallback, _ = h.tenantTopic(c, fallback) // de tenant lukte al voor topic
+	if h.checkIsolation(c, fallback) != nil {
+		return id, topic, err
+	}
+
+	log := middleware.Logger(c).With(zap.String("topic", topic), zap.String("fallbackTopic", fallback))
+	fid, ferr := h.Publisher.Send(ctx, fallback, payload, opts)
+	if ferr != nil {
+		log.Warn("fallback topic failed too", zap.Error(ferr), zap.NamedError("topicError", err))
+		return "", topic, err
+	}
+	log.Warn("topic unavailable, event sent to fallback topic", zap.Error(err))
+	return fid, fallback, nil
+}
+
+// sendStarted: de send mislukte nadat de payload aan de client gegeven werd;
+// bij ErrTooManyInFlight is hij niet geprobeerd.
+func sendStarted(err error) bool {
+	return err != nil && !errors.Is(err, pulsar.ErrTooManyInFlight)
+}
+
+// sendOptions geeft de cluster van de route, de priority lane en de key en
+// properties van de routing regel die past; de correlation ID wint van een
+// property.
+func (h *EventHandler) sendOptions(c *gin.Context, req EventRequest, corrID string) pulsar.SendOptions {
+	opts := pulsar.SendOptions{
+		Properties: map[string]string{},
+		Cluster:    h.resolveCluster(req),
+		Priority:   h.isPriority(c, req),
+	}
+	if d, ok := h.route(c, req); ok {
+		opts.Key = d.Key
+		maps.Copy(opts.Properties, d.Properties)
+	}
+	opts.Properties[pulsar.CorrelationIDProperty] = corrID
+	return opts
+}
+
 // POST /api/v1/events
 func (h *EventHandler) PostEvent(c *gin.Context) {
-	log := h.Logger.With(
-		zap.String("path", c.FullPath()),
-		zap.String("method", c.Request.Method),
-	)
+	log := middleware.Logger(c)
 	corrID := middleware.GetCorrelationID(c)
+	dryRun := h.isDryRun()
 
 	var req EventRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Warn("invalid request body", zap.Error(err), zap.String("correlationId", corrID))
+	_, stage := tracing.Stage(c.Request.Context(), "bind",
+		attribute.Int64("http.request.body.size", c.Request.ContentLength))
+	err := h.bindJSON(c, &req)
+	tracing.End(stage, err)
+	if err != nil {
+		log.Warn("invalid request body", zap.Error(err))
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		c.JSON(http.StatusBadRequest, errorBody("invalid request body", err, corrID))
+		return
+	}
+
+	span := trace.SpanFromContext(c.Request.Context())
+	span.SetAttributes(
+		attribute.String("event.type", req.EventType),
+		attribute.String("event.source_system", req.SourceSystem),
+		attribute.String("correlation_id", corrID),
+	)
+	sentry.SetTag(c, "eventType", req.EventType)
+
+	deprecation, err := h.resolveVersion(&req)
+	if err != nil {
+		log.Warn("unsupported event version", zap.Error(err), zap.String("eventType", req.EventType))
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":        "error",
-			"error":         "invalid request body",
+			"error":         "unsupported event version",
 			"details":       err.Error(),
 			"correlationId": corrID,
 		})
 		return
 	}
+	if req.EventVersion > 0 {
+		span.SetAttributes(attribute.Int("event.version", req.EventVersion))
+	}
+	if deprecation != "" {
+		log.Warn("deprecated event version",
+			zap.String("eventType", req.EventType),
+			zap.Int("eventVersion", req.EventVersion),
+			zap.String("sourceSystem", req.SourceSystem),
+		)
+		c.Header("Deprecation", "true")
+	}
 
-	if err := validateEventSchema(req); err != nil {
-		log.Warn("schema validation failed",
+	h.applyDefaults(req)
+	if err := h.validate(c, req); err != nil {
+		status, msg := validateStatus(err)
+		log.Warn(msg,
 			zap.Error(err),
 			zap.String("eventType", req.EventType),
-			zap.String("correlationId", corrID),
 		)
-		c.JSON(http.StatusBadRequest, gin.H{
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		c.JSON(status, errorBody(msg, err, corrID))
+		return
+	}
+
+	if err := h.enrichPayload(c, req); err != nil {
+		status, msg := enrichmentStatus(err)
+		log.Warn(msg,
+			zap.Error(err),
+			zap.String("eventType", req.EventType),
+		)
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		c.JSON(status, errorBody(msg, err, corrID))
+		return
+	}
+
+	buf, payloadBytes, err := h.marshal(c, &req)
+	if err != nil {
+		log.Error("failed to marshal payload", zap.Error(err))
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		_ = c.Error(err) // voor Sentry
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":        "error",
-			"error":         "schema validation failed",
-			"details":       err.Error(),
+			"error":         "internal serialization error",
 			"correlationId": corrID,
 		})
 		return
 	}
+	sendFailed := false // dan de buffer niet hergebruiken, zie release
+	defer func() {
+		if !sendFailed {
+			buf.release()
+		}
+	}()
 
-	payloadBytes, err := json.Marshal(req)
+	topic, err := h.enrich(c, req)
+	var fanOut []string
+	if err == nil {
+		fanOut, err = h.fanOutTopics(c, req, topic)
+	}
+	sentry.SetTag(c, "topic", topic)
 	if err != nil {
-		log.Error("failed to marshal payload", zap.Error(err), zap.String("correlationId", corrID))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status, msg := enrichStatus(err)
+		log.Warn("publish "+msg,
+			zap.Error(err),
+			zap.String("eventType", req.EventType),
+			zap.String("topic", topic),
+		)
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		c.JSON(status, gin.H{
 			"status":        "error",
-			"error":         "internal serialization error",
+			"error":         msg,
+			"details":       err.Error(),
 			"correlationId": corrID,
 		})
 		return
 	}
 
-	topic := h.resolveTopic(req)
+	if err := h.checkRegistry(c, req, topic); err != nil {
+		status, msg := http.StatusBadRequest, "schema validation failed"
+		if errors.Is(err, schema.ErrRegistryUnavailable) {
+			status, msg = http.StatusServiceUnavailable, "schema registry unavailable"
+		}
+		log.Warn("topic schema check failed",
+			zap.Error(err),
+			zap.String("eventType", req.EventType),
+			zap.String("topic", topic),
+		)
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		c.JSON(status, errorBody(msg, err, corrID))
+		return
+	}
 
 	log.Info("Received event",
 		zap.String("eventType", req.EventType),
 		zap.String("sourceSystem", req.SourceSystem),
 		zap.String("topic", topic),
 		zap.Int("bytes", len(payloadBytes)),
-		zap.String("correlationId", corrID),
+		zap.Any("payload", h.Redactor.Payload(req.EventType, req.Payload)),
 	)
 
 	resp := EventResponse{
 		Topic:         topic,
+		Cluster:       h.resolveCluster(req),
 		Bytes:         len(payloadBytes),
-		DryRun:        h.DryRun,
+		DryRun:        dryRun,
 		CorrelationID: corrID,
-		Event:         &req,
+		EventID:       req.EventID,
+		Event:         h.echo(req),
+	}
+	if deprecation != "" {
+		resp.Warnings = []string{deprecation}
 	}
 
-	if h.DryRun {
-		log.Info("DRY-RUN → not sending to Pulsar", zap.String("correlationId", corrID))
+	if dryRun {
+		log.Info("DRY-RUN → not sending to Pulsar")
 		resp.Status = "dry-run"
+		resp.Destinations = destinations(topic, fanOut, resp.Status)
+		h.recordPublish(c, req, topic, "", audit.ResultDryRun, len(payloadBytes), nil)
 		c.JSON(http.StatusOK, resp)
 		return
 	}
 
-	msgID, err := h.Producer.Send(payloadBytes)
+	client := quotaClient(c)
+	if err := h.Quotas.Reserve(client, len(payloadBytes)); err != nil {
+		log.Warn("quota exceeded", zap.Error(err))
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		var qe *quota.ExceededError
+		if errors.As(err, &qe) {
+			c.Header("Retry-After", strconv.Itoa(int(qe.RetryAfter.Seconds())+1))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"status":        "error",
+			"error":         "quota exceeded",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	id, sentTo, err := h.send(c, req, topic, payloadBytes, corrID)
+	sendFailed = sendStarted(err) || sentTo != topic // ook een fallback: de eerste send faalde
+	if errors.Is(err, pulsar.ErrTooManyInFlight) {
+		h.Quotas.Release(client, len(payloadBytes))
+		log.Warn("too many in-flight publishes", zap.Error(err), zap.String("topic", topic))
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"status":        "error",
+			"error":         "too many in-flight publishes",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+	if err != nil && h.spoolEvent(c, req, topic, payloadBytes, corrID, err) {
+		resp.Status = "spooled"
+		h.recordPublish(c, req, topic, "", audit.ResultSpooled, len(payloadBytes), err)
+		c.JSON(http.StatusAccepted, resp)
+		return
+	}
 	if err != nil {
-		log.Error("failed sending to Pulsar",
-			zap.Error(err),
-			zap.String("correlationId", corrID),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.Quotas.Release(client, len(payloadBytes))
+		log.Error("failed sending to Pulsar", zap.Error(err))
+		h.recordPublish(c, req, topic, "", audit.ResultFailed, len(payloadBytes), err)
+		_ = c.Error(err) // voor Sentry
+		var open *pulsar.CircuitOpenError
+		if errors.As(err, &open) {
+			c.Header("Retry-After", strconv.Itoa(int(open.RetryAfter.Seconds())+1))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":        "error",
+				"error":         "Pulsar unavailable",
+				"details":       err.Error(),
+				"correlationId": corrID,
+			})
+			return
+		}
+		status, body := http.StatusInternalServerError, gin.H{
 			"status":        "error",
 			"error":         "failed sending to Pulsar",
 			"details":       err.Error(),
 			"correlationId": corrID,
-		})
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			status, body["error"] = http.StatusGatewayTimeout, "publish to Pulsar timed out"
+		}
+		if id := h.deadLetter(c, req, topic, payloadBytes, corrID, err); id != "" {
+			body["deadLetterId"] = id
+		}
+		c.JSON(status, body)
 		return
 	}
 
+	msgID := string(id)
 	resp.Status = "sent"
 	resp.MessageID = msgID
+	result := audit.ResultSent
+	if sentTo != topic {
+		resp.Topic, resp.Fallback, result = sentTo, true, audit.ResultFallback
+	}
+	h.recordPublish(c, req, sentTo, msgID, result, len(payloadBytes), nil)
 
 	log.Info("Event sent to Pulsar",
 		zap.String("messageId", msgID),
-		zap.String("topic", topic),
-		zap.String("correlationId", corrID),
+		zap.String("topic", sentTo),
 	)
 
-	c.JSON(http.StatusCreated, resp)
+	status := http.StatusCreated
+	dests, failed := h.fanOut(c, req, sentTo, msgID, fanOut, payloadBytes, corrID)
+	resp.Destinations = dests
+	if failed > 0 {
+		sendFailed = true
+		resp.Status, status = statusPartial, http.StatusMultiStatus
+	}
+	c.JSON(status, resp)
 }
 
-// POST /api/v1/events/batch
+// POST /api/v1/events/batch?mode=atomic
 func (h *EventHandler) PostBatch(c *gin.Context) {
-	log := h.Logger.With(
-		zap.String("path", c.FullPath()),
-		zap.String("method", c.Request.Method),
-	)
+	log := middleware.Logger(c)
 	corrID := middleware.GetCorrelationID(c)
+	dryRun := h.isDryRun()
 
-	var reqs []EventRequest
-	if err := c.ShouldBindJSON(&reqs); err != nil {
-		log.Warn("invalid batch body", zap.Error(err), zap.String("correlationId", corrID))
+	mode := c.Query("mode")
+	if mode != "" && mode != batchModeAtomic {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":        "error",
-			"error":         "invalid batch body",
-			"details":       err.Error(),
+			"error":         "invalid mode",
+			"details":       fmt.Sprintf("mode %q is not supported, use %q", mode, batchModeAtomic),
 			"correlationId": corrID,
 		})
 		return
 	}
+	// een atomic batch moet volledig gecontroleerd zijn vóór de eerste publish
+	if mode != batchModeAtomic && h.streamBatch(c) {
+		h.postBatchStream(c)
+		return
+	}
 
-	results := make([]BatchItemResult, 0, len(reqs))
+	var reqs []EventRequest
+	_, stage := tracing.Stage(c.Request.Context(), "bind",
+		attribute.Int64("http.request.body.size", c.Request.ContentLength))
+	err := h.bindJSON(c, &reqs)
+	tracing.End(stage, err)
+	if err != nil {
+		log.Warn("invalid batch body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, errorBody("invalid batch body", err, corrID))
+		return
+	}
 
-	for i, req := range reqs {
-		itemCorr := corrID // je kan evt. per item een eigen ID genereren
+	client := quotaClient(c)
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(
+		attribute.Int("batch.size", len(reqs)),
+		attribute.String("correlation_id", corrID),
+		attribute.String("batch.mode", mode),
+	)
 
-		r := BatchItemResult{
-			Index:         i,
-			CorrelationID: itemCorr,
-			Event:         &req,
+	// een atomic batch boekt de quota van alle items vooraf
+	reserved := make([]int, len(reqs))
+	if mode == batchModeAtomic {
+		sizes, ok := h.checkAtomicBatch(c, reqs, corrID)
+		if !ok {
+			return
 		}
+		if !dryRun {
+			if !h.reserveAtomicBatch(c, reqs, sizes, client, corrID) {
+				return
+			}
+			reserved = sizes
+		}
+	}
 
-		if err := validateEventSchema(req); err != nil {
-			r.Status = "error"
-			r.Error = "schema validation failed: " + err.Error()
-			results = append(results, r)
-			continue
+	// elke worker schrijft enkel results[i] van zijn eigen items, zodat de
+	// volgorde die van de request blijft
+	results := make([]BatchItemResult, len(reqs))
+	h.forEachItem(len(reqs), func(i int) {
+		results[i] = h.publishBatchItem(c, i, reqs[i], corrID, client, dryRun, reserved[i])
+	})
+
+	status := "sent"
+	if dryRun {
+		status = "dry-run"
+	}
+
+	resp := BatchResponse{
+		Status:  status,
+		Count:   len(results),
+		DryRun:  dryRun,
+		Results: results,
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// forEachItem roept fn op voor de items 0..n-1 van een batch, parallel
+// volgens api.batch.parallelism.
+func (h *EventHandler) forEachItem(n int, fn func(i int)) {
+	items := make(chan int)
+	var wg sync.WaitGroup
+	for range min(h.batchParallelism(), n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range items {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		items <- i
+	}
+	close(items)
+	wg.Wait()
+}
+
+// spoolEvent bewaart een event dat niet verstuurd kon worden omdat Pulsar
+// onbereikbaar is, om het later te versturen. false als er geen spool is, de
+// fout niet aan de broker ligt of de spool vol is; de quota blijven bij
+// true gereserveerd.
+func (h *EventHandler) spoolEvent(c *gin.Context, req EventRequest, topic string, payload []byte, corrID string, sendErr error) bool {
+	if h.Spool == nil || !pulsar.IsUnavailable(sendErr) {
+		return false
+	}
+	log := middleware.Logger(c)
+	opts := h.sendOptions(c, req, corrID)
+	err := h.Spool.Put(spool.Message{
+		Topic:      topic,
+		Cluster:    opts.Cluster,
+		Key:        opts.Key,
+		Payload:    payload,
+		Properties: opts.Properties,
+	})
+	if err != nil {
+		log.Error("failed to spool event", zap.Error(err), zap.NamedError("sendError", sendErr))
+		return false
+	}
+	log.Warn("Pulsar unavailable, event spooled", zap.Error(sendErr), zap.String("topic", topic))
+	return true
+}
+
+// deadLetter bewaart een event dat niet verstuurd kon worden, met de fout.
+// Geeft het ID, of "" als er geen store is of het bewaren mislukt.
+func (h *EventHandler) deadLetter(c *gin.Context, req EventRequest, topic string, payload []byte, corrID string, sendErr error) string {
+	if h.DeadLetter == nil {
+		return ""
+	}
+	log := middleware.Logger(c)
+	id, err := h.DeadLetter.Put(deadletter.Entry{
+		CorrelationID: corrID,
+		ClientID:      middleware.GetClientID(c),
+		EventType:     req.EventType,
+		Cluster:       h.resolveCluster(req),
+		Topic:         topic,
+		Reason:        sendErr.Error(),
+		Request:       payload,
+	})
+	if err != nil {
+		log.Error("failed to dead-letter event", zap.Error(err), zap.NamedError("sendError", sendErr))
+		return ""
+	}
+	log.Warn("Event dead-lettered", zap.String("deadLetterId", id), zap.String("topic", topic))
+	return id
+}
+
+// publishBatchItem valideert en publiceert één item van een batch. reserved
+// is de quota die een atomic batch al voor het item boekte (0 = nog niet);
+// die wordt vrijgegeven als het item niet verstuurd wordt.
+func (h *EventHandler) publishBatchItem(c *gin.Context, i int, req EventRequest, corrID, client string, dryRun bool, reserved int) (r BatchItemResult) {
+	itemCorr := corrID // je kan evt. per item een eigen ID genereren
+
+	booked := reserved
+	defer func() {
+		if booked > 0 && r.Status == "error" {
+			h.Quotas.Release(client, booked)
 		}
+	}()
 
-		payloadBytes, err := json.Marshal(req)
-		if err != nil {
-			r.Status = "error"
-			r.Error = "marshal error: " + err.Error()
-			results = append(results, r)
-			continue
+	deprecation, versionErr := h.resolveVersion(&req)
+	r = BatchItemResult{
+		Index:         i,
+		CorrelationID: itemCorr,
+		Event:         h.echo(req),
+	}
+	if versionErr != nil {
+		r.Status = "error"
+		r.Error = versionErr.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, versionErr)
+		return r
+	}
+	if deprecation != "" {
+		r.Warnings = []string{deprecation}
+	}
+
+	h.applyDefaults(req)
+	if err := h.validate(c, req); err != nil {
+		r.Status = "error"
+		r.Error = err.Error()
+		if _, msg := validateStatus(err); msg == "schema validation failed" {
+			r.Error = msg + ": " + err.Error()
+		}
+		r.Errors = validationErrors(err)
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r
+	}
+
+	if err := h.enrichPayload(c, req); err != nil {
+		_, msg := enrichmentStatus(err)
+		r.Status = "error"
+		r.Error = msg + ": " + err.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r
+	}
+
+	buf, payloadBytes, err := h.marshal(c, &req)
+	if err != nil {
+		r.Status = "error"
+		r.Error = "marshal error: " + err.Error()
+		h.recordPublish(c, req, "", "", audit.ResultRejected, 0, err)
+		return r
+	}
+	sendFailed := false // dan de buffer niet hergebruiken, zie release
+	defer func() {
+		if !sendFailed {
+			buf.release()
 		}
+	}()
+	// zoals in PostEvent: de echo is het event met defaults, enrichment en
+	// envelope, niet wat de client stuurde
+	r.Event = h.echo(req)
 
-		topic := h.resolveTopic(req)
-		r.Topic = topic
-		r.Bytes = len(payloadBytes)
+	topic, err := h.enrich(c, req)
+	var fanOut []string
+	if err == nil {
+		fanOut, err = h.fanOutTopics(c, req, topic)
+	}
+	r.Topic = topic
+	r.Cluster = h.resolveCluster(req)
+	r.Bytes = len(payloadBytes)
+	r.EventID = req.EventID
+	if err != nil {
+		_, msg := enrichStatus(err)
+		r.Status = "error"
+		r.Error = msg + ": " + err.Error()
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		return r
+	}
 
-		if h.DryRun {
-			r.Status = "dry-run"
-			results = append(results, r)
-			continue
+	if err := h.checkRegistry(c, req, topic); err != nil {
+		r.Status = "error"
+		r.Error = "schema validation failed: " + err.Error()
+		if errors.Is(err, schema.ErrRegistryUnavailable) {
+			r.Error = err.Error()
 		}
+		r.Errors = validationErrors(err)
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		return r
+	}
 
-		msgID, err := h.Producer.Send(payloadBytes)
-		if err != nil {
+	if dryRun {
+		r.Status = "dry-run"
+		r.Destinations = destinations(topic, fanOut, r.Status)
+		h.recordPublish(c, req, topic, "", audit.ResultDryRun, len(payloadBytes), nil)
+		return r
+	}
+
+	send := h.send
+	if booked == 0 {
+		if err := h.Quotas.Reserve(client, len(payloadBytes)); err != nil {
 			r.Status = "error"
-			r.Error = "send error: " + err.Error()
-			results = append(results, r)
-			continue
+			r.Error = "quota exceeded: " + err.Error()
+			h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+			return r
 		}
+		booked = len(payloadBytes)
+	} else {
+		send = h.sendAtomicItem
+	}
 
-		r.Status = "sent"
-		r.MessageID = msgID
-		results = append(results, r)
+	id, sentTo, err := send(c, req, topic, payloadBytes, itemCorr)
+	sendFailed = sendStarted(err) || sentTo != topic // ook een fallback: de eerste send faalde
+	if errors.Is(err, pulsar.ErrTooManyInFlight) {
+		r.Status = "error"
+		r.Error = err.Error()
+		h.recordPublish(c, req, topic, "", audit.ResultRejected, len(payloadBytes), err)
+		return r
+	}
+	if err != nil && h.spoolEvent(c, req, topic, payloadBytes, itemCorr, err) {
+		r.Status = "spooled"
+		h.recordPublish(c, req, topic, "", audit.ResultSpooled, len(payloadBytes), err)
+		return r
+	}
+	if err != nil {
+		r.Status = "error"
+		r.Error = "send error: " + err.Error()
+		h.recordPublish(c, req, topic, "", audit.ResultFailed, len(payloadBytes), err)
+		if !errors.As(err, new(*pulsar.CircuitOpenError)) {
+			r.DeadLetterID = h.deadLetter(c, req, topic, payloadBytes, itemCorr, err)
+		}
+		return r
 	}
 
-	status := "sent"
-	if h.DryRun {
-		status = "dry-run"
+	msgID := string(id)
+	r.Status = "sent"
+	r.MessageID = msgID
+	result := audit.ResultSent
+	if sentTo != topic {
+		r.Topic, r.Fallback, result = sentTo, true, audit.ResultFallback
+	}
+	h.recordPublish(c, req, sentTo, msgID, result, len(payloadBytes), nil)
+	dests, failed := h.fanOut(c, req, sentTo, msgID, fanOut, payloadBytes, itemCorr)
+	r.Destinations = dests
+	if failed > 0 {
+		sendFailed = true
+		r.Status = statusPartial
 	}
+	return r
+}
 
-	resp := BatchResponse{
-		Status:  status,
-		Count:   len(results),
-		DryRun:  h.DryRun,
-		Results: results,
+// GET /api/v1/usage
+func (h *EventHandler) GetUsage(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Quotas.Usage(quotaClient(c)))
+}
+
+// GET /api/v1/recent?eventType=&status=&limit=
+// De laatst aanvaarde events van de client zelf (zonder API key: die zonder
+// client), nieuwste eerst en geredacteerd.
+func (h *EventHandler) GetRecent(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+	if h.Recent == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "recent events are disabled",
+			"details":       "recent.size is 0",
+			"correlationId": corrID,
+		})
+		return
 	}
 
+	f := recent.Filter{
+		ClientID:  middleware.GetClientID(c),
+		EventType: c.Query("eventType"),
+		Status:    c.Query("status"),
+		Limit:     50,
+	}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		f.Limit, err = strconv.Atoi(limit)
+		if err == nil && f.Limit < 1 {
+			err = errors.New("limit must be at least 1")
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":        "error",
+				"error":         "invalid query",
+				"details":       err.Error(),
+				"correlationId": corrID,
+			})
+			return
+		}
+	}
+
+	events := h.Recent.List(f)
+	c.JSON(http.StatusOK, gin.H{"count": len(events), "events": events})
+}
+
+// maxHistoryLimit begrenst limit van GetHistory.
+const maxHistoryLimit = 500
+
+// GetHistory (GET /api/v1/history) bladert door de events van de client in
+// Recent, nieuwste eerst: limit (standaard 50) per pagina, filters eventType,
+// status en correlationId. Is er meer, dan geeft next de before voor de
+// volgende pagina (de seq van het oudste event op deze pagina); zo verschuift
+// een pagina niet als er intussen events bijkomen.
+func (h *EventHandler) GetHistory(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+	if h.Recent == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "recent events are disabled",
+			"details":       "recent.size is 0",
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	f := recent.Filter{
+		ClientID:      middleware.GetClientID(c),
+		EventType:     c.Query("eventType"),
+		Status:        c.Query("status"),
+		CorrelationID: c.Query("correlationId"),
+		Limit:         50,
+	}
+	var err error
+	if limit := c.Query("limit"); limit != "" {
+		f.Limit, err = strconv.Atoi(limit)
+		if err == nil && (f.Limit < 1 || f.Limit > maxHistoryLimit) {
+			err = fmt.Errorf("limit must be between 1 and %d", maxHistoryLimit)
+		}
+	}
+	if before := c.Query("before"); err == nil && before != "" {
+		f.Before, err = strconv.ParseUint(before, 10, 64)
+		if err == nil && f.Before == 0 {
+			err = errors.New("before must be at least 1")
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid query",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	// een event meer vragen om te weten of er nog een pagina is
+	limit := f.Limit
+	f.Limit++
+	events := h.Recent.List(f)
+	resp := gin.H{}
+	if len(events) > limit {
+		events = events[:limit]
+		resp["next"] = events[limit-1].Seq
+	}
+	resp["count"] = len(events)
+	resp["events"] = events
 	c.JSON(http.StatusOK, resp)
 }
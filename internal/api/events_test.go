@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/deadletter"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar/pulsartest"
+	"github.com/rubenclaes/pulsar-api/internal/quota"
+	"github.com/rubenclaes/pulsar-api/internal/spool"
+)
+
+const (
+	testTopic     = "persistent://acerta/payroll/wage-errors"
+	testFallback  = "persistent://acerta/payroll/wage-errors-fallback"
+	testDefault   = "persistent://acerta/payroll/default"
+	testEventBody = `{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"dossierId":"ABC-123"}}`
+)
+
+// errUnavailable is een fout waarvoor pulsar.IsUnavailable true geeft, zoals
+// een send timeout.
+var errUnavailable = fmt.Errorf("send timeout: %w", context.DeadlineExceeded)
+
+func newTestHandler(t *testing.T) (*EventHandler, *pulsartest.Publisher) {
+	t.Helper()
+	pub := pulsartest.NewPublisher()
+	h := NewEventHandler(pub, testDefault, map[string]string{"WAGE_ERROR": testTopic}, false, nil, nil, nil, nil, nil)
+	return h, pub
+}
+
+func do(t *testing.T, handler gin.HandlerFunc, body string, out any) int {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", handler)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode response %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestPostEventSent(t *testing.T) {
+	h, pub := newTestHandler(t)
+
+	var resp EventResponse
+	if code := do(t, h.PostEvent, testEventBody, &resp); code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", code, http.StatusCreated)
+	}
+	msgs := pub.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(msgs))
+	}
+	if resp.Status != "sent" || resp.Topic != testTopic || resp.MessageID != string(msgs[0].ID) {
+		t.Errorf("response = %+v, want sent to %s as %s", resp, testTopic, msgs[0].ID)
+	}
+	if msgs[0].Topic != testTopic {
+		t.Errorf("message topic = %s, want %s", msgs[0].Topic, testTopic)
+	}
+	var sent EventRequest
+	if err := json.Unmarshal(msgs[0].Payload, &sent); err != nil {
+		t.Fatal(err)
+	}
+	if sent.EventType != "WAGE_ERROR" || sent.Payload["dossierId"] != "ABC-123" {
+		t.Errorf("message = %s", msgs[0].Payload)
+	}
+}
+
+func TestPostEventFallback(t *testing.T) {
+	h, pub := newTestHandler(t)
+	h.SetFallbackTopics(map[string]string{"WAGE_ERROR": testFallback})
+	pub.FailTopic(testTopic, errUnavailable)
+
+	var resp EventResponse
+	if code := do(t, h.PostEvent, testEventBody, &resp); code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", code, http.StatusCreated)
+	}
+	if !resp.Fallback || resp.Topic != testFallback {
+		t.Errorf("response = %+v, want a fallback to %s", resp, testFallback)
+	}
+	if msgs := pub.Messages(); len(msgs) != 1 || msgs[0].Topic != testFallback {
+		t.Errorf("messages = %+v, want one on %s", msgs, testFallback)
+	}
+}
+
+func TestPostEventSpooled(t *testing.T) {
+	h, pub := newTestHandler(t)
+	sp, err := spool.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Spool = sp
+	pub.FailWith(errUnavailable)
+
+	var resp EventResponse
+	if code := do(t, h.PostEvent, testEventBody, &resp); code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d", code, http.StatusAccepted)
+	}
+	if resp.Status != "spooled" {
+		t.Errorf("status = %q, want spooled", resp.Status)
+	}
+	if n := sp.Len(); n != 1 {
+		t.Errorf("spool has %d events, want 1", n)
+	}
+}
+
+func TestPostEventDeadLetter(t *testing.T) {
+	h, pub := newTestHandler(t)
+	store, err := deadletter.New(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.DeadLetter = store
+	// geen broker probleem: niet spoolen, wel dead-letteren
+	pub.FailWith(errors.New("topic is not authorized"))
+
+	var resp struct {
+		Error        string `json:"error"`
+		DeadLetterID string `json:"deadLetterId"`
+	}
+	if code := do(t, h.PostEvent, testEventBody, &resp); code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", code, http.StatusInternalServerError)
+	}
+	if resp.DeadLetterID == "" {
+		t.Fatalf("no deadLetterId in the response (error %q)", resp.Error)
+	}
+	e, err := store.Get(resp.DeadLetterID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Topic != testTopic || !strings.Contains(e.Reason, "not authorized") {
+		t.Errorf("dead letter = %+v", e)
+	}
+}
+
+func TestPostBatchPartialFailure(t *testing.T) {
+	h, pub := newTestHandler(t)
+	const failing = "persistent://acerta/payroll/leave-errors"
+	h.ApplyConfig(false, map[string]string{"WAGE_ERROR": testTopic, "LEAVE_ERROR": failing}, nil)
+	pub.FailTopic(failing, errors.New("topic is terminated"))
+
+	body := `[
+		{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":1}},
+		{"eventType":"LEAVE_ERROR","sourceSystem":"EverESSt","payload":{"n":2}},
+		{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":3}}
+	]`
+	var resp BatchResponse
+	if code := do(t, h.PostBatch, body, &resp); code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", code, http.StatusOK)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("%d results, want 3", len(resp.Results))
+	}
+	for i, want := range []string{"sent", "error", "sent"} {
+		if r := resp.Results[i]; r.Index != i || r.Status != want {
+			t.Errorf("results[%d] = %+v, want status %s", i, r, want)
+		}
+	}
+	if !strings.Contains(resp.Results[1].Error, "topic is terminated") {
+		t.Errorf("results[1].error = %q", resp.Results[1].Error)
+	}
+	if msgs := pub.Messages(); len(msgs) != 2 {
+		t.Errorf("sent %d messages, want 2", len(msgs))
+	}
+}
+
+func TestBatchEchoIsStamped(t *testing.T) {
+	h, _ := newTestHandler(t)
+	h.SetEnvelope(true, true, "pulsar-api-test")
+	const spoofed = "client-chosen-id"
+
+	for _, tt := range []struct {
+		name, query string
+		want        int
+	}{
+		{"batch", "", http.StatusOK},
+		// het tweede item mist occurredAt: het eerste wordt enkel gecontroleerd
+		{"atomic batch", "?mode=atomic", http.StatusBadRequest},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.POST("/", h.PostBatch)
+			body := `[
+				{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","occurredAt":"` + time.Now().UTC().Format(time.RFC3339) + `","eventId":"` + spoofed + `","payload":{"n":1}},
+				{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":2}}
+			]`
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/"+tt.query, strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			var resp BatchResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			ev := resp.Results[0].Event
+			if ev == nil || ev.EventID == "" || ev.EventID == spoofed || ev.Gateway != "pulsar-api-test" {
+				t.Errorf("results[0].event = %+v, want the stamped envelope", ev)
+			}
+		})
+	}
+}
+
+func TestAtomicBatchReservesQuotaUpFront(t *testing.T) {
+	h, pub := newTestHandler(t)
+	h.Quotas = quota.New(quota.Limits{HourlyEvents: 2}, nil)
+
+	body := `[
+		{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":1}},
+		{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":2}},
+		{"eventType":"WAGE_ERROR","sourceSystem":"EverESSt","payload":{"n":3}}
+	]`
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", h.PostBatch)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/?mode=atomic", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body)
+	}
+	if msgs := pub.Messages(); len(msgs) != 0 {
+		t.Errorf("sent %d messages, want none", len(msgs))
+	}
+	if u := h.Quotas.Usage(""); u.Hourly.Events != 0 {
+		t.Errorf("hourly events = %d, want nothing booked", u.Hourly.Events)
+	}
+}
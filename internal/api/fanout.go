@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/metrics"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
+)
+
+// statusPartial: het event staat op zijn topic, maar een kopie van de
+// fan-out mislukte (207).
+const statusPartial = "partial"
+
+// Destination is één topic van een event met fan-out (zie
+// routing.Rule.FanOut); de eerste is de topic van de route of regel.
+type Destination struct {
+	Topic     string `json:"topic"`
+	Status    string `json:"status"` // sent, dry-run of error
+	MessageID string `json:"messageId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// fanOutTopics geeft de extra topics van de routing regel die past, met de
+// tenant ingevuld. De client moet elke topic ook mogen (tenants.isolate en
+// authorization), anders de fout zoals bij enrich; topic zelf valt weg.
+func (h *EventHandler) fanOutTopics(c *gin.Context, req EventRequest, topic string) ([]string, error) {
+	d, ok := h.route(c, req)
+	if !ok || len(d.FanOut) == 0 {
+		return nil, nil
+	}
+	topics := make([]string, 0, len(d.FanOut))
+	for _, t := range d.FanOut {
+		t, err := h.tenantTopic(c, t)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.checkIsolation(c, t); err != nil {
+			return nil, err
+		}
+		if err := h.authorize(c, req, t); err != nil {
+			return nil, err
+		}
+		if t != topic && !slices.Contains(topics, t) {
+			topics = append(topics, t)
+		}
+	}
+	return topics, nil
+}
+
+// destinations geeft topic en de fan-out topics met status, zonder message
+// ID; nil zonder fan-out.
+func destinations(topic string, topics []string, status string) []Destination {
+	if len(topics) == 0 {
+		return nil
+	}
+	dests := make([]Destination, 0, len(topics)+1)
+	for _, t := range append([]string{topic}, topics...) {
+		dests = append(dests, Destination{Topic: t, Status: status})
+	}
+	return dests
+}
+
+// fanOut stuurt een kopie naar elke fan-out topic nadat het event als msgID
+// op sentTo staat, zonder fallback topic of spool; een mislukte kopie maakt
+// de rest niet ongedaan. Geeft de destinations en het aantal mislukte
+// kopieën.
+func (h *EventHandler) fanOut(c *gin.Context, req EventRequest, sentTo, msgID string, topics []string, payload []byte, corrID string) ([]Destination, int) {
+	dests := destinations(sentTo, topics, "sent")
+	if dests == nil {
+		return nil, 0
+	}
+	dests[0].MessageID = msgID
+	opts := h.sendOptions(c, req, corrID)
+	failed := 0
+	for i, t := range topics {
+		d := &dests[i+1]
+		ctx, span := tracing.Stage(c.Request.Context(), "fanout",
+			attribute.String("messaging.destination.name", t))
+		start := time.Now()
+		id, err := h.Publisher.Send(ctx, t, payload, opts)
+		tracing.End(span, err)
+		if !errors.Is(err, pulsar.ErrTooManyInFlight) {
+			result := metrics.ResultSent
+			if err != nil {
+				result = metrics.ResultSendFailed
+			}
+			h.Metrics.Send(ctx, t, result, time.Since(start))
+		}
+		if err != nil {
+			failed++
+			d.Status, d.Error = "error", err.Error()
+			middleware.Logger(c).Warn("fan-out copy failed", zap.Error(err), zap.String("topic", t))
+			h.recordPublish(c, req, t, "", audit.ResultFailed, len(payload), err)
+			continue
+		}
+		d.MessageID = string(id)
+		h.recordPublish(c, req, t, d.MessageID, audit.ResultSent, len(payload), nil)
+	}
+	return dests, failed
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// vendorName is het eventType in een vendor media type, bv. signalitiek-error.
+var vendorName = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// mediaType is het eventType en de versie uit een vendor media type.
+type mediaType struct {
+	eventType string
+	version   int // 0 = geen versie in het media type
+}
+
+// SetMediaTypeVendor zet de vendor van de media types die het eventType
+// kiezen ("" = uit), ook bij een config reload.
+func (h *EventHandler) SetMediaTypeVendor(vendor string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.MediaTypeVendor = strings.ToLower(vendor)
+}
+
+// mediaType leest de Content-Type, bv. application/vnd.acerta.signalitiek-error.v1+json
+// voor SIGNALITIEK_ERROR versie 1. nil bij een ander media type.
+func (h *EventHandler) mediaType(c *gin.Context) (*mediaType, error) {
+	h.mu.RLock()
+	vendor := h.MediaTypeVendor
+	h.mu.RUnlock()
+	header := c.GetHeader("Content-Type")
+	if vendor == "" || header == "" {
+		return nil, nil
+	}
+	full, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil, nil // gin leest de body als JSON, zoals zonder vendor
+	}
+	prefix := "application/vnd." + vendor + "."
+	if !strings.HasPrefix(full, prefix) {
+		return nil, nil
+	}
+	name, ok := strings.CutSuffix(strings.TrimPrefix(full, prefix), "+json")
+	if !ok {
+		return nil, fmt.Errorf("content type %s: want %s<eventType>[.v<n>]+json", full, prefix)
+	}
+	mt := &mediaType{}
+	if i := strings.LastIndex(name, ".v"); i >= 0 {
+		n, err := strconv.Atoi(name[i+2:])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("content type %s: version %q must be v<n>, e.g. v1", full, name[i+1:])
+		}
+		name, mt.version = name[:i], n
+	}
+	if !vendorName.MatchString(name) {
+		return nil, fmt.Errorf("content type %s: eventType %q must be lowercase words separated by '-', e.g. signalitiek-error", full, name)
+	}
+	mt.eventType = strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	return mt, nil
+}
+
+// apply vult eventType en eventVersion van req in; staan ze al in de body,
+// dan moeten ze overeenkomen met het media type.
+func (mt *mediaType) apply(req *EventRequest) error {
+	if req.EventType != "" && !strings.EqualFold(req.EventType, mt.eventType) {
+		return fmt.Errorf("eventType %s does not match the content type (%s)", req.EventType, mt.eventType)
+	}
+	if req.EventVersion != 0 && mt.version != 0 && req.EventVersion != mt.version {
+		return fmt.Errorf("eventVersion %d does not match the content type (v%d)", req.EventVersion, mt.version)
+	}
+	if req.EventType == "" {
+		req.EventType = mt.eventType
+	}
+	if req.EventVersion == 0 {
+		req.EventVersion = mt.version
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// SetPriority zet de priority lane en de topic suffix van priority events
+// (ook bij een config reload).
+func (h *EventHandler) SetPriority(lane *middleware.PriorityLane, topicSuffix string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.PriorityLane = lane
+	h.PriorityTopicSuffix = topicSuffix
+}
+
+// isPriority: de request heeft een priority header (zie
+// middleware.PriorityLane), of het event een priority veld met één van
+// api.priority.values. In een batch geldt de header voor elk item.
+func (h *EventHandler) isPriority(c *gin.Context, req EventRequest) bool {
+	if middleware.IsPriority(c) {
+		return true
+	}
+	h.mu.RLock()
+	lane := h.PriorityLane
+	h.mu.RUnlock()
+	return lane.Matches(req.Priority)
+}
+
+// priorityTopic geeft de topic van een priority event: topic met de suffix,
+// zodat het een eigen producer heeft.
+func (h *EventHandler) priorityTopic(topic string) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return topic + h.PriorityTopicSuffix
+}
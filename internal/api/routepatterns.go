@@ -0,0 +1,64 @@
+package api
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// routePattern is een route die op een familie van eventTypes past: een
+// wildcard key (bv. *_error) of een route met pattern.
+type routePattern struct {
+	key     string // key in de route maps
+	version int    // enkel voor deze eventVersion (key <pattern>@v<n>), 0 = elke
+	re      *regexp.Regexp
+}
+
+// SetRoutePatterns zet route key → regex op het eventType (zie
+// config.RoutePatterns), ook bij een config reload. Routes met een versie
+// komen eerst, daarna in volgorde van de key; een ongeldige regex (zie
+// config.Validate) wordt overgeslagen.
+func (h *EventHandler) SetRoutePatterns(patterns map[string]string) {
+	compiled := make([]routePattern, 0, len(patterns))
+	for key, expr := range patterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			continue
+		}
+		p := routePattern{key: strings.ToLower(key), re: re}
+		if _, v, ok := strings.Cut(p.key, "@v"); ok {
+			p.version, _ = strconv.Atoi(v)
+		}
+		compiled = append(compiled, p)
+	}
+	slices.SortFunc(compiled, func(a, b routePattern) int {
+		if (a.version == 0) != (b.version == 0) {
+			if a.version != 0 {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.key, b.key)
+	})
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.RoutePatterns = compiled
+}
+
+// matchRoute geeft de key van de eerste route pattern die op eventType
+// (lowercase) en version past, of "". h.mu moet gelockt zijn.
+func (h *EventHandler) matchRoute(eventType string, version int) string {
+	for _, p := range h.RoutePatterns {
+		if (p.version == 0 || p.version == version) && p.re.MatchString(eventType) {
+			return p.key
+		}
+	}
+	return ""
+}
+
+// isRoutePattern meldt of key de key van een route pattern is en dus geen
+// eventType. h.mu moet gelockt zijn.
+func (h *EventHandler) isRoutePattern(key string) bool {
+	return slices.ContainsFunc(h.RoutePatterns, func(p routePattern) bool { return p.key == key })
+}
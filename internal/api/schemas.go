@@ -0,0 +1,228 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/audit"
+	"github.com/rubenclaes/pulsar-api/internal/config"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+)
+
+// errSchemaNotManaged: het schema komt van een URL of uit de schema
+// registry en kan niet via de admin API gewijzigd worden (409).
+var errSchemaNotManaged = errors.New("schema is not managed by the API")
+
+// schemaFile geeft het bestand met het schema van eventType: uit de schemas
+// mapping, of <schemaDir>/<eventType>.json (ook als het nog niet bestaat).
+func schemaFile(cfg *config.Config, eventType string) (string, error) {
+	et := strings.ToLower(eventType) // viper lowercased map keys
+	if path, ok := cfg.Schemas[et]; ok {
+		if schema.IsURL(path) {
+			return "", fmt.Errorf("%w: %s is loaded from %s", errSchemaNotManaged, eventType, path)
+		}
+		return path, nil
+	}
+	if subject, ok := cfg.Registry.Subjects[et]; ok && cfg.Registry.Provider != "none" {
+		return "", fmt.Errorf("%w: %s comes from the schema registry (subject %s)", errSchemaNotManaged, eventType, subject)
+	}
+	if cfg.SchemaDir == "" {
+		return "", fmt.Errorf("%w: no schemaDir configured", errSchemaNotManaged)
+	}
+	matches, err := filepath.Glob(filepath.Join(cfg.SchemaDir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+	for _, m := range matches {
+		if strings.EqualFold(strings.TrimSuffix(filepath.Base(m), ".json"), et) {
+			return m, nil
+		}
+	}
+	return filepath.Join(cfg.SchemaDir, et+".json"), nil
+}
+
+// GET /admin/schemas/:eventType
+func (h *AdminHandler) GetSchema(c *gin.Context) {
+	path, err := schemaFile(h.Config.Current(), c.Param("eventType"))
+	var data []byte
+	if err == nil {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		h.schemaError(c, "schemas.read", err)
+		return
+	}
+	c.Data(http.StatusOK, "application/schema+json", data)
+}
+
+// PUT /admin/schemas/:eventType?force=true
+// Schrijft het schema in het bestand van het eventType en compileert de
+// schema's opnieuw. Een wijziging die niet compatibel is met de vorige
+// versie (zie schemaCompatibility) wordt geweigerd, tenzij met force.
+func (h *AdminHandler) PutSchema(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+	log := middleware.Logger(c)
+	eventType := c.Param("eventType")
+
+	force, err := strconv.ParseBool(c.DefaultQuery("force", "false"))
+	var raw []byte
+	if err == nil {
+		raw, err = io.ReadAll(c.Request.Body)
+	}
+	var doc any
+	if err == nil {
+		doc, err = jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	}
+	if err != nil {
+		h.auditAdmin(c, "schemas.update", audit.ResultRejected, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid request body",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	h.schemaMu.Lock()
+	defer h.schemaMu.Unlock()
+	cfg := h.Config.Current()
+	path, err := schemaFile(cfg, eventType)
+	if err != nil {
+		h.schemaError(c, "schemas.update", err)
+		return
+	}
+	if err := schema.Compile(path, doc); err != nil {
+		h.auditAdmin(c, "schemas.update", audit.ResultRejected, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid schema",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	previous, err := os.ReadFile(path)
+	created := errors.Is(err, os.ErrNotExist)
+	if err != nil && !created {
+		h.schemaError(c, "schemas.update", err)
+		return
+	}
+	var problems []string
+	if !created {
+		prevDoc, err := jsonschema.UnmarshalJSON(bytes.NewReader(previous))
+		if err != nil {
+			h.schemaError(c, "schemas.update", fmt.Errorf("previous version of %s: %w", eventType, err))
+			return
+		}
+		problems = schema.Incompatibilities(cfg.SchemaCompat, prevDoc, doc)
+	}
+	if len(problems) > 0 && !force {
+		err := fmt.Errorf("incompatible schema: %s", strings.Join(problems, "; "))
+		h.auditAdmin(c, "schemas.update", audit.ResultRejected, err)
+		c.JSON(http.StatusConflict, gin.H{
+			"status":        "error",
+			"error":         "incompatible schema",
+			"details":       strings.Join(problems, "; "),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	if err := writeSchema(path, raw); err != nil {
+		h.schemaError(c, "schemas.update", err)
+		return
+	}
+	if err := h.ReloadSchemas(); err != nil {
+		// de vorige versie terugzetten, zodat een herstart hetzelfde laadt
+		if created {
+			_ = os.Remove(path)
+		} else {
+			_ = writeSchema(path, previous)
+		}
+		h.schemaError(c, "schemas.update", fmt.Errorf("reload schemas: %w", err))
+		return
+	}
+
+	if len(problems) > 0 {
+		log.Warn("Incompatible schema forced",
+			zap.String("eventType", eventType),
+			zap.String("file", path),
+			zap.Strings("incompatibilities", problems),
+		)
+	} else {
+		log.Info("Schema updated", zap.String("eventType", eventType), zap.String("file", path))
+	}
+	h.auditAdmin(c, "schemas.update", audit.ResultOK, nil)
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	body := gin.H{
+		"eventType":     eventType,
+		"file":          path,
+		"compatibility": cfg.SchemaCompat,
+		"forced":        len(problems) > 0,
+	}
+	if len(problems) > 0 {
+		body["incompatibilities"] = problems
+	}
+	c.JSON(status, body)
+}
+
+func (h *AdminHandler) schemaError(c *gin.Context, action string, err error) {
+	corrID := middleware.GetCorrelationID(c)
+	switch {
+	case errors.Is(err, errSchemaNotManaged):
+		h.auditAdmin(c, action, audit.ResultRejected, err)
+		c.JSON(http.StatusConflict, gin.H{
+			"status":        "error",
+			"error":         "schema not managed by the API",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+	case errors.Is(err, os.ErrNotExist):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "schema not found",
+			"correlationId": corrID,
+		})
+	default:
+		middleware.Logger(c).Error("schema admin error", zap.Error(err))
+		h.auditAdmin(c, action, audit.ResultFailed, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":        "error",
+			"error":         "failed to access schema file",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+	}
+}
+
+// writeSchema schrijft via een tijdelijk bestand, zodat een reload nooit een
+// half geschreven schema leest.
+func writeSchema(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+var (
+	// errUnknownSourceSystem: sourceSystem staat niet in sourceSystems.known (400).
+	errUnknownSourceSystem = errors.New("unknown sourceSystem")
+	// errSourceSystemNotAllowed: de client mag dit sourceSystem niet gebruiken (403).
+	errSourceSystemNotAllowed = errors.New("sourceSystem not allowed")
+)
+
+// SetSourceSystems zet de gekende sourceSystems (leeg = elke) en per client
+// de sourceSystems die hij mag gebruiken (ook bij een config reload).
+func (h *EventHandler) SetSourceSystems(known []string, clients map[string][]string) {
+	lower := make(map[string][]string, len(clients))
+	for client, systems := range clients {
+		lower[strings.ToLower(client)] = systems
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.SourceSystems = known
+	h.ClientSourceSystems = lower
+}
+
+// SetSourceSystemRoutes zet per sourceSystem de topic per eventType ("*" =
+// alle), ook bij een config reload. Viper lowercased de keys, dus sourceSystem
+// en eventType worden case-insensitief opgezocht.
+func (h *EventHandler) SetSourceSystemRoutes(routes map[string]map[string]string) {
+	lower := make(map[string]map[string]string, len(routes))
+	for system, topics := range routes {
+		lower[strings.ToLower(system)] = lowerKeys(topics)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.SourceSystemRoutes = lower
+}
+
+// exampleSourceSystem geeft een sourceSystem dat de client mag gebruiken:
+// het eerste van zijn eigen lijst, anders het eerste gekende, anders "".
+func (h *EventHandler) exampleSourceSystem(c *gin.Context) string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, s := range h.ClientSourceSystems[strings.ToLower(middleware.GetClientID(c))] {
+		if s != "*" {
+			return s
+		}
+	}
+	if len(h.SourceSystems) > 0 {
+		return h.SourceSystems[0]
+	}
+	return ""
+}
+
+// checkSourceSystem controleert req.SourceSystem tegen de gekende
+// sourceSystems en die van de client. Hoofdletters tellen: downstream wordt
+// op de exacte waarde gegroepeerd. Bij een gesigneerde request moet het het
+// gesigneerde X-Source-System zijn (zonder op hoofdletters te letten, zoals
+// de secrets), anders publiceert een client met zijn eigen secret onder de
+// naam van een ander systeem.
+func (h *EventHandler) checkSourceSystem(c *gin.Context, req EventRequest) error {
+	client := middleware.GetClientID(c)
+	if signed := middleware.GetSignedSourceSystem(c); signed != "" && !strings.EqualFold(signed, req.SourceSystem) {
+		return fmt.Errorf("%w: request is signed for %q, not %q", errSourceSystemNotAllowed, signed, req.SourceSystem)
+	}
+	h.mu.RLock()
+	known := h.SourceSystems
+	allowed, restricted := h.ClientSourceSystems[strings.ToLower(client)]
+	h.mu.RUnlock()
+	if len(known) > 0 && !slices.Contains(known, req.SourceSystem) {
+		return fmt.Errorf("%w %q", errUnknownSourceSystem, req.SourceSystem)
+	}
+	if restricted && !slices.Contains(allowed, "*") && !slices.Contains(allowed, req.SourceSystem) {
+		return fmt.Errorf("%w: client %q may not publish as %q", errSourceSystemNotAllowed, client, req.SourceSystem)
+	}
+	return nil
+}
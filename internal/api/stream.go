@@ -0,0 +1,141 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/recent"
+)
+
+// streamHeartbeat: een SSE comment om de zoveel tijd, zodat proxies een
+// stille stream niet afsluiten.
+const streamHeartbeat = 15 * time.Second
+
+// GetRecentStream (GET /api/v1/recent/stream) is een live tail van de
+// aanvaarde events als Server-Sent Events: eerst de laatste backlog (standaard
+// 20) uit Recent, oudste eerst, daarna elk nieuw event. Dezelfde filters en
+// dezelfde client als GetRecent. Elk event heeft zijn Seq als id, zodat een
+// EventSource na een reconnect (Last-Event-ID) verdergaat waar hij was; een
+// client die te traag leest krijgt een "missed" event met het aantal gemiste
+// events.
+func (h *EventHandler) GetRecentStream(c *gin.Context) {
+	corrID := middleware.GetCorrelationID(c)
+	if h.Recent == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":        "error",
+			"error":         "recent events are disabled",
+			"details":       "recent.size is 0",
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	f := recent.Filter{
+		ClientID:  middleware.GetClientID(c),
+		EventType: c.Query("eventType"),
+		Status:    c.Query("status"),
+		Limit:     20,
+	}
+	var err error
+	if backlog := c.Query("backlog"); backlog != "" {
+		f.Limit, err = strconv.Atoi(backlog)
+		if err == nil && f.Limit < 0 {
+			err = errors.New("backlog must not be negative")
+		}
+	}
+	if last := c.GetHeader("Last-Event-ID"); err == nil && last != "" {
+		// na een reconnect alles sinds het laatste event, niet de backlog
+		if f.After, err = strconv.ParseUint(last, 10, 64); err == nil {
+			f.Limit = 0
+		}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":        "error",
+			"error":         "invalid query",
+			"details":       err.Error(),
+			"correlationId": corrID,
+		})
+		return
+	}
+
+	// eerst subscriben, dan de backlog lezen: zo valt er geen event tussen
+	sub, err := h.Recent.Subscribe(f)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":        "error",
+			"error":         "too many live tails",
+			"details":       fmt.Sprintf("at most %d streams can be open", recent.MaxSubscribers),
+			"correlationId": corrID,
+		})
+		return
+	}
+	defer sub.Cancel()
+
+	var backlog []recent.Event
+	if f.Limit > 0 || f.After > 0 {
+		backlog = h.Recent.List(f)
+	}
+
+	// de stream loopt langer dan api.writeTimeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx buffert anders de stream
+	c.Status(http.StatusOK)
+
+	var last uint64
+	write := func(e recent.Event) bool {
+		if e.Seq <= last {
+			return true // zat al in de backlog
+		}
+		last = e.Seq
+		b, err := json.Marshal(e)
+		if err != nil {
+			return true
+		}
+		_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: event\ndata: %s\n\n", e.Seq, b)
+		return err == nil
+	}
+	for i := len(backlog) - 1; i >= 0; i-- {
+		if !write(backlog[i]) {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	var missed int64
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.Events:
+			if !ok {
+				return // Recent is gesloten bij het afsluiten
+			}
+			if !write(e) {
+				return
+			}
+			if n := sub.Missed(); n > missed {
+				missed = n
+				if _, err := fmt.Fprintf(c.Writer, "event: missed\ndata: %d\n\n", n); err != nil {
+					return
+				}
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// tenantPlaceholder in een topic wordt de tenant van de request, bv.
+// persistent://{tenant}/hr/wage-errors.
+const tenantPlaceholder = "{tenant}"
+
+var (
+	// errMissingTenant: de topic heeft {tenant} maar de request geen tenant (400).
+	errMissingTenant = errors.New("missing tenant")
+	// errUnknownTenant: de tenant is ongeldig of staat niet in tenants.known (400).
+	errUnknownTenant = errors.New("unknown tenant")
+	// errTenantNotAllowed: de client identity hoort bij een andere tenant (403).
+	errTenantNotAllowed = errors.New("tenant not allowed")
+)
+
+// tenantName: een tenant moet een geldig deel van een Pulsar topic zijn.
+var tenantName = regexp.MustCompile(`^[A-Za-z0-9_.=-]+$`)
+
+// SetTenants zet waar de tenant van een request vandaan komt (ook bij een
+// config reload): de client identity (clients, een tenant of
+// tenant/namespace), de header of de default. Met isolate mag een client uit
+// clients enkel naar topics van zijn tenant (of namespace) publiceren.
+func (h *EventHandler) SetTenants(header, def string, known []string, clients map[string]string, isolate bool) {
+	lower := make(map[string]string, len(clients))
+	for client, tenant := range clients {
+		lower[strings.ToLower(client)] = tenant
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.TenantHeader = header
+	h.DefaultTenant = def
+	h.KnownTenants = known
+	h.ClientTenants = lower
+	h.IsolateTenants = isolate
+}
+
+// tenant geeft de tenant van de request: die van de client identity als die
+// in tenants.clients staat (een andere header is dan een fout), anders de
+// header, anders tenants.default.
+func (h *EventHandler) tenant(c *gin.Context) (string, error) {
+	client := middleware.GetClientID(c)
+	h.mu.RLock()
+	header, def, known := h.TenantHeader, h.DefaultTenant, h.KnownTenants
+	mapped, ok := h.ClientTenants[strings.ToLower(client)]
+	h.mu.RUnlock()
+	mapped, _, _ = strings.Cut(mapped, "/") // tenant/namespace
+
+	requested := c.GetHeader(header)
+	tenant := requested
+	switch {
+	case ok && requested != "" && requested != mapped:
+		return "", fmt.Errorf("%w: client %q may not publish for tenant %q", errTenantNotAllowed, client, requested)
+	case ok:
+		tenant = mapped
+	case tenant == "":
+		tenant = def
+	}
+	switch {
+	case tenant == "":
+		return "", fmt.Errorf("%w: set the %s header", errMissingTenant, header)
+	case !tenantName.MatchString(tenant):
+		return "", fmt.Errorf("%w %q: only letters, digits, '_', '.', '=' and '-'", errUnknownTenant, tenant)
+	case len(known) > 0 && !slices.Contains(known, tenant):
+		return "", fmt.Errorf("%w %q", errUnknownTenant, tenant)
+	}
+	return tenant, nil
+}
+
+// tenantTopic vult {tenant} in topic in; een topic zonder {tenant} blijft
+// ongewijzigd, ook zonder tenant in de request.
+func (h *EventHandler) tenantTopic(c *gin.Context, topic string) (string, error) {
+	if !strings.Contains(topic, tenantPlaceholder) {
+		return topic, nil
+	}
+	tenant, err := h.tenant(c)
+	if err != nil {
+		return topic, err
+	}
+	return strings.ReplaceAll(topic, tenantPlaceholder, tenant), nil
+}
+
+// checkIsolation: met tenants.isolate mag een client uit tenants.clients
+// enkel publiceren op topics van zijn tenant, of van zijn namespace als die
+// er ook staat. Clients zonder entry zijn niet beperkt.
+func (h *EventHandler) checkIsolation(c *gin.Context, topic string) error {
+	client := middleware.GetClientID(c)
+	h.mu.RLock()
+	isolate := h.IsolateTenants
+	bound, ok := h.ClientTenants[strings.ToLower(client)]
+	h.mu.RUnlock()
+	if !isolate || !ok {
+		return nil
+	}
+	tenant, namespace := topicNamespace(topic)
+	wantTenant, wantNamespace, withNamespace := strings.Cut(bound, "/")
+	if tenant != wantTenant || (withNamespace && namespace != wantNamespace) {
+		return fmt.Errorf("%w: client %q is bound to %s, topic %s is not", errTenantNotAllowed, client, bound, topic)
+	}
+	return nil
+}
+
+// topicNamespace geeft de tenant en namespace van topic; een korte naam ligt
+// in public/default, zoals bij de Pulsar client.
+func topicNamespace(topic string) (tenant, namespace string) {
+	_, rest, ok := strings.Cut(topic, "://")
+	if !ok {
+		return "public", "default"
+	}
+	tenant, rest, _ = strings.Cut(rest, "/")
+	namespace, _, _ = strings.Cut(rest, "/")
+	return tenant, namespace
+}
+
+// enrichStatus geeft de HTTP status en de fout van een mislukte enrich.
+func enrichStatus(err error) (int, string) {
+	if errors.Is(err, errMissingTenant) || errors.Is(err, errUnknownTenant) {
+		return http.StatusBadRequest, "invalid tenant"
+	}
+	return http.StatusForbidden, "not authorized"
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rubenclaes/pulsar-api/internal/config"
+)
+
+// errUnsupportedVersion: de eventVersion staat niet in versions.<eventType>.supported (400).
+var errUnsupportedVersion = errors.New("unsupported event version")
+
+// SetVersions zet de versies per eventType (ook bij een config reload).
+func (h *EventHandler) SetVersions(versions map[string]config.VersionConfig) {
+	lower := make(map[string]config.VersionConfig, len(versions))
+	for et, v := range versions {
+		lower[strings.ToLower(et)] = v
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Versions = lower
+}
+
+// resolveVersion vult een ontbrekende eventVersion aan met de default van
+// het eventType en controleert of de versie ondersteund is. Geeft de
+// waarschuwing voor een deprecated versie, of "".
+func (h *EventHandler) resolveVersion(req *EventRequest) (deprecation string, err error) {
+	h.mu.RLock()
+	v := h.Versions[strings.ToLower(req.EventType)]
+	h.mu.RUnlock()
+	if req.EventVersion == 0 {
+		req.EventVersion = v.Default
+	}
+	if req.EventVersion == 0 {
+		return "", nil
+	}
+	if len(v.Supported) > 0 && !slices.Contains(v.Supported, req.EventVersion) {
+		supported := make([]string, len(v.Supported))
+		for i, n := range v.Supported {
+			supported[i] = fmt.Sprintf("v%d", n)
+		}
+		return "", fmt.Errorf("%w: %s v%d, supported: %s", errUnsupportedVersion, req.EventType, req.EventVersion, strings.Join(supported, ", "))
+	}
+	if msg, ok := v.Deprecated[req.EventVersion]; ok {
+		if msg == "" {
+			msg = fmt.Sprintf("%s v%d is deprecated", req.EventType, req.EventVersion)
+		}
+		return msg, nil
+	}
+	return "", nil
+}
+
+// versionKey is de config key van een versie van een eventType, bv.
+// "wage_error@v2" in routes en schemas.
+func versionKey(eventType string, version int) string {
+	return fmt.Sprintf("%s@v%d", strings.ToLower(eventType), version)
+}
+
+// routeKey geeft de key van req in de route maps: die van de versie als er
+// een route voor is, anders die van het eventType, anders die van de eerste
+// route pattern die past (zie matchRoute). Zonder route het eventType. h.mu
+// moet gelockt zijn.
+func (h *EventHandler) routeKey(req EventRequest) string {
+	if req.EventVersion > 0 {
+		if key := versionKey(req.EventType, req.EventVersion); h.Routes[key] != "" && !h.isRoutePattern(key) {
+			return key
+		}
+	}
+	et := strings.ToLower(req.EventType)
+	if _, ok := h.Routes[et]; ok && !h.isRoutePattern(et) {
+		return et
+	}
+	if key := h.matchRoute(et, req.EventVersion); key != "" {
+		return key
+	}
+	return et
+}
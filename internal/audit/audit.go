@@ -0,0 +1,166 @@
+package audit
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Results voor Entry.Result
+const (
+	ResultSent     = "sent"
+	ResultDryRun   = "dry-run"
+	ResultRejected = "rejected" // request geweigerd vóór publish (body, schema, ...)
+	ResultFailed   = "failed"   // publish naar Pulsar mislukt
+	ResultSpooled  = "spooled"  // Pulsar onbereikbaar, bewaard in de spool
+	ResultFallback = "fallback" // verstuurd naar de fallback topic van de route
+	ResultOK       = "ok"       // geslaagde admin actie
+)
+
+// Entry is één audit record. Entries worden enkel toegevoegd, nooit aangepast.
+type Entry struct {
+	Time          time.Time              `json:"time"`
+	Actor         string                 `json:"actor"`
+	ClientIP      string                 `json:"clientIp,omitempty"`
+	Action        string                 `json:"action"` // "publish" of "admin.<actie>"
+	EventType     string                 `json:"eventType,omitempty"`
+	SourceSystem  string                 `json:"sourceSystem,omitempty"`
+	Topic         string                 `json:"topic,omitempty"`
+	Cluster       string                 `json:"cluster,omitempty"` // leeg = default
+	MessageID     string                 `json:"messageId,omitempty"`
+	Payload       map[string]interface{} `json:"payload,omitempty"` // geredacteerd
+	Result        string                 `json:"result"`
+	Error         string                 `json:"error,omitempty"`
+	CorrelationID string                 `json:"correlationId"`
+}
+
+// Sink schrijft audit entries weg, los van de applicatielogs.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// queueSize is het aantal entries dat op de sink mag wachten; daarboven
+// worden entries gedropt (en geteld) in plaats van de request op te houden.
+const queueSize = 1024
+
+// queued is een entry die wacht op de sink, met wat de foutmelding nodig heeft.
+type queued struct {
+	line                  []byte
+	action, correlationID string
+}
+
+type Logger struct {
+	sink   Sink
+	log    *zap.Logger
+	queue  chan queued
+	closed chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+
+	dropped  atomic.Int64
+	dropping atomic.Bool
+}
+
+// New geeft een audit logger terug; met een nil sink worden entries genegeerd.
+// Entries worden asynchroon naar de sink geschreven, zodat een trage sink
+// (fsync, een onbereikbare broker) de requests niet vertraagt.
+func New(sink Sink, log *zap.Logger) *Logger {
+	l := &Logger{sink: sink, log: log}
+	if sink != nil {
+		l.queue = make(chan queued, queueSize)
+		l.closed = make(chan struct{})
+		l.wg.Add(1)
+		go l.work()
+	}
+	return l
+}
+
+// Record zet een entry in de wachtrij. Fouten van de sink laten de request
+// niet falen, maar worden wel gelogd zodat een kapotte audit sink opvalt. Is
+// de wachtrij vol, dan wordt de entry gedropt en geteld (zie Dropped).
+func (l *Logger) Record(e Entry) {
+	if l == nil || l.sink == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		l.log.Error("failed to marshal audit entry", zap.Error(err), zap.String("correlationId", e.CorrelationID))
+		return
+	}
+	select {
+	case l.queue <- queued{line: line, action: e.Action, correlationID: e.CorrelationID}:
+		l.dropping.Store(false)
+	default:
+		l.dropped.Add(1)
+		if !l.dropping.Swap(true) {
+			l.log.Warn("audit queue is full, dropping entries",
+				zap.String("action", e.Action),
+				zap.String("correlationId", e.CorrelationID),
+			)
+		}
+	}
+}
+
+func (l *Logger) work() {
+	defer l.wg.Done()
+	for {
+		select {
+		case q := <-l.queue:
+			l.write(q)
+		case <-l.closed:
+			// wat nog wacht, eerst wegschrijven
+			for {
+				select {
+				case q := <-l.queue:
+					l.write(q)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (l *Logger) write(q queued) {
+	if err := l.sink.Write(q.line); err != nil {
+		l.log.Error("failed to write audit entry",
+			zap.Error(err),
+			zap.String("action", q.action),
+			zap.String("correlationId", q.correlationID),
+		)
+	}
+}
+
+// Queued geeft het aantal entries dat nog naar de sink moet.
+func (l *Logger) Queued() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.queue)
+}
+
+// Dropped geeft het aantal entries dat gedropt werd omdat de wachtrij vol was.
+func (l *Logger) Dropped() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.dropped.Load()
+}
+
+// Close schrijft de wachtende entries weg en sluit daarna de sink.
+func (l *Logger) Close() error {
+	if l == nil || l.sink == nil {
+		return nil
+	}
+	l.once.Do(func() { close(l.closed) })
+	l.wg.Wait()
+	return l.sink.Close()
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// blockingSink houdt elke Write op tot release gesloten wordt.
+type blockingSink struct {
+	release chan struct{}
+	mu      sync.Mutex
+	lines   int
+}
+
+func (s *blockingSink) Write([]byte) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lines++
+	return nil
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestRecordDoesNotWaitForTheSink(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	l := New(sink, zap.NewNop())
+
+	start := time.Now()
+	for range queueSize + 10 {
+		l.Record(Entry{Action: "publish", Result: ResultSent})
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Record took %s with a blocked sink", d)
+	}
+	// één entry zit in de Write van de worker, de wachtrij is vol
+	if n := l.Dropped(); n < 9 || n > 10 {
+		t.Errorf("dropped = %d, want 9 or 10", n)
+	}
+
+	close(sink.release)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := int(queueSize + 10 - l.Dropped()); sink.lines != want {
+		t.Errorf("sink got %d entries, want %d", sink.lines, want)
+	}
+}
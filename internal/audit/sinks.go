@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+)
+
+// FileSink schrijft JSON lines naar een append-only bestand.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// topicTimeout begrenst één send van een audit entry, zodat een
+// onbereikbare broker de wachtrij niet onbeperkt ophoudt.
+const topicTimeout = 5 * time.Second
+
+// TopicSink publiceert elke entry op een aparte Pulsar audit topic. De
+// producer wordt bij de eerste entry gemaakt, zodat een onbereikbare broker
+// het opstarten niet tegenhoudt.
+type TopicSink struct {
+	pool  *pulsar.Pool
+	topic string
+}
+
+func NewTopicSink(brokerURL, topic string, token func() (string, error)) (*TopicSink, error) {
+	pool, err := pulsar.NewPool(brokerURL, token, nil, pulsar.RetryPolicy{Attempts: 1}, pulsar.ClientMetrics{})
+	if err != nil {
+		return nil, err
+	}
+	return &TopicSink{pool: pool, topic: topic}, nil
+}
+
+func (s *TopicSink) Write(line []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), topicTimeout)
+	defer cancel()
+	_, err := s.pool.Send(ctx, s.topic, line, pulsar.SendOptions{})
+	return err
+}
+
+func (s *TopicSink) Close() error {
+	s.pool.Close()
+	return nil
+}
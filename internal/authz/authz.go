@@ -0,0 +1,86 @@
+package authz
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule beschrijft welke eventTypes en topics toegelaten zijn. Een entry die
+// eindigt op "*" is een prefix match; "*" alleen laat alles toe.
+type Rule struct {
+	EventTypes []string `mapstructure:"eventTypes" json:"eventTypes"`
+	Topics     []string `mapstructure:"topics" json:"topics"`
+}
+
+// Policy mapt client identities en scopes naar rules. Een client mag publiceren
+// als minstens één van zijn rules (eigen identity of een van zijn scopes)
+// zowel het eventType als de topic toelaat.
+type Policy struct {
+	enabled bool
+	clients map[string]Rule // lowercased client identity → rule
+	scopes  map[string]Rule // lowercased scope → rule
+}
+
+func New(enabled bool, clients, scopes map[string]Rule) *Policy {
+	return &Policy{
+		enabled: enabled,
+		clients: lowerKeys(clients),
+		scopes:  lowerKeys(scopes),
+	}
+}
+
+// viper lowercased map keys, dus we matchen case-insensitive
+func lowerKeys(in map[string]Rule) map[string]Rule {
+	out := make(map[string]Rule, len(in))
+	for k, v := range in {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+func (p *Policy) Enabled() bool {
+	return p != nil && p.enabled
+}
+
+// Authorize geeft een error terug als client (met scopes) eventType niet op
+// topic mag publiceren.
+func (p *Policy) Authorize(client string, scopes []string, eventType, topic string) error {
+	if !p.Enabled() {
+		return nil
+	}
+	if client == "" {
+		return fmt.Errorf("unauthenticated clients may not publish")
+	}
+
+	rules := make([]Rule, 0, 1+len(scopes))
+	if r, ok := p.clients[strings.ToLower(client)]; ok {
+		rules = append(rules, r)
+	}
+	for _, s := range scopes {
+		if r, ok := p.scopes[strings.ToLower(s)]; ok {
+			rules = append(rules, r)
+		}
+	}
+
+	for _, r := range rules {
+		if matchAny(r.EventTypes, eventType) && matchAny(r.Topics, topic) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client %q is not allowed to publish %s to %s", client, eventType, topic)
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(value, strings.TrimSuffix(p, "*")) {
+				return true
+			}
+			continue
+		}
+		if p == value {
+			return true
+		}
+	}
+	return false
+}
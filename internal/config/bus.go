@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Bus verdeelt nieuwe configuraties onder de componenten die ze at runtime
+// kunnen toepassen. Validators kunnen een reload weigeren vóór er iets wijzigt.
+type Bus struct {
+	mu         sync.RWMutex
+	current    *Config
+	loadedAt   time.Time
+	failedAt   time.Time // laatste geweigerde reload, na loadedAt
+	failure    error
+	prepare    []func(*Config) error
+	validators []func(*Config) error
+	subs       []func(*Config)
+}
+
+func NewBus(initial *Config) *Bus {
+	return &Bus{current: initial, loadedAt: time.Now()}
+}
+
+// BusStatus zegt sinds wanneer de huidige config geldt en of een latere
+// reload geweigerd werd (dan draait de service op een oudere config).
+type BusStatus struct {
+	LoadedAt time.Time
+	FailedAt time.Time // zero als er sindsdien geen reload faalde
+	Failure  error
+}
+
+func (b *Bus) Status() BusStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return BusStatus{LoadedAt: b.loadedAt, FailedAt: b.failedAt, Failure: b.failure}
+}
+
+func (b *Bus) Current() *Config {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.current
+}
+
+// Prepare registreert een stap die een nieuwe config aanvult vóór validatie,
+// bv. het resolven van secret referenties.
+func (b *Bus) Prepare(fn func(*Config) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prepare = append(b.prepare, fn)
+}
+
+// Validate registreert een extra controle die bij elke reload uitgevoerd wordt.
+func (b *Bus) Validate(fn func(*Config) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.validators = append(b.validators, fn)
+}
+
+// Subscribe registreert fn; die wordt bij elke geldige nieuwe config aangeroepen.
+func (b *Bus) Subscribe(fn func(*Config)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = append(b.subs, fn)
+}
+
+// Publish valideert cfg en past hem pas toe als alle controles slagen.
+func (b *Bus) Publish(cfg *Config) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current != nil {
+		cfg.inheritFlags(b.current)
+	}
+	for _, fn := range b.prepare {
+		if err := fn(cfg); err != nil {
+			b.failedAt, b.failure = time.Now(), err
+			return err
+		}
+	}
+
+	errs := []error{cfg.Validate()}
+	for _, v := range b.validators {
+		errs = append(errs, v(cfg))
+	}
+	if err := errors.Join(errs...); err != nil {
+		b.failedAt, b.failure = time.Now(), err
+		return err
+	}
+
+	b.current = cfg
+	b.loadedAt, b.failedAt, b.failure = time.Now(), time.Time{}, nil
+	for _, fn := range b.subs {
+		fn(cfg)
+	}
+	return nil
+}
@@ -0,0 +1,916 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+
+	"github.com/rubenclaes/pulsar-api/internal/authz"
+	"github.com/rubenclaes/pulsar-api/internal/enrich"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/quota"
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+	"github.com/rubenclaes/pulsar-api/internal/routing"
+	"github.com/rubenclaes/pulsar-api/internal/rules"
+	"github.com/rubenclaes/pulsar-api/internal/secrets"
+)
+
+const EnvPrefix = "PULSAR_API"
+
+// Config is de volledige, geresolvede configuratie (file + env + defaults).
+type Config struct {
+	Pulsar        PulsarConfig             `mapstructure:"pulsar"`
+	API           APIConfig                `mapstructure:"api"`
+	Routes        map[string]Route         `mapstructure:"routes"`
+	Routing       []routing.Rule           `mapstructure:"routing"` // geordende regels vóór routes
+	SchemaDir     string                   `mapstructure:"schemaDir"`
+	Schemas       map[string]string        `mapstructure:"schemas"`
+	SchemaCompat  string                   `mapstructure:"schemaCompatibility"` // none | backward | forward | full, voor PUT /admin/schemas
+	Strict        StrictConfig             `mapstructure:"strict"`
+	Versions      map[string]VersionConfig `mapstructure:"versions"`
+	SourceSystems SourceSystemsConfig      `mapstructure:"sourceSystems"`
+	Tenants       TenantsConfig            `mapstructure:"tenants"`
+	Envelope      EnvelopeConfig           `mapstructure:"envelope"`
+	CloudEvents   CloudEventsConfig        `mapstructure:"cloudEvents"`
+	IPFilter      map[string]IPFilterRules `mapstructure:"ipFilter"`
+	Signature     SignatureConfig          `mapstructure:"signature"`
+	APIKeys       []middleware.APIKey      `mapstructure:"apiKeys"`
+	UI            UIConfig                 `mapstructure:"ui"`
+	Authorization AuthorizationConfig      `mapstructure:"authorization"`
+	Quotas        QuotaConfig              `mapstructure:"quotas"`
+	Audit         AuditConfig              `mapstructure:"audit"`
+	Tracing       TracingConfig            `mapstructure:"tracing"`
+	Metrics       MetricsConfig            `mapstructure:"metrics"`
+	Redaction     map[string][]redact.Rule `mapstructure:"redaction"`
+	RedactSecret  string                   `mapstructure:"redactionSecret"` // HMAC key van action hash, mag een secret referentie zijn
+	Rules         map[string][]rules.Rule  `mapstructure:"rules"`
+	Enrichment    map[string][]enrich.Step `mapstructure:"enrichment"`
+	Defaults      map[string][]Default     `mapstructure:"defaults"`
+	Admin         AdminConfig              `mapstructure:"admin"`
+	Secrets       SecretsConfig            `mapstructure:"secrets"`
+	Remote        RemoteConfig             `mapstructure:"remote"`
+	Logging       LoggingConfig            `mapstructure:"logging"`
+	Sentry        SentryConfig             `mapstructure:"sentry"`
+	Spool         SpoolConfig              `mapstructure:"spool"`
+	DeadLetter    DeadLetterConfig         `mapstructure:"deadLetter"`
+	Idempotency   IdempotencyConfig        `mapstructure:"idempotency"`
+	Recent        RecentConfig             `mapstructure:"recent"`
+	Registry      RegistryConfig           `mapstructure:"registry"`
+
+	// platte key → waarde weergave en herkomst, voor Diff en Effective
+	settings map[string]interface{}
+	origins  map[string]string
+	sources  []string // bestanden en remote store, zie Read
+}
+
+// Route is de topic van een eventType, met optioneel eigen producer opties.
+// In de config mag een route ook gewoon de topic naam zijn. Een key met * of
+// ? (bv. *_ERROR) of een route met pattern geldt voor een familie van
+// eventTypes, zie RoutePatterns.
+type Route struct {
+	Topic                  string `mapstructure:"topic"`
+	Pattern                string `mapstructure:"pattern"`         // regex op het eventType, de key is dan enkel een naam
+	FallbackTopic          string `mapstructure:"fallbackTopic"`   // als de topic onbereikbaar is, leeg = geen
+	Cluster                string `mapstructure:"cluster"`         // uit pulsar.clusters, leeg = default
+	ShadowTopic            string `mapstructure:"shadowTopic"`     // topic op de shadow cluster, leeg = dezelfde naam
+	MaxPayloadBytes        int64  `mapstructure:"maxPayloadBytes"` // grotere payloads krijgen 413, 0 = geen limiet
+	pulsar.ProducerOptions `mapstructure:",squash"`
+}
+
+type PulsarConfig struct {
+	URL            string                `mapstructure:"url"`
+	DefaultTopic   string                `mapstructure:"defaultTopic"`
+	AuthToken      string                `mapstructure:"authToken"` // mag een secret:<path>#<field> referentie zijn
+	Retry          pulsar.RetryPolicy    `mapstructure:"retry"`
+	CircuitBreaker pulsar.BreakerOptions `mapstructure:"circuitBreaker"`
+	InFlight       pulsar.InFlightLimits `mapstructure:"inFlight"` // max. lopende sends, daarboven 429
+	Shadow         pulsar.ShadowOptions  `mapstructure:"shadow"`   // elk bericht ook naar een tweede cluster
+	AdminURL       string                `mapstructure:"adminURL"` // admin API (http://host:8080), voor schemaRegistry en provision
+	SchemaRegistry SchemaRegistryConfig  `mapstructure:"schemaRegistry"`
+
+	// extra clusters naast url (de cluster "default"), te kiezen per route;
+	// elk met een eigen client, producers en circuit breaker
+	Clusters map[string]ClusterConfig `mapstructure:"clusters"`
+
+	// ontbrekende tenants, namespaces en topics aanmaken via adminURL
+	Provision pulsar.ProvisionOptions `mapstructure:"provision"`
+
+	// controleren dat elke topic bestaat en er geproduceerd mag worden
+	VerifyTopics VerifyTopicsConfig `mapstructure:"verifyTopics"`
+}
+
+// VerifyTopicsConfig: bij het opstarten, na een reload en elke Interval de
+// producer van elke topic maken; een fout staat in /health en eventueel
+// /ready. Enabled wordt bij het opstarten gelezen.
+type VerifyTopicsConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Interval      time.Duration `mapstructure:"interval"`      // 0 = enkel bij het opstarten en een reload
+	Timeout       time.Duration `mapstructure:"timeout"`       // per topic
+	FailReadiness bool          `mapstructure:"failReadiness"` // /ready 503 zolang een topic faalt
+}
+
+// ClusterConfig is de verbinding met een extra Pulsar cluster. Retry en
+// circuitBreaker zijn die van pulsar. Wordt enkel bij het opstarten gelezen.
+type ClusterConfig struct {
+	URL       string `mapstructure:"url"`
+	AuthToken string `mapstructure:"authToken"` // mag een secret referentie zijn
+	AdminURL  string `mapstructure:"adminURL"`
+}
+
+// SchemaRegistryConfig: payloads ook valideren tegen het schema dat in de
+// schema registry van Pulsar op de topic staat. Wordt enkel bij het
+// opstarten gelezen.
+type SchemaRegistryConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	CacheTTL time.Duration `mapstructure:"cacheTTL"` // hoe lang een opgehaald schema geldt
+	Timeout  time.Duration `mapstructure:"timeout"`  // per request naar de admin API
+}
+
+type APIConfig struct {
+	DryRun            bool              `mapstructure:"dryRun"`
+	Port              int               `mapstructure:"port"`
+	ReadTimeout       time.Duration     `mapstructure:"readTimeout"`
+	ReadHeaderTimeout time.Duration     `mapstructure:"readHeaderTimeout"`
+	WriteTimeout      time.Duration     `mapstructure:"writeTimeout"`
+	IdleTimeout       time.Duration     `mapstructure:"idleTimeout"`
+	RequestTimeout    time.Duration     `mapstructure:"requestTimeout"`
+	ShutdownTimeout   time.Duration     `mapstructure:"shutdownTimeout"` // hoe lang lopende requests mogen afwerken bij SIGTERM
+	Concurrency       ConcurrencyConfig `mapstructure:"concurrency"`
+	Maintenance       MaintenanceConfig `mapstructure:"maintenance"`
+	TLS               TLSConfig         `mapstructure:"tls"`
+	TrustedProxies    []string          `mapstructure:"trustedProxies"`  // IP's/CIDR's van load balancers die X-Forwarded-For mogen zetten
+	RemoteIPHeaders   []string          `mapstructure:"remoteIPHeaders"` // headers met het client IP, in volgorde
+	Gin               GinConfig         `mapstructure:"gin"`
+	Batch             BatchConfig       `mapstructure:"batch"`
+	MediaTypeVendor   string            `mapstructure:"mediaTypeVendor"` // application/vnd.<vendor>.<eventType>[.v<n>]+json kiest het eventType, "" = uit
+	Priority          PriorityConfig    `mapstructure:"priority"`
+}
+
+// PriorityConfig: een priority lane zodat dringende events (bv. kritieke
+// fouten) niet achter bulk imports aanschuiven. Een event met de header, of
+// met een priority veld, met één van Values gaat naar zijn topic met
+// TopicSuffix en mag de gereserveerde plaatsen van api.concurrency en
+// pulsar.inFlight gebruiken. Volgt een reload.
+type PriorityConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Header      string   `mapstructure:"header"`      // standaard X-Priority
+	Values      []string `mapstructure:"values"`      // case-insensitief, standaard [high, critical]
+	TopicSuffix string   `mapstructure:"topicSuffix"` // bv. -priority: een eigen topic en producer, leeg = dezelfde topic
+}
+
+type BatchConfig struct {
+	Parallelism     int   `mapstructure:"parallelism"`     // items van een batch die tegelijk gepubliceerd worden
+	StreamThreshold int64 `mapstructure:"streamThreshold"` // bodies boven zoveel bytes gestreamd decoderen, 0 = nooit
+}
+
+// GinConfig stelt de gin engine in; wordt enkel bij het opstarten gelezen.
+type GinConfig struct {
+	Mode                   string `mapstructure:"mode"` // debug, release of test; leeg = GIN_MODE
+	MaxMultipartMemory     int64  `mapstructure:"maxMultipartMemory"`
+	HandleMethodNotAllowed bool   `mapstructure:"handleMethodNotAllowed"`
+}
+
+type ConcurrencyConfig struct {
+	MaxInFlight int           `mapstructure:"maxInFlight"`
+	Reserved    int           `mapstructure:"reserved"` // van MaxInFlight, enkel voor priority requests
+	QueueWait   time.Duration `mapstructure:"queueWait"`
+	RetryAfter  time.Duration `mapstructure:"retryAfter"`
+}
+
+type MaintenanceConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Message string `mapstructure:"message"`
+}
+
+type TLSConfig struct {
+	CertFile         string            `mapstructure:"certFile"`
+	KeyFile          string            `mapstructure:"keyFile"`
+	ClientCAFile     string            `mapstructure:"clientCAFile"`
+	Secret           string            `mapstructure:"secret"` // secret path met certificate/private_key, i.p.v. certFile/keyFile
+	RedirectPort     int               `mapstructure:"redirectPort"`
+	ClientIdentities map[string]string `mapstructure:"clientIdentities"`
+	ACME             ACMEConfig        `mapstructure:"acme"`
+}
+
+type ACMEConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Hosts        []string `mapstructure:"hosts"`
+	CacheDir     string   `mapstructure:"cacheDir"`
+	Email        string   `mapstructure:"email"`
+	DirectoryURL string   `mapstructure:"directoryURL"`
+}
+
+type IPFilterRules struct {
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+type SignatureConfig struct {
+	Required bool              `mapstructure:"required"`
+	Secrets  map[string]string `mapstructure:"secrets"`
+	Window   time.Duration     `mapstructure:"window"`
+	NonceTTL time.Duration     `mapstructure:"nonceTTL"`
+
+	AllowUnsignedSessions bool `mapstructure:"allowUnsignedSessions"` // UI sessies zonder signature bij required
+}
+
+type AuthorizationConfig struct {
+	Enabled bool                  `mapstructure:"enabled"`
+	Clients map[string]authz.Rule `mapstructure:"clients"`
+	Scopes  map[string]authz.Rule `mapstructure:"scopes"`
+}
+
+type QuotaConfig struct {
+	Default quota.Limits            `mapstructure:"default"`
+	Clients map[string]quota.Limits `mapstructure:"clients"`
+}
+
+type AuditConfig struct {
+	Sink  string `mapstructure:"sink"`
+	File  string `mapstructure:"file"`
+	Topic string `mapstructure:"topic"`
+}
+
+type TracingConfig struct {
+	Enabled     bool    `mapstructure:"enabled"`
+	Endpoint    string  `mapstructure:"endpoint"`
+	Insecure    bool    `mapstructure:"insecure"`
+	ServiceName string  `mapstructure:"serviceName"`
+	SampleRatio float64 `mapstructure:"sampleRatio"`
+}
+
+// SpoolConfig bewaart events op schijf als Pulsar onbereikbaar is; wordt
+// enkel bij het opstarten gelezen.
+type SpoolConfig struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	Dir           string        `mapstructure:"dir"`
+	MaxBytes      int64         `mapstructure:"maxBytes"` // 0 = onbeperkt
+	RetryInterval time.Duration `mapstructure:"retryInterval"`
+}
+
+// DeadLetterConfig bewaart events die definitief niet verstuurd konden worden
+// (zie /admin/failures); wordt enkel bij het opstarten gelezen.
+// MetricsConfig: Prometheus metrics op /metrics (ipFilter group "metrics")
+// en/of via OTLP naar een collector. Enkel bij het opstarten gelezen.
+type MetricsConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`
+	Prometheus  bool              `mapstructure:"prometheus"`  // /metrics serveren
+	ClientLabel bool              `mapstructure:"clientLabel"` // label "client" op de event- en request metrics
+	MaxClients  int               `mapstructure:"maxClients"`  // daarboven telt een client als "other"
+	OTLP        MetricsOTLPConfig `mapstructure:"otlp"`
+}
+
+// MetricsOTLPConfig pusht de metrics via OTLP/HTTP; zonder endpoint naar
+// dezelfde collector als de traces (tracing.endpoint en tracing.insecure).
+type MetricsOTLPConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Endpoint string        `mapstructure:"endpoint"`
+	Insecure bool          `mapstructure:"insecure"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// RecentConfig: de laatste size aanvaarde events in het geheugen, voor
+// /api/v1/recent. 0 = uit; aan of uit enkel bij het opstarten, de grootte
+// volgt een reload.
+type RecentConfig struct {
+	Size int `mapstructure:"size"`
+}
+
+// StrictConfig: properties die het JSON Schema niet kent weigeren, voor
+// alle eventTypes (enabled, dan ook onbekende velden in de envelope) of
+// enkel voor eventTypes. Volgt een reload.
+type StrictConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	EventTypes []string `mapstructure:"eventTypes"`
+}
+
+// VersionConfig: de versies van een eventType (eventVersion in de request).
+// Een versie kan een eigen route en schema hebben, met key
+// "<eventType>@v<n>" in routes en schemas. Volgt een reload.
+type VersionConfig struct {
+	Default    int            `mapstructure:"default"`    // versie als eventVersion ontbreekt, 0 = geen
+	Supported  []int          `mapstructure:"supported"`  // andere versies krijgen 400, leeg = elke versie
+	Deprecated map[int]string `mapstructure:"deprecated"` // versie → waarschuwing in de response, "" = standaard tekst
+}
+
+// Default is de waarde van een veld in de payload (pad met punten, bv.
+// "channel" of "address.country") als de caller het weglaat. Volgt een
+// reload.
+type Default struct {
+	Field string      `mapstructure:"field"`
+	Value interface{} `mapstructure:"value"`
+}
+
+// SourceSystemsConfig: de gekende sourceSystems, zodat downstream analytics
+// niet vervuild worden door willekeurige waarden, en eventueel per client
+// welke hij mag gebruiken. Volgt een reload.
+type SourceSystemsConfig struct {
+	Known   []string            `mapstructure:"known"`   // leeg = elke sourceSystem
+	Clients map[string][]string `mapstructure:"clients"` // client → sourceSystems ("*" = alle gekende), ontbrekend = alle gekende
+
+	// sourceSystem → eventType ("*" = alle) → topic, wint van routes
+	Routes map[string]map[string]string `mapstructure:"routes"`
+}
+
+// TenantsConfig: één instance voor meerdere Pulsar tenants. In een topic
+// (pulsar.defaultTopic, routes, fallbackTopic) wordt {tenant} de tenant van
+// de request: die van de client identity, anders de header, anders Default.
+// Met Isolate kan een client uit Clients niet buiten zijn tenant publiceren,
+// ook niet via een route of routing regel. Volgt een reload.
+type TenantsConfig struct {
+	Header  string            `mapstructure:"header"`  // standaard X-Tenant
+	Default string            `mapstructure:"default"` // zonder header, "" = header verplicht
+	Known   []string          `mapstructure:"known"`   // gekende tenants, verplicht met een {tenant} topic
+	Clients map[string]string `mapstructure:"clients"` // client identity → tenant of tenant/namespace, een andere header geeft 403
+	Isolate bool              `mapstructure:"isolate"` // clients uit Clients enkel naar topics van hun tenant (of namespace)
+}
+
+// EnvelopeConfig: metadata die de API in elk gepubliceerd bericht zet, zodat
+// consumers die voor alle eventTypes op dezelfde manier vinden. Volgt een
+// reload.
+type EnvelopeConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`           // eventId, ingestedAt, gateway en schemaVersion invullen
+	Gateway           string `mapstructure:"gateway"`           // naam van deze instance, "" = hostname
+	RequireOccurredAt bool   `mapstructure:"requireOccurredAt"` // events zonder occurredAt weigeren (400)
+}
+
+// CloudEventsConfig: events als CloudEvents 1.0 in structured mode
+// (application/cloudevents+json en application/cloudevents-batch+json).
+// Volgt een reload.
+type CloudEventsConfig struct {
+	Enabled    bool                           `mapstructure:"enabled"`
+	TypePrefix string                         `mapstructure:"typePrefix"` // wordt van type geknipt voor het eventType, bv. "be.acerta."
+	Extensions map[string]CloudEventExtension `mapstructure:"extensions"` // naam → controle van het extension attribuut
+}
+
+// CloudEventExtension controleert een extension attribuut van een CloudEvent.
+type CloudEventExtension struct {
+	Required bool   `mapstructure:"required"`
+	Type     string `mapstructure:"type"`    // string (default), integer, boolean, uri of timestamp
+	Pattern  string `mapstructure:"pattern"` // regex voor de waarde, enkel bij string, uri en timestamp
+}
+
+// RegistryConfig: de JSON Schema's van eventTypes uit een centrale schema
+// registry. Enkel subjects volgt een reload.
+type RegistryConfig struct {
+	Provider string            `mapstructure:"provider"` // none, confluent of apicurio
+	URL      string            `mapstructure:"url"`
+	Group    string            `mapstructure:"group"`    // apicurio
+	Username string            `mapstructure:"username"` // basic auth
+	Password string            `mapstructure:"password"` // mag een secret referentie zijn
+	Token    string            `mapstructure:"token"`    // bearer token, mag een secret referentie zijn
+	Subjects map[string]string `mapstructure:"subjects"` // eventType → subject (confluent) of artifactId (apicurio)
+	CacheTTL time.Duration     `mapstructure:"cacheTTL"`
+	Timeout  time.Duration     `mapstructure:"timeout"`
+}
+
+type DeadLetterConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Dir      string `mapstructure:"dir"`
+	MaxBytes int64  `mapstructure:"maxBytes"` // 0 = onbeperkt
+}
+
+// IdempotencyConfig: retries met een Idempotency-Key, en met dedupWindow ook
+// identieke events, krijgen de eerste response i.p.v. opnieuw gepubliceerd te
+// worden. Store en redis worden enkel bij het opstarten gelezen.
+type IdempotencyConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	TTL         time.Duration `mapstructure:"ttl"`         // hoe lang een Idempotency-Key onthouden wordt
+	DedupWindow time.Duration `mapstructure:"dedupWindow"` // identieke events zonder key, 0 = uit
+	Store       string        `mapstructure:"store"`       // memory (één replica) of redis (gedeeld)
+	Redis       RedisConfig   `mapstructure:"redis"`
+}
+
+type RedisConfig struct {
+	Addr      string `mapstructure:"addr"` // host:port
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"` // mag een secret referentie zijn
+	DB        int    `mapstructure:"db"`
+	TLS       bool   `mapstructure:"tls"`
+	KeyPrefix string `mapstructure:"keyPrefix"`
+}
+
+// SentryConfig rapporteert panics en 5xx responses aan Sentry of GlitchTip.
+type SentryConfig struct {
+	DSN         string  `mapstructure:"dsn"` // leeg = uit, mag een secret referentie zijn
+	Environment string  `mapstructure:"environment"`
+	Release     string  `mapstructure:"release"`
+	SampleRate  float64 `mapstructure:"sampleRate"`
+}
+
+// UIConfig: met login moet je in de tester UI eerst inloggen met een van de
+// apiKeys; de sessie zit in een cookie (zie middleware.Sessions). Enkel bij
+// het opstarten.
+type UIConfig struct {
+	Login        bool          `mapstructure:"login"`
+	SessionTTL   time.Duration `mapstructure:"sessionTTL"`
+	SecureCookie bool          `mapstructure:"secureCookie"` // cookies enkel over HTTPS
+}
+
+type AdminConfig struct {
+	Clients []string `mapstructure:"clients"`
+}
+
+type SecretsConfig struct {
+	Provider        string        `mapstructure:"provider"` // none, vault of aws
+	RefreshInterval time.Duration `mapstructure:"refreshInterval"`
+	Vault           VaultConfig   `mapstructure:"vault"`
+	AWS             AWSConfig     `mapstructure:"aws"`
+}
+
+type VaultConfig struct {
+	Address   string `mapstructure:"address"`
+	Namespace string `mapstructure:"namespace"`
+	Auth      string `mapstructure:"auth"`
+	Token     string `mapstructure:"token"`
+	Role      string `mapstructure:"role"`
+	Mount     string `mapstructure:"mount"`
+	JWTFile   string `mapstructure:"jwtFile"`
+	CAFile    string `mapstructure:"caFile"`
+}
+
+// LoggingConfig wordt bij het opstarten gelezen, enkel level volgt een reload.
+type LoggingConfig struct {
+	Mode     string         `mapstructure:"mode"`     // development of production
+	Encoding string         `mapstructure:"encoding"` // console of json, leeg = volgens mode
+	Level    string         `mapstructure:"level"`    // debug, info, warn of error, leeg = volgens mode; herlaadbaar
+	Sampling SamplingConfig `mapstructure:"sampling"` // enkel debug en info
+	File     LogFileConfig  `mapstructure:"file"`
+	Bodies   BodyLogConfig  `mapstructure:"bodies"` // herlaadbaar
+	OTLP     LogOTLPConfig  `mapstructure:"otlp"`
+}
+
+// LogOTLPConfig stuurt de logs ook via OTLP/HTTP; zonder endpoint gaan ze naar
+// dezelfde collector als de traces (tracing.endpoint en tracing.insecure).
+type LogOTLPConfig struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	Endpoint string `mapstructure:"endpoint"`
+	Insecure bool   `mapstructure:"insecure"`
+}
+
+// BodyLogConfig logt request/response bodies op debug level.
+type BodyLogConfig struct {
+	Enabled  bool `mapstructure:"enabled"`
+	MaxBytes int  `mapstructure:"maxBytes"` // per body, de rest wordt afgekapt
+}
+
+// LogFileConfig schrijft de logs ook naar een bestand met rotatie.
+type LogFileConfig struct {
+	Path       string `mapstructure:"path"` // leeg = enkel stdout
+	MaxSizeMB  int    `mapstructure:"maxSizeMB"`
+	MaxAgeDays int    `mapstructure:"maxAgeDays"`
+	MaxBackups int    `mapstructure:"maxBackups"`
+	Compress   bool   `mapstructure:"compress"`
+}
+
+type SamplingConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Initial    int           `mapstructure:"initial"`    // per boodschap en per tick altijd gelogd
+	Thereafter int           `mapstructure:"thereafter"` // daarna elke n-de
+	Tick       time.Duration `mapstructure:"tick"`
+}
+
+// RemoteConfig wijst naar het centrale config document (enkel in de lokale config).
+type RemoteConfig struct {
+	Provider string `mapstructure:"provider"` // none, consul of etcd
+	Endpoint string `mapstructure:"endpoint"`
+	Key      string `mapstructure:"key"`
+	Token    string `mapstructure:"token"`
+}
+
+type AWSConfig struct {
+	Region   string `mapstructure:"region"`
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// New maakt de viper instance met de gekende config paden, env overrides
+// (pulsar.url → PULSAR_API_PULSAR_URL) en defaults.
+func New() *viper.Viper {
+	v := viper.New()
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
+
+	// 1. Local dev path
+	v.AddConfigPath("./config") // go run
+	v.AddConfigPath(".")        // fallback
+
+	// 2. Standard OS config locations (cross-platform best practice)
+	v.AddConfigPath("/etc/pulsar-api/")
+	v.AddConfigPath("$HOME/.config/pulsar-api/")
+	v.AddConfigPath("$XDG_CONFIG_HOME/pulsar-api/")
+
+	// 3. Env overrides: pulsar.url → PULSAR_API_PULSAR_URL, api.dryRun → PULSAR_API_API_DRYRUN
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	v.SetDefault("api.port", 8969)
+	v.SetDefault("pulsar.retry.attempts", 3)
+	v.SetDefault("pulsar.retry.baseDelay", "100ms")
+	v.SetDefault("pulsar.retry.maxDelay", "2s")
+	v.SetDefault("pulsar.retry.jitter", 0.2)
+	v.SetDefault("pulsar.circuitBreaker.consecutiveFailures", 5)
+	v.SetDefault("pulsar.circuitBreaker.errorRate", 0.5)
+	v.SetDefault("pulsar.circuitBreaker.minRequests", 20)
+	v.SetDefault("pulsar.circuitBreaker.window", "30s")
+	v.SetDefault("pulsar.circuitBreaker.openTimeout", "10s")
+	v.SetDefault("pulsar.shadow.queueSize", 10000)
+	v.SetDefault("pulsar.shadow.timeout", "5s")
+	v.SetDefault("pulsar.schemaRegistry.cacheTTL", "1m")
+	v.SetDefault("pulsar.schemaRegistry.timeout", "5s")
+	v.SetDefault("pulsar.provision.timeout", "10s")
+	v.SetDefault("pulsar.verifyTopics.interval", "5m")
+	v.SetDefault("pulsar.verifyTopics.timeout", "10s")
+	v.SetDefault("registry.provider", "none")
+	v.SetDefault("registry.group", "default")
+	v.SetDefault("registry.cacheTTL", "5m")
+	v.SetDefault("registry.timeout", "10s")
+	v.SetDefault("api.dryRun", false)
+	v.SetDefault("api.readTimeout", "15s")
+	v.SetDefault("api.readHeaderTimeout", "5s")
+	v.SetDefault("api.writeTimeout", "30s")
+	v.SetDefault("api.idleTimeout", "60s")
+	v.SetDefault("api.requestTimeout", "10s")
+	v.SetDefault("api.shutdownTimeout", "20s")
+	v.SetDefault("api.concurrency.queueWait", "250ms")
+	v.SetDefault("api.concurrency.retryAfter", "1s")
+	v.SetDefault("api.remoteIPHeaders", []string{"X-Forwarded-For", "X-Real-IP"})
+	v.SetDefault("api.gin.maxMultipartMemory", 32<<20) // gin default
+	v.SetDefault("api.batch.parallelism", 8)
+	v.SetDefault("api.batch.streamThreshold", 1<<20)
+	v.SetDefault("api.priority.header", "X-Priority")
+	v.SetDefault("api.priority.values", []string{"high", "critical"})
+	v.SetDefault("tenants.header", "X-Tenant")
+	v.SetDefault("signature.window", "5m")
+	v.SetDefault("signature.nonceTTL", "10m")
+	v.SetDefault("schemaDir", "schemas")
+	v.SetDefault("schemaCompatibility", "backward")
+	v.SetDefault("secrets.provider", "none")
+	v.SetDefault("secrets.refreshInterval", "5m")
+	v.SetDefault("secrets.vault.auth", "token")
+	v.SetDefault("audit.sink", "none")
+	v.SetDefault("audit.file", "audit.log")
+	v.SetDefault("logging.mode", "development")
+	v.SetDefault("logging.sampling.initial", 100)
+	v.SetDefault("logging.sampling.thereafter", 100)
+	v.SetDefault("logging.sampling.tick", "1s")
+	v.SetDefault("logging.bodies.maxBytes", 4096)
+	v.SetDefault("logging.file.maxSizeMB", 100)
+	v.SetDefault("logging.file.maxAgeDays", 28)
+	v.SetDefault("logging.file.maxBackups", 7)
+	v.SetDefault("tracing.serviceName", "pulsar-api")
+	v.SetDefault("tracing.sampleRatio", 1.0)
+	v.SetDefault("metrics.enabled", true)
+	v.SetDefault("metrics.prometheus", true)
+	v.SetDefault("metrics.maxClients", 50)
+	v.SetDefault("metrics.otlp.interval", "30s")
+	v.SetDefault("sentry.sampleRate", 1.0)
+	v.SetDefault("spool.dir", "spool")
+	v.SetDefault("spool.maxBytes", 1<<30)
+	v.SetDefault("spool.retryInterval", "5s")
+	v.SetDefault("deadLetter.dir", "deadletter")
+	v.SetDefault("deadLetter.maxBytes", 100<<20)
+	v.SetDefault("recent.size", 100)
+	v.SetDefault("ui.sessionTTL", "8h")
+	v.SetDefault("ui.secureCookie", true)
+	v.SetDefault("idempotency.enabled", true)
+	v.SetDefault("idempotency.ttl", "1h")
+	v.SetDefault("idempotency.store", "memory")
+	v.SetDefault("idempotency.redis.keyPrefix", "pulsar-api:")
+
+	// zonder config bestand kent viper enkel de keys met een default; elke key
+	// expliciet aan zijn env variabele binden zodat Unmarshal ze ook ziet
+	bindEnv(v, reflect.TypeOf(Config{}), "")
+
+	return v
+}
+
+// bindEnv bindt elke enkelvoudige key van t (recursief door structs) aan zijn
+// env variabele. Maps en lijsten van structs (routes, apiKeys, ...) komen uit
+// het bestand of uit envStringMap.
+func bindEnv(v *viper.Viper, t reflect.Type, prefix string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		switch {
+		case f.Type.Kind() == reflect.Struct:
+			bindEnv(v, f.Type, key+".")
+		case f.Type.Kind() == reflect.Map,
+			f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct:
+			continue
+		default:
+			_ = v.BindEnv(key) // faalt enkel zonder key
+		}
+	}
+}
+
+// Load zet de huidige viper state om naar een Config.
+func Load(v *viper.Viper) (*Config, error) {
+	var cfg Config
+	hooks := mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToSliceHookFunc(","),
+		jsonStringToMap,
+		stringToRoute,
+		stringToRedactRule,
+	)
+	if err := v.Unmarshal(&cfg, viper.DecodeHook(hooks)); err != nil {
+		return nil, err
+	}
+	if cfg.Routes == nil {
+		cfg.Routes = map[string]Route{}
+	}
+	for et, topic := range envStringMap(nil, "routes") {
+		r := cfg.Routes[et]
+		r.Topic = topic
+		cfg.Routes[et] = r
+	}
+	cfg.Schemas = envStringMap(cfg.Schemas, "schemas")
+	cfg.Signature.Secrets = envStringMap(cfg.Signature.Secrets, "signature.secrets")
+	cfg.Registry.Subjects = envStringMap(cfg.Registry.Subjects, "registry.subjects")
+	cfg.settings = flatten("", v.AllSettings())
+	cfg.origins = origins(v, cfg.settings)
+	return &cfg, nil
+}
+
+// jsonStringToMap laat een volledige map als JSON in één env variabele toe,
+// bv. PULSAR_API_SCHEMAS='{"WAGE_ERROR":"schemas/wage.json"}'.
+func jsonStringToMap(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to.Kind() != reflect.Map {
+		return data, nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(data.(string)), &m); err != nil {
+		return nil, fmt.Errorf("expected a JSON object: %w", err)
+	}
+	return m, nil
+}
+
+// stringToRoute laat een route als enkel de topic naam toe.
+func stringToRoute(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(Route{}) {
+		return data, nil
+	}
+	return Route{Topic: data.(string)}, nil
+}
+
+// stringToRedactRule laat een redaction rule als enkel het pad toe (mask).
+func stringToRedactRule(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() != reflect.String || to != reflect.TypeOf(redact.Rule{}) {
+		return data, nil
+	}
+	return redact.Rule{Path: data.(string)}, nil
+}
+
+// RouteTopics geeft eventType → topic.
+func (c *Config) RouteTopics() map[string]string {
+	out := make(map[string]string, len(c.Routes))
+	for et, r := range c.Routes {
+		out[et] = r.Topic
+	}
+	return out
+}
+
+// RoutePatterns geeft route key → regex op het eventType, voor routes met
+// een wildcard key of een pattern; een eventType met een eigen route gebruikt
+// die.
+func (c *Config) RoutePatterns() map[string]string {
+	out := map[string]string{}
+	for key, r := range c.Routes {
+		if p := r.eventTypePattern(key); p != "" {
+			out[key] = p
+		}
+	}
+	return out
+}
+
+// eventTypePattern geeft de regex (case-insensitief, volledig) van een route
+// met een wildcard key of pattern, "" voor een gewone route. Een @v<n> in de
+// key hoort niet bij het pattern.
+func (r Route) eventTypePattern(key string) string {
+	et, _, _ := strings.Cut(key, "@")
+	switch {
+	case r.Pattern != "":
+		return "(?i)^(?:" + r.Pattern + ")$"
+	case strings.ContainsAny(et, "*?"):
+		p := strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(et))
+		return "(?i)^" + p + "$"
+	}
+	return ""
+}
+
+// FallbackTopics geeft eventType → fallback topic, voor routes die er een hebben.
+func (c *Config) FallbackTopics() map[string]string {
+	out := map[string]string{}
+	for et, r := range c.Routes {
+		if r.FallbackTopic != "" {
+			out[et] = r.FallbackTopic
+		}
+	}
+	return out
+}
+
+// PayloadLimits geeft eventType → max. grootte van de payload, voor routes
+// met een limiet.
+func (c *Config) PayloadLimits() map[string]int64 {
+	out := map[string]int64{}
+	for et, r := range c.Routes {
+		if r.MaxPayloadBytes > 0 {
+			out[et] = r.MaxPayloadBytes
+		}
+	}
+	return out
+}
+
+// ShadowOptions geeft pulsar.shadow met de clusternaam zoals in Clusters.
+func (c *Config) ShadowOptions() pulsar.ShadowOptions {
+	o := c.Pulsar.Shadow
+	o.Cluster = strings.ToLower(o.Cluster)
+	return o
+}
+
+// ShadowTopics geeft topic → topic op de shadow cluster, voor routes met een
+// andere shadowTopic.
+func (c *Config) ShadowTopics() map[string]string {
+	out := map[string]string{}
+	for _, r := range c.Routes {
+		if r.ShadowTopic != "" {
+			out[r.Topic] = r.ShadowTopic
+		}
+	}
+	return out
+}
+
+// RouteClusters geeft eventType → cluster, voor routes die niet op de
+// default cluster publiceren.
+func (c *Config) RouteClusters() map[string]string {
+	out := map[string]string{}
+	for et, r := range c.Routes {
+		if cl := r.cluster(); cl != pulsar.DefaultCluster {
+			out[et] = cl
+		}
+	}
+	return out
+}
+
+func (r Route) cluster() string {
+	if r.Cluster == "" {
+		return pulsar.DefaultCluster
+	}
+	return strings.ToLower(r.Cluster)
+}
+
+// Clusters geeft de namen van alle clusters, default eerst.
+func (c *Config) Clusters() []string {
+	return append([]string{pulsar.DefaultCluster}, sortedKeys(c.Pulsar.Clusters)...)
+}
+
+// ClusterURL geeft de broker URL en het auth token van een cluster.
+func (c *Config) ClusterURL(name string) (brokerURL, authToken string) {
+	if name == pulsar.DefaultCluster {
+		return c.Pulsar.URL, c.Pulsar.AuthToken
+	}
+	cl := c.Pulsar.Clusters[name]
+	return cl.URL, cl.AuthToken
+}
+
+// ClusterAdminURL geeft de URL van de admin API van een cluster.
+func (c *Config) ClusterAdminURL(name string) string {
+	if name == pulsar.DefaultCluster {
+		return c.Pulsar.AdminURL
+	}
+	return c.Pulsar.Clusters[name].AdminURL
+}
+
+// ConnectTopic is de topic waarmee bij het opstarten de verbinding met een
+// cluster gemaakt wordt: de default topic, of de eerste route op die
+// cluster. Leeg als geen enkele route de cluster gebruikt, of als de topic
+// {tenant} heeft en er geen tenants.default is.
+func (c *Config) ConnectTopic(cluster string) string {
+	return c.connectable(c.connectTopic(cluster))
+}
+
+func (c *Config) connectTopic(cluster string) string {
+	if cluster == pulsar.DefaultCluster {
+		return c.Pulsar.DefaultTopic
+	}
+	for _, et := range sortedKeys(c.Routes) {
+		if r := c.Routes[et]; r.cluster() == cluster {
+			return r.Topic
+		}
+	}
+	if c.Pulsar.Shadow.Enabled && strings.EqualFold(c.Pulsar.Shadow.Cluster, cluster) {
+		if t, ok := c.ShadowTopics()[c.Pulsar.DefaultTopic]; ok {
+			return t
+		}
+		return c.Pulsar.DefaultTopic
+	}
+	return ""
+}
+
+// connectable vult {tenant} in met tenants.default; zonder default is er
+// geen topic om vooraf mee te verbinden.
+func (c *Config) connectable(topic string) string {
+	if !strings.Contains(topic, "{tenant}") {
+		return topic
+	}
+	if c.Tenants.Default == "" {
+		return ""
+	}
+	return strings.ReplaceAll(topic, "{tenant}", c.Tenants.Default)
+}
+
+// ProducerOptions geeft de producer opties per topic op een cluster.
+// Validate zorgt dat eventTypes op dezelfde topic dezelfde opties hebben.
+func (c *Config) ProducerOptions(cluster string) map[string]pulsar.ProducerOptions {
+	out := make(map[string]pulsar.ProducerOptions, len(c.Routes))
+	for _, r := range c.Routes {
+		if r.cluster() == cluster {
+			out[r.Topic] = r.ProducerOptions
+		}
+	}
+	return out
+}
+
+// envStringMap vult een map aan met losse env variabelen per key, bv.
+// PULSAR_API_SCHEMAS_WAGE_ERROR=schemas/wage.json. Een volledige map kan ook
+// als JSON in PULSAR_API_SCHEMAS meegegeven worden (zie jsonStringToMap).
+func envStringMap(m map[string]string, key string) map[string]string {
+	if m == nil {
+		m = map[string]string{}
+	}
+	prefix := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + "_"
+	for _, kv := range os.Environ() {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		// viper lowercased map keys, env keys volgen dezelfde conventie
+		m[strings.ToLower(strings.TrimPrefix(name, prefix))] = value
+	}
+	return m
+}
+
+// ResolveSecrets vervangt secret:<path>#<field> referenties in de velden die
+// secrets mogen bevatten door hun waarde. De referenties zelf blijven in de
+// settings (en dus in Diff), de waarden komen nooit in de logs.
+func (c *Config) ResolveSecrets(ctx context.Context, r *secrets.Resolver) error {
+	var errs []error
+	resolve := func(key string, value *string) {
+		v, err := r.Resolve(ctx, *value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			return
+		}
+		*value = v
+	}
+
+	// pulsar.authToken blijft een referentie: de producer resolvet het token
+	// bij elke (re)connect, hier enkel controleren dat het bestaat
+	if secrets.IsRef(c.Pulsar.AuthToken) {
+		if _, err := r.Resolve(ctx, c.Pulsar.AuthToken); err != nil {
+			errs = append(errs, fmt.Errorf("pulsar.authToken: %w", err))
+		}
+	}
+	for _, name := range sortedKeys(c.Pulsar.Clusters) {
+		if token := c.Pulsar.Clusters[name].AuthToken; secrets.IsRef(token) {
+			if _, err := r.Resolve(ctx, token); err != nil {
+				errs = append(errs, fmt.Errorf("pulsar.clusters.%s.authToken: %w", name, err))
+			}
+		}
+	}
+	for i := range c.APIKeys {
+		resolve(fmt.Sprintf("apiKeys[%d].key", i), &c.APIKeys[i].Key)
+	}
+	secretsCopy := make(map[string]string, len(c.Signature.Secrets))
+	for k, v := range c.Signature.Secrets {
+		resolve("signature.secrets."+k, &v)
+		secretsCopy[k] = v
+	}
+	c.Signature.Secrets = secretsCopy
+	resolve("sentry.dsn", &c.Sentry.DSN)
+	resolve("redactionSecret", &c.RedactSecret)
+	resolve("idempotency.redis.password", &c.Idempotency.Redis.Password)
+	resolve("registry.password", &c.Registry.Password)
+	resolve("registry.token", &c.Registry.Token)
+	for _, et := range sortedKeys(c.Enrichment) {
+		for i := range c.Enrichment[et] {
+			resolve(fmt.Sprintf("enrichment.%s[%d].token", et, i), &c.Enrichment[et][i].Token)
+		}
+	}
+	return errors.Join(errs...)
+}
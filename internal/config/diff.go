@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// keys met een van deze delen worden nooit in klare tekst gelogd
+var secretKeyParts = []string{"secret", "password", "token", "apikeys", "dsn"}
+
+func isSecretKey(key string) bool {
+	k := strings.ToLower(key)
+	for _, p := range secretKeyParts {
+		if strings.Contains(k, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func flatten(prefix string, in map[string]interface{}) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range in {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			for fk, fv := range flatten(key, m) {
+				out[fk] = fv
+			}
+			continue
+		}
+		out[key] = v
+	}
+	return out
+}
+
+// Diff beschrijft per gewijzigde key de oude en nieuwe waarde (secrets
+// gemaskeerd, ook in lijsten, zie maskValue).
+func Diff(old, new *Config) []string {
+	keys := map[string]bool{}
+	for k := range old.settings {
+		keys[k] = true
+	}
+	for k := range new.settings {
+		keys[k] = true
+	}
+
+	var changes []string
+	for k := range keys {
+		ov, ook := old.settings[k]
+		nv, nok := new.settings[k]
+		if ook && nok && reflect.DeepEqual(ov, nv) {
+			continue
+		}
+		ov, nv = maskValue(k, ov), maskValue(k, nv)
+		switch {
+		case isSecretKey(k):
+			changes = append(changes, k+": <changed>")
+		case !ook:
+			changes = append(changes, fmt.Sprintf("%s: added %v", k, nv))
+		case !nok:
+			changes = append(changes, fmt.Sprintf("%s: removed (was %v)", k, ov))
+		default:
+			changes = append(changes, fmt.Sprintf("%s: %v → %v", k, ov, nv))
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// herkomst van een effectieve config waarde
+const (
+	OriginDefault = "default"
+	OriginFile    = "file" // config.yml, profiel of remote document
+	OriginEnv     = "env"
+	OriginFlag    = "flag"
+)
+
+const masked = "***"
+
+// Setting is één effectieve config waarde met zijn herkomst.
+type Setting struct {
+	Value  interface{} `json:"value"`
+	Origin string      `json:"origin"`
+}
+
+func origins(v *viper.Viper, settings map[string]interface{}) map[string]string {
+	out := make(map[string]string, len(settings))
+	for key := range settings {
+		env := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		switch _, isEnv := os.LookupEnv(env); {
+		case isEnv:
+			out[key] = OriginEnv
+		case v.InConfig(key):
+			out[key] = OriginFile
+		default:
+			out[key] = OriginDefault
+		}
+	}
+	return out
+}
+
+// MarkFlag noteert dat key via een CLI flag gezet werd. Bij een reload neemt
+// de Bus dat over, flags wijzigen niet at runtime.
+func (c *Config) MarkFlag(key string) {
+	if c.origins == nil {
+		c.origins = map[string]string{}
+	}
+	c.origins[strings.ToLower(key)] = OriginFlag
+}
+
+// SetSources noteert waaruit de config gelezen werd (de uitkomst van Read).
+func (c *Config) SetSources(sources []string) {
+	c.sources = sources
+}
+
+// Sources: de config bestanden en de remote store, leeg met enkel env vars
+// en defaults.
+func (c *Config) Sources() []string {
+	return c.sources
+}
+
+func (c *Config) inheritFlags(prev *Config) {
+	for k, o := range prev.origins {
+		if o == OriginFlag {
+			c.MarkFlag(k)
+		}
+	}
+}
+
+// Effective geeft elke key met zijn waarde en herkomst. Secrets worden
+// gemaskeerd, net als wachtwoorden in URL's (als "xxxxx").
+func (c *Config) Effective() map[string]Setting {
+	out := make(map[string]Setting, len(c.settings))
+	for k, val := range c.settings {
+		out[k] = Setting{Value: maskValue(k, val), Origin: c.origins[k]}
+	}
+	return out
+}
+
+// maskValue maskeert de waarde van key. Een lijst of map (bv. de lookups
+// van enrichment.<eventType>) blijft staan, met de secrets erin gemaskeerd op
+// hun veldnaam.
+func maskValue(key string, val interface{}) interface{} {
+	if isSecretKey(key) {
+		return masked
+	}
+	switch v := val.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, el := range v {
+			out[k] = maskValue(k, el)
+		}
+		return out
+	case []map[string]interface{}:
+		out := make([]interface{}, len(v))
+		for i, el := range v {
+			out[i] = maskValue("", el)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, el := range v {
+			out[i] = maskValue("", el)
+		}
+		return out
+	case string:
+		if !strings.Contains(v, "@") {
+			return v
+		}
+		if u, err := url.Parse(v); err == nil {
+			return u.Redacted()
+		}
+	}
+	return val
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvVar kiest het profiel: config.<APP_ENV>.yml wordt over config.yml gelegd.
+const EnvVar = "APP_ENV"
+
+// Read leest het basis config bestand, legt daar het profiel van env over
+// (config.yml + config.prod.yml, in dezelfde map) en daarna het document uit
+// de remote store (remote.*) als die ingesteld is. Maps worden per key
+// samengevoegd, andere waarden uit de latere laag winnen. Geeft de gelezen
+// bronnen terug.
+//
+// Zonder config bestand (en zonder --config) draait de service op defaults en
+// PULSAR_API_* env vars; een profiel vraagt wel een basis bestand.
+func Read(v *viper.Viper, env string) ([]string, error) {
+	var sources []string
+	if err := v.ReadInConfig(); err != nil {
+		if !errors.As(err, new(viper.ConfigFileNotFoundError)) {
+			return nil, err
+		}
+		if env != "" {
+			return nil, fmt.Errorf("profile %q: no config.yml found to apply it to", env)
+		}
+	} else {
+		sources = append(sources, v.ConfigFileUsed())
+	}
+	base := v.ConfigFileUsed()
+
+	if env != "" {
+		overlay := ProfileFile(base, env)
+		f, err := os.Open(overlay)
+		if err != nil {
+			return nil, fmt.Errorf("profile %q: %w", env, err)
+		}
+		defer f.Close()
+		if err := v.MergeConfig(f); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", env, err)
+		}
+		sources = append(sources, overlay)
+	}
+
+	remote, err := newRemote(v)
+	if err != nil || remote == nil {
+		return sources, err
+	}
+	doc, err := remote.Get(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("remote config (%s): %w", remote, err)
+	}
+	if err := v.MergeConfig(bytes.NewReader(doc)); err != nil {
+		return nil, fmt.Errorf("remote config (%s): %w", remote, err)
+	}
+	return append(sources, remote.String()), nil
+}
+
+// ProfileFile geeft het profiel bestand naast base, bv. config.yml → config.prod.yml.
+func ProfileFile(base, env string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + env + ext
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	remoteTimeout     = 10 * time.Second
+	remoteRetry       = 5 * time.Second
+	consulBlockingFor = "5m"
+)
+
+// remoteSource is een centrale key/value store met één YAML (of JSON)
+// document dat over de lokale config gelegd wordt.
+type remoteSource interface {
+	Get(ctx context.Context) ([]byte, error)
+	// Watch roept changed aan bij elke wijziging van de key, tot ctx afloopt.
+	Watch(ctx context.Context, changed func(), log *zap.Logger)
+	String() string
+}
+
+// newRemote leest de remote.* keys uit de lokale config; nil als er geen
+// remote provider ingesteld is.
+func newRemote(v *viper.Viper) (remoteSource, error) {
+	provider := v.GetString("remote.provider")
+	endpoint := strings.TrimSuffix(v.GetString("remote.endpoint"), "/")
+	key := strings.TrimPrefix(v.GetString("remote.key"), "/")
+	token := v.GetString("remote.token")
+
+	switch provider {
+	case "", "none":
+		return nil, nil
+	case "consul", "etcd":
+		if endpoint == "" || key == "" {
+			return nil, errors.New("remote.endpoint and remote.key are required")
+		}
+	default:
+		return nil, fmt.Errorf("unknown remote.provider %q (consul or etcd)", provider)
+	}
+
+	kv := kvStore{endpoint: endpoint, key: key, token: token, client: &http.Client{}}
+	if provider == "consul" {
+		return &consulKV{kv}, nil
+	}
+	return &etcdKV{kv}, nil
+}
+
+type kvStore struct {
+	endpoint string
+	key      string
+	token    string
+	client   *http.Client // zonder timeout: watches blijven lang open
+}
+
+func (s kvStore) do(ctx context.Context, method, url string, body interface{}, header string) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
+	if err != nil {
+		return nil, err
+	}
+	if s.token != "" {
+		req.Header.Set(header, s.token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// consulKV gebruikt de Consul KV HTTP API met blocking queries.
+type consulKV struct{ kvStore }
+
+func (c *consulKV) String() string { return "consul " + c.endpoint + "/" + c.key }
+
+func (c *consulKV) Get(ctx context.Context) ([]byte, error) {
+	doc, _, err := c.get(ctx, "")
+	return doc, err
+}
+
+func (c *consulKV) get(ctx context.Context, index string) ([]byte, string, error) {
+	url := c.endpoint + "/v1/kv/" + c.key + "?raw"
+	if index != "" {
+		url += "&index=" + index + "&wait=" + consulBlockingFor
+	} else {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, remoteTimeout)
+		defer cancel()
+	}
+	resp, err := c.do(ctx, http.MethodGet, url, nil, "X-Consul-Token")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	doc, err := io.ReadAll(resp.Body)
+	return doc, resp.Header.Get("X-Consul-Index"), err
+}
+
+func (c *consulKV) Watch(ctx context.Context, changed func(), log *zap.Logger) {
+	index := ""
+	for ctx.Err() == nil {
+		_, next, err := c.get(ctx, index)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Warn("Remote config watch failed, retrying", zap.Stringer("remote", c), zap.Error(err))
+				sleep(ctx, remoteRetry)
+			}
+			continue
+		}
+		if index != "" && next != index {
+			changed()
+		}
+		index = next
+	}
+}
+
+// etcdKV gebruikt de etcd v3 JSON gateway (/v3/kv/range en /v3/watch).
+type etcdKV struct{ kvStore }
+
+func (e *etcdKV) String() string { return "etcd " + e.endpoint + "/" + e.key }
+
+func (e *etcdKV) encodedKey() string {
+	return base64.StdEncoding.EncodeToString([]byte(e.key))
+}
+
+func (e *etcdKV) Get(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, remoteTimeout)
+	defer cancel()
+
+	resp, err := e.do(ctx, http.MethodPost, e.endpoint+"/v3/kv/range", map[string]string{"key": e.encodedKey()}, "Authorization")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		KVs []struct {
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.KVs) == 0 {
+		return nil, fmt.Errorf("key %s not found", e.key)
+	}
+	return out.KVs[0].Value, nil
+}
+
+func (e *etcdKV) Watch(ctx context.Context, changed func(), log *zap.Logger) {
+	body := map[string]interface{}{"create_request": map[string]string{"key": e.encodedKey()}}
+	for ctx.Err() == nil {
+		err := func() error {
+			resp, err := e.do(ctx, http.MethodPost, e.endpoint+"/v3/watch", body, "Authorization")
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+
+			// de gateway streamt één JSON object per watch response
+			dec := json.NewDecoder(resp.Body)
+			for {
+				var msg struct {
+					Result struct {
+						Events []json.RawMessage `json:"events"`
+					} `json:"result"`
+				}
+				if err := dec.Decode(&msg); err != nil {
+					return err
+				}
+				if len(msg.Result.Events) > 0 {
+					changed()
+				}
+			}
+		}()
+		if ctx.Err() == nil {
+			log.Warn("Remote config watch stopped, retrying", zap.Stringer("remote", e), zap.Error(err))
+			sleep(ctx, remoteRetry)
+		}
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,805 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rubenclaes/pulsar-api/internal/logging"
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+	"github.com/rubenclaes/pulsar-api/internal/secrets"
+)
+
+// volledige topic naam (persistent://tenant/ns/topic) of korte naam (topic)
+var (
+	fullTopicRe  = regexp.MustCompile(`^(persistent|non-persistent)://[\w.:=-]+/[\w.:=-]+/[\w.:=-]+$`)
+	shortTopicRe = regexp.MustCompile(`^[\w.:=-]+$`)
+)
+
+// validTopic: {tenant} staat voor de tenant van de request, zie tenants.
+func validTopic(topic string) bool {
+	topic = strings.ReplaceAll(topic, "{tenant}", "tenant")
+	return fullTopicRe.MatchString(topic) || shortTopicRe.MatchString(topic)
+}
+
+var (
+	tenantRe     = regexp.MustCompile(`^[A-Za-z0-9_.=-]+$`)
+	headerNameRe = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+var versionRe = regexp.MustCompile(`^v[1-9][0-9]*$`)
+
+// naam van een CloudEvents attribuut, en de attributen die geen extension zijn
+var (
+	cloudEventsAttribute = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+	cloudEventsCore      = []string{"id", "source", "specversion", "type", "datacontenttype", "dataschema", "subject", "time", "data", "data_base64"}
+)
+
+// vendor in application/vnd.<vendor>.<eventType>+json, bv. acerta of acerta.hr
+var mediaTypeVendor = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*(\.[a-z0-9]+(-[a-z0-9]+)*)*$`)
+
+// versionSuffix controleert de versie in een key "<eventType>@v<n>".
+func versionSuffix(key string) error {
+	et, version, ok := strings.Cut(key, "@")
+	if !ok {
+		return nil
+	}
+	if et == "" || !versionRe.MatchString(version) {
+		return fmt.Errorf("version %q must be v<n>, e.g. %s@v2", version, et)
+	}
+	return nil
+}
+
+// Validate controleert de volledige config en geeft alle problemen in één
+// keer terug (errors.Join), zodat een deploy niet per fout opnieuw moet.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(key, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	validEndpoint := func(key, endpoint string) {
+		if _, port, err := net.SplitHostPort(endpoint); err != nil {
+			add(key, "%q must be host:port (without scheme)", endpoint)
+		} else if n, err := strconv.Atoi(port); err != nil || !validPort(n) {
+			add(key, "%q has an invalid port", endpoint)
+		}
+	}
+
+	// pulsar: in dry-run wordt Pulsar niet gecontacteerd (behalve een audit topic)
+	needsPulsar := !c.API.DryRun || c.Audit.Sink == "topic"
+	if c.Pulsar.URL == "" {
+		if needsPulsar {
+			add("pulsar.url", "is required unless api.dryRun is true (or PULSAR_API_PULSAR_URL)")
+		}
+	} else if u, err := url.Parse(c.Pulsar.URL); err != nil || (u.Scheme != "pulsar" && u.Scheme != "pulsar+ssl") || u.Host == "" {
+		add("pulsar.url", "%q must look like pulsar://host:6650 or pulsar+ssl://host:6651", c.Pulsar.URL)
+	}
+	if c.Pulsar.DefaultTopic == "" {
+		if !c.API.DryRun {
+			add("pulsar.defaultTopic", "is required unless api.dryRun is true (or PULSAR_API_PULSAR_DEFAULTTOPIC)")
+		}
+	} else if !validTopic(c.Pulsar.DefaultTopic) {
+		add("pulsar.defaultTopic", "%q is not a valid topic, expected persistent://tenant/namespace/topic", c.Pulsar.DefaultTopic)
+	}
+	if err := c.Pulsar.Retry.Validate(); err != nil {
+		add("pulsar.retry", "%v", err)
+	}
+	if err := c.Pulsar.CircuitBreaker.Validate(); err != nil {
+		add("pulsar.circuitBreaker", "%v", err)
+	}
+	if err := c.Pulsar.InFlight.Validate(); err != nil {
+		add("pulsar.inFlight", "%v", err)
+	}
+	if sh := c.ShadowOptions(); sh.Enabled {
+		if _, ok := c.Pulsar.Clusters[sh.Cluster]; !ok && sh.Cluster != pulsar.DefaultCluster {
+			add("pulsar.shadow.cluster", "unknown cluster %q, not in pulsar.clusters", sh.Cluster)
+		}
+		if sh.QueueSize <= 0 {
+			add("pulsar.shadow.queueSize", "must be positive")
+		}
+		if sh.Timeout <= 0 {
+			add("pulsar.shadow.timeout", "must be positive")
+		}
+	}
+	for _, name := range sortedKeys(c.Pulsar.Clusters) {
+		key := "pulsar.clusters." + name
+		if name == pulsar.DefaultCluster {
+			add(key, "%q is reserved for pulsar.url", name)
+		}
+		if u, err := url.Parse(c.Pulsar.Clusters[name].URL); err != nil || (u.Scheme != "pulsar" && u.Scheme != "pulsar+ssl") || u.Host == "" {
+			add(key+".url", "%q must look like pulsar://host:6650 or pulsar+ssl://host:6651", c.Pulsar.Clusters[name].URL)
+		}
+	}
+	for _, name := range c.Clusters() {
+		key := "pulsar.clusters." + name + ".adminURL"
+		if name == pulsar.DefaultCluster {
+			key = "pulsar.adminURL"
+		}
+		if e := c.ClusterAdminURL(name); e == "" {
+			if c.Pulsar.SchemaRegistry.Enabled {
+				add(key, "is required when pulsar.schemaRegistry.enabled is true")
+			}
+			if c.Pulsar.Provision.Enabled {
+				add(key, "is required when pulsar.provision.enabled is true")
+			}
+		} else if u, err := url.Parse(e); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add(key, "%q must be an http(s) URL", e)
+		}
+	}
+	if vt := c.Pulsar.VerifyTopics; vt.Enabled && vt.Timeout <= 0 {
+		add("pulsar.verifyTopics.timeout", "must be positive")
+	}
+	if c.Pulsar.VerifyTopics.Interval < 0 {
+		add("pulsar.verifyTopics.interval", "must not be negative (0 = only at startup and reload)")
+	}
+	if err := c.Pulsar.Provision.Validate(); err != nil {
+		add("pulsar.provision", "%v", err)
+	}
+	if sr := c.Pulsar.SchemaRegistry; sr.Enabled {
+		if sr.CacheTTL <= 0 {
+			add("pulsar.schemaRegistry.cacheTTL", "must be positive")
+		}
+		if sr.Timeout <= 0 {
+			add("pulsar.schemaRegistry.timeout", "must be positive")
+		}
+	}
+
+	// api
+	if !validPort(c.API.Port) {
+		add("api.port", "%d is out of range 1-65535", c.API.Port)
+	}
+	if p := c.API.TLS.RedirectPort; p != 0 && (!validPort(p) || p == c.API.Port) {
+		add("api.tls.redirectPort", "%d must be in range 1-65535 and differ from api.port", p)
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"api.readTimeout", c.API.ReadTimeout},
+		{"api.readHeaderTimeout", c.API.ReadHeaderTimeout},
+		{"api.writeTimeout", c.API.WriteTimeout},
+		{"api.idleTimeout", c.API.IdleTimeout},
+		{"api.requestTimeout", c.API.RequestTimeout},
+		{"api.shutdownTimeout", c.API.ShutdownTimeout},
+		{"api.concurrency.queueWait", c.API.Concurrency.QueueWait},
+		{"api.concurrency.retryAfter", c.API.Concurrency.RetryAfter},
+	} {
+		if d.value < 0 {
+			add(d.key, "must not be negative")
+		}
+	}
+	if c.API.Batch.Parallelism < 1 {
+		add("api.batch.parallelism", "must be at least 1")
+	}
+	if c.API.Batch.StreamThreshold < 0 {
+		add("api.batch.streamThreshold", "must not be negative (0 = never stream)")
+	}
+	if v := c.API.MediaTypeVendor; v != "" && !mediaTypeVendor.MatchString(v) {
+		add("api.mediaTypeVendor", "%q must be lowercase letters, digits, '-' or '.', e.g. acerta", v)
+	}
+	if c.API.WriteTimeout > 0 && c.API.RequestTimeout >= c.API.WriteTimeout {
+		add("api.writeTimeout", "%s must be larger than api.requestTimeout (%s)", c.API.WriteTimeout, c.API.RequestTimeout)
+	}
+	for i, p := range c.API.TrustedProxies {
+		if _, err := netip.ParsePrefix(p); err != nil {
+			if _, err := netip.ParseAddr(p); err != nil {
+				add(fmt.Sprintf("api.trustedProxies[%d]", i), "%q is not an IP address or CIDR", p)
+			}
+		}
+	}
+	switch c.API.Gin.Mode {
+	case "", "debug", "release", "test":
+	default:
+		add("api.gin.mode", "unknown mode %q (debug, release or test)", c.API.Gin.Mode)
+	}
+	if c.API.Gin.MaxMultipartMemory < 0 {
+		add("api.gin.maxMultipartMemory", "must not be negative")
+	}
+	if c.API.Concurrency.MaxInFlight < 0 {
+		add("api.concurrency.maxInFlight", "must not be negative (0 = unlimited)")
+	}
+	if r := c.API.Concurrency.Reserved; r < 0 || (r > 0 && r >= c.API.Concurrency.MaxInFlight) {
+		add("api.concurrency.reserved", "must be at least 0 and less than maxInFlight")
+	}
+
+	// priority lane
+	if p := c.API.Priority; p.Enabled {
+		if !headerNameRe.MatchString(p.Header) {
+			add("api.priority.header", "%q is not a valid header name", p.Header)
+		}
+		if len(p.Values) == 0 {
+			add("api.priority.values", "at least one value is required")
+		}
+		if p.TopicSuffix != "" && !tenantRe.MatchString(p.TopicSuffix) {
+			add("api.priority.topicSuffix", "%q must be letters, digits, '_', '.', '=' or '-'", p.TopicSuffix)
+		}
+	}
+
+	// tls
+	tls := c.API.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		add("api.tls", "certFile and keyFile must be set together")
+	}
+	if tls.ACME.Enabled {
+		if len(tls.ACME.Hosts) == 0 {
+			add("api.tls.acme.hosts", "at least one host is required when acme is enabled")
+		}
+		if tls.CertFile != "" {
+			add("api.tls.acme.enabled", "cannot be combined with api.tls.certFile/keyFile")
+		}
+	} else {
+		errs = append(errs, fileExists("api.tls.certFile", tls.CertFile), fileExists("api.tls.keyFile", tls.KeyFile))
+	}
+	errs = append(errs, fileExists("api.tls.clientCAFile", tls.ClientCAFile))
+
+	// ip filters en api keys
+	groups := make([]string, 0, len(c.IPFilter))
+	for g := range c.IPFilter {
+		groups = append(groups, g)
+	}
+	sort.Strings(groups)
+	for _, g := range groups {
+		if _, err := middleware.IPFilter(c.IPFilter[g].Allow, c.IPFilter[g].Deny); err != nil {
+			add("ipFilter."+g, "%v", err)
+		}
+	}
+	for i, k := range c.APIKeys {
+		if k.Key == "" || k.Client == "" {
+			add(fmt.Sprintf("apiKeys[%d]", i), "key and client are required")
+		}
+	}
+
+	if c.UI.Login {
+		if len(c.APIKeys) == 0 {
+			add("ui.login", "requires at least one apiKeys entry")
+		}
+		if c.UI.SessionTTL <= 0 {
+			add("ui.sessionTTL", "must be positive")
+		}
+	}
+
+	// signature
+	if c.Signature.Required && len(c.Signature.Secrets) == 0 {
+		add("signature.secrets", "at least one secret is required when signature.required is true")
+	}
+
+	// secrets
+	switch c.Secrets.Provider {
+	case "", "none":
+		if tls.Secret != "" {
+			add("api.tls.secret", "requires a secrets.provider")
+		}
+	case "vault":
+		if c.Secrets.Vault.Address == "" && os.Getenv("VAULT_ADDR") == "" {
+			add("secrets.vault.address", "is required (or VAULT_ADDR)")
+		}
+		switch c.Secrets.Vault.Auth {
+		case "", "token":
+		case "kubernetes":
+			if c.Secrets.Vault.Role == "" {
+				add("secrets.vault.role", "is required for kubernetes auth")
+			}
+		default:
+			add("secrets.vault.auth", "unknown auth method %q (token or kubernetes)", c.Secrets.Vault.Auth)
+		}
+		errs = append(errs, fileExists("secrets.vault.caFile", c.Secrets.Vault.CAFile))
+	case "aws":
+		if c.Secrets.AWS.Region == "" && os.Getenv("AWS_REGION") == "" && os.Getenv("AWS_DEFAULT_REGION") == "" {
+			add("secrets.aws.region", "is required (or AWS_REGION)")
+		}
+		if e := c.Secrets.AWS.Endpoint; e != "" {
+			if u, err := url.Parse(e); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				add("secrets.aws.endpoint", "%q must be an http(s) URL", e)
+			}
+		}
+	default:
+		add("secrets.provider", "unknown provider %q (none, vault or aws)", c.Secrets.Provider)
+	}
+	if tls.Secret != "" && (tls.CertFile != "" || tls.ACME.Enabled) {
+		add("api.tls.secret", "cannot be combined with api.tls.certFile/keyFile or acme")
+	}
+	if c.Secrets.RefreshInterval < 0 {
+		add("secrets.refreshInterval", "must not be negative")
+	}
+
+	// remote (fouten in provider/endpoint/key komen al bij het lezen naar boven)
+	if e := c.Remote.Endpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add("remote.endpoint", "%q must be an http(s) URL", e)
+		}
+	}
+
+	// audit
+	switch c.Audit.Sink {
+	case "", "none":
+	case "file":
+		if c.Audit.File == "" {
+			add("audit.file", "is required when audit.sink is file")
+		}
+	case "topic":
+		if !validTopic(c.Audit.Topic) || strings.Contains(c.Audit.Topic, "{tenant}") {
+			add("audit.topic", "%q is not a valid topic", c.Audit.Topic)
+		}
+	default:
+		add("audit.sink", "unknown sink %q (none, file or topic)", c.Audit.Sink)
+	}
+
+	// tracing
+	if c.Tracing.Enabled {
+		validEndpoint("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	if c.Logging.OTLP.Enabled {
+		if c.Logging.OTLP.Endpoint != "" {
+			validEndpoint("logging.otlp.endpoint", c.Logging.OTLP.Endpoint)
+		} else {
+			validEndpoint("tracing.endpoint", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sampleRatio", "%v must be between 0 and 1", c.Tracing.SampleRatio)
+	}
+
+	// metrics
+	if c.Metrics.ClientLabel && c.Metrics.MaxClients < 1 {
+		add("metrics.maxClients", "must be at least 1 when metrics.clientLabel is true")
+	}
+	if c.Metrics.Enabled && c.Metrics.OTLP.Enabled {
+		if c.Metrics.OTLP.Endpoint != "" {
+			validEndpoint("metrics.otlp.endpoint", c.Metrics.OTLP.Endpoint)
+		} else {
+			validEndpoint("tracing.endpoint", c.Tracing.Endpoint)
+		}
+		if c.Metrics.OTLP.Interval <= 0 {
+			add("metrics.otlp.interval", "must be positive")
+		}
+	}
+
+	// spool
+	if c.Spool.Enabled {
+		if c.Spool.Dir == "" {
+			add("spool.dir", "is required when spool.enabled is true")
+		}
+		if c.Spool.MaxBytes < 0 {
+			add("spool.maxBytes", "must not be negative")
+		}
+		if c.Spool.RetryInterval <= 0 {
+			add("spool.retryInterval", "must be positive")
+		}
+	}
+
+	// dead letters
+	if c.DeadLetter.Enabled {
+		if c.DeadLetter.Dir == "" {
+			add("deadLetter.dir", "is required when deadLetter.enabled is true")
+		}
+		if c.DeadLetter.MaxBytes < 0 {
+			add("deadLetter.maxBytes", "must not be negative")
+		}
+		if c.Spool.Enabled && filepath.Clean(c.DeadLetter.Dir) == filepath.Clean(c.Spool.Dir) {
+			add("deadLetter.dir", "must differ from spool.dir")
+		}
+	}
+
+	// schema registry
+	switch c.Registry.Provider {
+	case "none":
+	case "confluent", "apicurio":
+		if u, err := url.Parse(c.Registry.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add("registry.url", "%q must be an http(s) URL", c.Registry.URL)
+		}
+		if len(c.Registry.Subjects) == 0 {
+			add("registry.subjects", "is required when registry.provider is set")
+		}
+		for _, et := range sortedKeys(c.Registry.Subjects) {
+			if c.Registry.Subjects[et] == "" {
+				add("registry.subjects."+et, "must not be empty")
+			}
+		}
+		if c.Registry.Token != "" && c.Registry.Username != "" {
+			add("registry.token", "cannot be combined with registry.username")
+		}
+		if c.Registry.CacheTTL <= 0 {
+			add("registry.cacheTTL", "must be positive")
+		}
+		if c.Registry.Timeout <= 0 {
+			add("registry.timeout", "must be positive")
+		}
+	default:
+		add("registry.provider", "unknown provider %q (none, confluent or apicurio)", c.Registry.Provider)
+	}
+
+	// recente events
+	if c.Recent.Size < 0 {
+		add("recent.size", "must not be negative")
+	}
+
+	// idempotency
+	if c.Idempotency.Enabled && c.Idempotency.TTL <= 0 {
+		add("idempotency.ttl", "must be positive")
+	}
+	if c.Idempotency.DedupWindow < 0 {
+		add("idempotency.dedupWindow", "must not be negative")
+	}
+	switch c.Idempotency.Store {
+	case "memory":
+	case "redis":
+		if _, _, err := net.SplitHostPort(c.Idempotency.Redis.Addr); err != nil {
+			add("idempotency.redis.addr", "%q must be host:port", c.Idempotency.Redis.Addr)
+		}
+		if c.Idempotency.Redis.DB < 0 {
+			add("idempotency.redis.db", "must not be negative")
+		}
+	default:
+		add("idempotency.store", "unknown store %q (memory or redis)", c.Idempotency.Store)
+	}
+
+	// sentry, een secret referentie wordt pas na ResolveSecrets gekend
+	if dsn := c.Sentry.DSN; dsn != "" && !secrets.IsRef(dsn) {
+		if u, err := url.Parse(dsn); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.User == nil || u.Host == "" {
+			add("sentry.dsn", "is not a valid DSN (https://<key>@<host>/<project>)")
+		}
+	}
+	if c.Sentry.SampleRate < 0 || c.Sentry.SampleRate > 1 {
+		add("sentry.sampleRate", "%v must be between 0 and 1", c.Sentry.SampleRate)
+	}
+
+	// logging
+	switch c.Logging.Mode {
+	case "", "development", "production":
+	default:
+		add("logging.mode", "unknown mode %q (development or production)", c.Logging.Mode)
+	}
+	if c.Logging.Level != "" {
+		if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+			add("logging.level", "%v", err)
+		}
+	}
+	if sm := c.Logging.Sampling; sm.Enabled && (sm.Initial < 1 || sm.Thereafter < 1 || sm.Tick <= 0) {
+		add("logging.sampling", "initial and thereafter must be at least 1 and tick positive when sampling is enabled")
+	}
+	if f := c.Logging.File; f.Path != "" {
+		if f.MaxSizeMB < 1 {
+			add("logging.file.maxSizeMB", "must be at least 1")
+		}
+		if f.MaxAgeDays < 0 || f.MaxBackups < 0 {
+			add("logging.file", "maxAgeDays and maxBackups must not be negative (0 = keep all)")
+		}
+		if fi, err := os.Stat(filepath.Dir(f.Path)); err != nil || !fi.IsDir() {
+			add("logging.file.path", "directory of %s does not exist", f.Path)
+		}
+	}
+	if c.Logging.Bodies.Enabled && c.Logging.Bodies.MaxBytes < 1 {
+		add("logging.bodies.maxBytes", "must be at least 1 when body logging is enabled")
+	}
+	switch c.Logging.Encoding {
+	case "", "console", "json":
+	default:
+		add("logging.encoding", "unknown encoding %q (console or json)", c.Logging.Encoding)
+	}
+
+	// routes en schemas, gesorteerd zodat de output stabiel is
+	byTopic := map[string]string{} // cluster + topic → eerste eventType
+	for _, et := range sortedKeys(c.Routes) {
+		r := c.Routes[et]
+		if err := versionSuffix(et); err != nil {
+			add("routes."+et, "%v", err)
+		}
+		if !validTopic(r.Topic) {
+			add("routes."+et, "%q is not a valid topic", r.Topic)
+			continue
+		}
+		if _, ok := c.Pulsar.Clusters[r.cluster()]; !ok && r.cluster() != pulsar.DefaultCluster {
+			add("routes."+et+".cluster", "unknown cluster %q, not in pulsar.clusters", r.Cluster)
+		}
+		if err := r.ProducerOptions.Validate(); err != nil {
+			add("routes."+et, "%v", err)
+		}
+		if r.FallbackTopic != "" && !validTopic(r.FallbackTopic) {
+			add("routes."+et+".fallbackTopic", "%q is not a valid topic", r.FallbackTopic)
+		} else if r.FallbackTopic == r.Topic && r.FallbackTopic != "" {
+			add("routes."+et+".fallbackTopic", "must differ from the route topic")
+		}
+		if strings.Contains(r.FallbackTopic, "{tenant}") && !strings.Contains(r.Topic, "{tenant}") {
+			add("routes."+et+".fallbackTopic", "may only use {tenant} if the route topic does too")
+		}
+		if strings.Contains(r.ShadowTopic, "{tenant}") {
+			add("routes."+et+".shadowTopic", "{tenant} is not supported in a shadow topic")
+		}
+		if r.ShadowTopic != "" && !validTopic(r.ShadowTopic) {
+			add("routes."+et+".shadowTopic", "%q is not a valid topic", r.ShadowTopic)
+		}
+		if r.MaxPayloadBytes < 0 {
+			add("routes."+et+".maxPayloadBytes", "must not be negative")
+		}
+		if name, _, _ := strings.Cut(et, "@"); r.Pattern != "" && strings.ContainsAny(name, "*?") {
+			add("routes."+et+".pattern", "use either a wildcard in the key or pattern, not both")
+		} else if p := r.eventTypePattern(et); p != "" {
+			if _, err := regexp.Compile(p); err != nil {
+				add("routes."+et+".pattern", "%v", err)
+			}
+		}
+		if first, ok := byTopic[r.cluster()+" "+r.Topic]; !ok {
+			byTopic[r.cluster()+" "+r.Topic] = et
+		} else if c.Routes[first].ProducerOptions != r.ProducerOptions {
+			add("routes."+et, "producer options differ from routes.%s, which uses the same topic", first)
+		} else if c.Routes[first].ShadowTopic != r.ShadowTopic {
+			add("routes."+et+".shadowTopic", "differs from routes.%s, which uses the same topic", first)
+		}
+	}
+	names := map[string]int{}
+	for i, r := range c.Routing {
+		key := fmt.Sprintf("routing[%d]", i)
+		if err := r.Validate(); err != nil {
+			add(key, "%v", err)
+		}
+		if r.Topic != "" && !validTopic(r.Topic) {
+			add(key+".topic", "%q is not a valid topic", r.Topic)
+		}
+		for j, t := range r.FanOut {
+			if t != "" && !validTopic(t) {
+				add(fmt.Sprintf("%s.fanOut[%d]", key, j), "%q is not a valid topic", t)
+			}
+		}
+		for j, p := range r.Properties {
+			if p.Name == pulsar.CorrelationIDProperty || p.Name == "traceparent" || p.Name == "tracestate" {
+				add(fmt.Sprintf("%s.properties[%d].name", key, j), "%q is set by the API", p.Name)
+			}
+		}
+		if first, ok := names[r.Name]; ok && r.Name != "" {
+			add(key+".name", "%q is already used by routing[%d]", r.Name, first)
+		} else {
+			names[r.Name] = i
+		}
+	}
+	for _, et := range sortedKeys(c.Redaction) {
+		for i, r := range c.Redaction[et] {
+			if err := r.Validate(); err != nil {
+				add(fmt.Sprintf("redaction.%s[%d]", et, i), "%v", err)
+			}
+			if r.Action == redact.ActionHash && c.RedactSecret == "" {
+				add(fmt.Sprintf("redaction.%s[%d]", et, i), "action hash needs redactionSecret")
+			}
+		}
+	}
+	for _, et := range sortedKeys(c.Rules) {
+		for i, r := range c.Rules[et] {
+			if err := r.Validate(); err != nil {
+				add(fmt.Sprintf("rules.%s[%d]", et, i), "%v", err)
+			}
+		}
+	}
+	for _, et := range sortedKeys(c.Defaults) {
+		for i, d := range c.Defaults[et] {
+			key := fmt.Sprintf("defaults.%s[%d]", et, i)
+			if strings.TrimSpace(d.Field) == "" || slices.Contains(strings.Split(strings.TrimSpace(d.Field), "."), "") {
+				add(key+".field", "%q must be a path like channel or address.country", d.Field)
+			}
+			if d.Value == nil {
+				add(key+".value", "is required")
+			} else if _, err := json.Marshal(d.Value); err != nil {
+				add(key+".value", "%v", err)
+			}
+		}
+	}
+	for _, et := range sortedKeys(c.Enrichment) {
+		for i, st := range c.Enrichment[et] {
+			if err := st.Validate(); err != nil {
+				add(fmt.Sprintf("enrichment.%s[%d]", et, i), "%v", err)
+			}
+		}
+	}
+	if fi, err := os.Stat(c.SchemaDir); c.SchemaDir != "" && err == nil && !fi.IsDir() {
+		add("schemaDir", "%s is not a directory", c.SchemaDir)
+	}
+	switch c.SchemaCompat {
+	case schema.CompatNone, schema.CompatBackward, schema.CompatForward, schema.CompatFull:
+	default:
+		add("schemaCompatibility", "%q must be none, backward, forward or full", c.SchemaCompat)
+	}
+	for _, et := range sortedKeys(c.Schemas) {
+		if err := versionSuffix(et); err != nil {
+			add("schemas."+et, "%v", err)
+		}
+		if c.Schemas[et] == "" {
+			add("schemas."+et, "schema path is empty")
+			continue
+		}
+		if !schema.IsURL(c.Schemas[et]) {
+			errs = append(errs, fileExists("schemas."+et, c.Schemas[et]))
+		}
+	}
+	for i, et := range c.Strict.EventTypes {
+		if et == "" {
+			add(fmt.Sprintf("strict.eventTypes[%d]", i), "must not be empty")
+		}
+	}
+
+	// tenants
+	if h := c.Tenants.Header; h != "" && !headerNameRe.MatchString(h) {
+		add("tenants.header", "%q is not a valid header name", h)
+	}
+	checkTenant := func(key, tenant string) {
+		switch {
+		case !tenantRe.MatchString(tenant):
+			add(key, "%q must be letters, digits, '_', '.', '=' or '-'", tenant)
+		case len(c.Tenants.Known) > 0 && !slices.Contains(c.Tenants.Known, tenant):
+			add(key, "%q is not in tenants.known", tenant)
+		}
+	}
+	for i, t := range c.Tenants.Known {
+		if !tenantRe.MatchString(t) {
+			add(fmt.Sprintf("tenants.known[%d]", i), "%q must be letters, digits, '_', '.', '=' or '-'", t)
+		}
+	}
+	if c.Tenants.Default != "" {
+		checkTenant("tenants.default", c.Tenants.Default)
+	}
+	for _, client := range sortedKeys(c.Tenants.Clients) {
+		key := "tenants.clients." + client
+		tenant, namespace, ok := strings.Cut(c.Tenants.Clients[client], "/")
+		checkTenant(key, tenant)
+		if ok && !tenantRe.MatchString(namespace) {
+			add(key, "namespace %q must be letters, digits, '_', '.', '=' or '-'", namespace)
+		}
+	}
+	if c.Tenants.Isolate && len(c.Tenants.Clients) == 0 {
+		add("tenants.isolate", "needs tenants.clients")
+	}
+	// de tenant komt dan van de request: zonder tenants.known krijgt elke
+	// X-Tenant een eigen producer (en metrics series), en met provisioning
+	// kan een caller de gateway eender welke tenant laten aanmaken
+	if c.usesTenant() && len(c.Tenants.Known) == 0 {
+		add("tenants.known", "is required when a topic uses {tenant}")
+	}
+
+	// sourceSystems
+	for i, s := range c.SourceSystems.Known {
+		if s == "" {
+			add(fmt.Sprintf("sourceSystems.known[%d]", i), "must not be empty")
+		}
+	}
+	for _, client := range sortedKeys(c.SourceSystems.Clients) {
+		for i, s := range c.SourceSystems.Clients[client] {
+			switch {
+			case s == "":
+				add(fmt.Sprintf("sourceSystems.clients.%s[%d]", client, i), "must not be empty")
+			case s != "*" && len(c.SourceSystems.Known) > 0 && !slices.Contains(c.SourceSystems.Known, s):
+				add(fmt.Sprintf("sourceSystems.clients.%s[%d]", client, i), "%q is not in sourceSystems.known", s)
+			}
+		}
+	}
+
+	for _, system := range sortedKeys(c.SourceSystems.Routes) {
+		key := "sourceSystems.routes." + system
+		if len(c.SourceSystems.Known) > 0 && !slices.ContainsFunc(c.SourceSystems.Known, func(s string) bool { return strings.EqualFold(s, system) }) {
+			add(key, "%q is not in sourceSystems.known", system)
+		}
+		for _, et := range sortedKeys(c.SourceSystems.Routes[system]) {
+			if topic := c.SourceSystems.Routes[system][et]; !validTopic(topic) {
+				add(key+"."+et, "%q is not a valid topic", topic)
+			}
+			if strings.Contains(et, "@") {
+				add(key+"."+et, "versions are not supported, use routes or routing")
+			}
+		}
+	}
+
+	// cloudEvents
+	for _, name := range sortedKeys(c.CloudEvents.Extensions) {
+		ext := c.CloudEvents.Extensions[name]
+		key := "cloudEvents.extensions." + name
+		if !cloudEventsAttribute.MatchString(name) {
+			add(key, "name must be 1-20 lowercase letters or digits")
+		} else if slices.Contains(cloudEventsCore, name) {
+			add(key, "%q is a core CloudEvents attribute, not an extension", name)
+		}
+		switch ext.Type {
+		case "", "string", "uri", "timestamp":
+			if ext.Pattern != "" {
+				if _, err := regexp.Compile(ext.Pattern); err != nil {
+					add(key+".pattern", "%v", err)
+				}
+			}
+		case "integer", "boolean":
+			if ext.Pattern != "" {
+				add(key+".pattern", "only for string, uri or timestamp extensions")
+			}
+		default:
+			add(key+".type", "unknown type %q (string, integer, boolean, uri or timestamp)", ext.Type)
+		}
+	}
+
+	// versions
+	for _, et := range sortedKeys(c.Versions) {
+		v := c.Versions[et]
+		if strings.Contains(et, "@") {
+			add("versions."+et, "must be an eventType without version")
+		}
+		if v.Default < 0 {
+			add("versions."+et+".default", "must not be negative")
+		} else if v.Default > 0 && len(v.Supported) > 0 && !slices.Contains(v.Supported, v.Default) {
+			add("versions."+et+".default", "v%d is not in supported", v.Default)
+		}
+		for i, n := range v.Supported {
+			if n <= 0 {
+				add(fmt.Sprintf("versions.%s.supported[%d]", et, i), "must be positive")
+			}
+		}
+		for n := range v.Deprecated {
+			if n <= 0 {
+				add(fmt.Sprintf("versions.%s.deprecated.%d", et, n), "version must be positive")
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validPort(p int) bool {
+	return p >= 1 && p <= 65535
+}
+
+// fileExists geeft een fout als path ingevuld is maar niet naar een leesbaar bestand wijst.
+func fileExists(key, path string) error {
+	if path == "" {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%s: %s is a directory", key, path)
+	}
+	return nil
+}
+
+// Problems splitst een fout van Validate op in de afzonderlijke problemen.
+func Problems(err error) []string {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []string{err.Error()}
+	}
+	var out []string
+	for _, e := range joined.Unwrap() {
+		out = append(out, Problems(e)...)
+	}
+	return out
+}
+
+// usesTenant meldt of een topic (default, route, fallback of routing regel)
+// {tenant} bevat.
+func (c *Config) usesTenant() bool {
+	topics := []string{c.Pulsar.DefaultTopic}
+	for _, r := range c.Routes {
+		topics = append(topics, r.Topic, r.FallbackTopic)
+	}
+	for _, r := range c.Routing {
+		topics = append(topics, r.Topic)
+		topics = append(topics, r.FanOut...)
+	}
+	return slices.ContainsFunc(topics, func(t string) bool { return strings.Contains(t, "{tenant}") })
+}
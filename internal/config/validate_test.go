@@ -0,0 +1,27 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTenantTopicNeedsKnownTenants(t *testing.T) {
+	const doc = `
+routes:
+  WAGE_ERROR: "persistent://{tenant}/hr/wage-errors"
+tenants:
+  known: %s
+`
+	for _, tt := range []struct {
+		known   string
+		wantErr bool
+	}{
+		{"[]", true},
+		{"[acerta, partena]", false},
+	} {
+		err := loadYAML(t, strings.Replace(doc, "%s", tt.known, 1)).Validate()
+		if got := err != nil && strings.Contains(err.Error(), "tenants.known"); got != tt.wantErr {
+			t.Errorf("known %s: err = %v, want a tenants.known error: %v", tt.known, err, tt.wantErr)
+		}
+	}
+}
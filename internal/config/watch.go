@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// watchDebounce groepeert de fs events van één save (of ConfigMap update).
+const watchDebounce = 500 * time.Millisecond
+
+// reloadMu zorgt dat een bestand- en een remote wijziging niet tegelijk herladen.
+var reloadMu sync.Mutex
+
+// Watch herlaadt de config (basis + profiel van env + remote) bij elke
+// wijziging in de map(pen) van de config bestanden of in de remote store. Een
+// ongeldige nieuwe config wordt gelogd en genegeerd; de vorige blijft actief.
+func Watch(v *viper.Viper, env string, bus *Bus, log *zap.Logger) error {
+	remote, err := newRemote(v)
+	if err != nil {
+		return err
+	}
+	if remote != nil {
+		go remote.Watch(context.Background(), func() { reload(v, env, bus, log) }, log)
+	}
+
+	base := v.ConfigFileUsed()
+	if base == "" {
+		return nil // geen config bestand, enkel env vars en defaults
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{filepath.Dir(base): true}
+	if env != "" {
+		dirs[filepath.Dir(ProfileFile(base, env))] = true
+	}
+	for d := range dirs {
+		if err := w.Add(d); err != nil {
+			w.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer w.Close()
+		timer := time.NewTimer(watchDebounce)
+		timer.Stop()
+		for {
+			select {
+			case _, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				timer.Reset(watchDebounce)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Warn("Config watcher error", zap.Error(err))
+			case <-timer.C:
+				reload(v, env, bus, log)
+			}
+		}
+	}()
+	return nil
+}
+
+func reload(v *viper.Viper, env string, bus *Bus, log *zap.Logger) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	sources, err := Read(v, env)
+	if err == nil {
+		var cfg *Config
+		if cfg, err = Load(v); err == nil {
+			cfg.SetSources(sources)
+			publish(bus, cfg, sources, log)
+			return
+		}
+	}
+	log.Error("Config reload failed, keeping current config", zap.Error(err))
+}
+
+func publish(bus *Bus, cfg *Config, sources []string, log *zap.Logger) {
+	changes := Diff(bus.Current(), cfg)
+	if len(changes) == 0 {
+		return
+	}
+	if err := bus.Publish(cfg); err != nil {
+		log.Error("Rejected invalid config, keeping current config",
+			zap.Strings("sources", sources),
+			zap.Strings("changes", changes),
+			zap.Strings("problems", Problems(err)),
+		)
+		return
+	}
+	log.Info("Config reloaded", zap.Strings("sources", sources), zap.Strings("changes", changes))
+}
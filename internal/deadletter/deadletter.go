@@ -0,0 +1,206 @@
+// Package deadletter bewaart events die definitief niet naar Pulsar konden,
+// met de reden en de oorspronkelijke request, zodat ze na een 5xx niet enkel
+// in een response staan die de client misschien gemist heeft. Ze zijn op te
+// vragen via /admin/failures.
+package deadletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrFull: de store zit aan maxBytes.
+	ErrFull = errors.New("dead-letter store is full")
+	// ErrNotFound: geen entry met dat ID.
+	ErrNotFound = errors.New("dead-letter entry not found")
+)
+
+// Entry is een event dat niet verstuurd kon worden. Request is de body van
+// het event zoals de client het stuurde (niet geredacteerd: bedoeld om het
+// opnieuw in te sturen).
+type Entry struct {
+	ID            string          `json:"id"`
+	FailedAt      time.Time       `json:"failedAt"`
+	CorrelationID string          `json:"correlationId"`
+	ClientID      string          `json:"clientId,omitempty"`
+	EventType     string          `json:"eventType"`
+	Cluster       string          `json:"cluster,omitempty"` // leeg = default
+	Topic         string          `json:"topic"`
+	Reason        string          `json:"reason"`
+	Request       json.RawMessage `json:"request"`
+}
+
+// Filter beperkt List; lege velden filteren niet.
+type Filter struct {
+	EventType     string // hoofdletters maken niet uit
+	CorrelationID string
+	Since         time.Time
+	Limit         int // 0 = alles
+}
+
+func (f Filter) match(e Entry) bool {
+	return (f.EventType == "" || strings.EqualFold(f.EventType, e.EventType)) &&
+		(f.CorrelationID == "" || f.CorrelationID == e.CorrelationID) &&
+		(f.Since.IsZero() || !e.FailedAt.Before(f.Since))
+}
+
+var validID = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// Store bewaart elke entry als een apart bestand in dir; de bestandsnaam is
+// het ID, zodat de volgorde die van het falen is.
+type Store struct {
+	dir      string
+	maxBytes int64 // 0 = onbeperkt
+
+	mu   sync.Mutex
+	size int64
+	seq  uint64
+}
+
+// New opent (of maakt) de store in dir.
+func New(dir string, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir, maxBytes: maxBytes}
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if fi, err := os.Stat(s.path(id)); err == nil {
+			s.size += fi.Size()
+		}
+	}
+	return s, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// Put bewaart e en geeft het ID terug; ID en FailedAt worden hier gezet.
+func (s *Store) Put(e Entry) (string, error) {
+	e.FailedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	s.seq++
+	e.ID = fmt.Sprintf("%020d-%010d", e.FailedAt.UnixNano(), s.seq)
+	s.mu.Unlock()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	if s.maxBytes > 0 && s.size+int64(len(data)) > s.maxBytes {
+		s.mu.Unlock()
+		return "", ErrFull
+	}
+	s.size += int64(len(data))
+	s.mu.Unlock()
+
+	if err := os.WriteFile(s.path(e.ID), data, 0o600); err != nil {
+		s.removed(int64(len(data)))
+		return "", err
+	}
+	return e.ID, nil
+}
+
+func (s *Store) removed(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size -= size
+}
+
+// ids geeft de IDs van alle entries, oudste eerst.
+func (s *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok && !e.IsDir() && validID.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// List geeft de entries die aan f voldoen, nieuwste eerst.
+func (s *Store) List(f Filter) ([]Entry, error) {
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+	out := []Entry{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(out) >= f.Limit {
+			break
+		}
+		e, err := s.Get(ids[i])
+		if errors.Is(err, ErrNotFound) {
+			continue // net verwijderd
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.match(e) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Get geeft de entry met dat ID.
+func (s *Store) Get(id string) (Entry, error) {
+	if !validID.MatchString(id) {
+		return Entry{}, ErrNotFound
+	}
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Entry{}, ErrNotFound
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return Entry{}, fmt.Errorf("dead-letter entry %s: %w", id, err)
+	}
+	return e, nil
+}
+
+// Delete verwijdert de entry, bv. nadat het event opnieuw ingestuurd is.
+func (s *Store) Delete(id string) error {
+	if !validID.MatchString(id) {
+		return ErrNotFound
+	}
+	fi, err := os.Stat(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.removed(fi.Size())
+	return nil
+}
@@ -0,0 +1,349 @@
+// Package enrich vult payloads aan tussen de validatie en de publish, met
+// per eventType een ketting van stappen uit de config: een server
+// timestamp, een waarde uit een lookup service (bv. de naam van een
+// werkgever) en het normaliseren van hoofdletters.
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+)
+
+// AllEventTypes is de config key voor stappen die voor elk eventType
+// gelden; ze lopen vóór die van het eventType.
+const AllEventTypes = "*"
+
+var (
+	// ErrNotFound: de lookup service kent de waarde niet (404).
+	ErrNotFound = errors.New("lookup value not found")
+	// ErrUnavailable: de lookup service antwoordt niet of met een fout.
+	ErrUnavailable = errors.New("lookup service unavailable")
+)
+
+// defaultTimeout van een lookup zonder timeout in de config.
+const defaultTimeout = 5 * time.Second
+
+// maxCacheEntries: daarboven wordt de cache van een lookup leeggemaakt, zodat
+// veel verschillende waarden het geheugen niet laten groeien.
+const maxCacheEntries = 10000
+
+// Step is één stap van een ketting. Field en Fields zijn paden met punten
+// (bv. "employer.name"), zoals bij rules.
+type Step struct {
+	Type   string   `mapstructure:"type"`   // timestamp, lookup of case
+	Field  string   `mapstructure:"field"`  // timestamp, lookup: het veld dat gezet wordt
+	Fields []string `mapstructure:"fields"` // case: de velden die genormaliseerd worden
+	Case   string   `mapstructure:"case"`   // case: upper, lower of trim
+
+	URL      string        `mapstructure:"url"`      // lookup: met {pad} uit de payload, bv. https://employers/api/employers/{employerId}
+	Result   string        `mapstructure:"result"`   // lookup: pad in de JSON response, leeg = de hele response
+	Token    string        `mapstructure:"token"`    // lookup: bearer token
+	Timeout  time.Duration `mapstructure:"timeout"`  // lookup: 0 = 5s
+	CacheTTL time.Duration `mapstructure:"cacheTTL"` // lookup: hoe lang een antwoord gecached wordt, 0 = niet
+	Optional bool          `mapstructure:"optional"` // lookup: een mislukte lookup laat het veld weg i.p.v. het event te weigeren
+}
+
+var placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+
+func (s Step) Validate() error {
+	var errs []error
+	switch s.Type {
+	case "timestamp":
+		if len(fieldpath.Split(s.Field)) == 0 {
+			errs = append(errs, errors.New("field is required"))
+		}
+	case "lookup":
+		if len(fieldpath.Split(s.Field)) == 0 {
+			errs = append(errs, errors.New("field is required"))
+		}
+		u, err := url.Parse(placeholder.ReplaceAllString(s.URL, "x"))
+		switch {
+		case s.URL == "":
+			errs = append(errs, errors.New("url is required"))
+		case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+			errs = append(errs, fmt.Errorf("url %q must be an http(s) URL", s.URL))
+		case !placeholder.MatchString(s.URL):
+			errs = append(errs, fmt.Errorf("url %q needs at least one {field} from the payload", s.URL))
+		}
+		if s.Timeout < 0 || s.CacheTTL < 0 {
+			errs = append(errs, errors.New("timeout and cacheTTL must not be negative"))
+		}
+	case "case":
+		if len(s.Fields) == 0 {
+			errs = append(errs, errors.New("fields is required"))
+		}
+		for i, f := range s.Fields {
+			if len(fieldpath.Split(f)) == 0 {
+				errs = append(errs, fmt.Errorf("fields[%d] must not be empty", i))
+			}
+		}
+		if s.Case != "upper" && s.Case != "lower" && s.Case != "trim" {
+			errs = append(errs, fmt.Errorf("unknown case %q (upper, lower or trim)", s.Case))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown type %q (timestamp, lookup or case)", s.Type))
+	}
+	return errors.Join(errs...)
+}
+
+type step struct {
+	Step
+	path   []string
+	fields [][]string
+	lookup *lookup
+}
+
+// Chains bevat de ketting per eventType (lowercase).
+type Chains struct {
+	chains map[string][]step
+	now    func() time.Time
+}
+
+// New compileert de kettingen; ongeldige stappen (zie Step.Validate, dat de
+// config validatie al doet) worden overgeslagen.
+func New(steps map[string][]Step) *Chains {
+	c := &Chains{chains: make(map[string][]step, len(steps)), now: time.Now}
+	for eventType, list := range steps {
+		key := strings.ToLower(eventType) // viper lowercased map keys
+		for _, s := range list {
+			if s.Validate() != nil {
+				continue
+			}
+			compiled := step{Step: s, path: fieldpath.Split(s.Field)}
+			for _, f := range s.Fields {
+				compiled.fields = append(compiled.fields, fieldpath.Split(f))
+			}
+			if s.Type == "lookup" {
+				compiled.lookup = newLookup(s)
+			}
+			c.chains[key] = append(c.chains[key], compiled)
+		}
+	}
+	return c
+}
+
+// Has meldt of eventType (of elk eventType) een ketting heeft.
+func (c *Chains) Has(eventType string) bool {
+	return c != nil && (len(c.chains[AllEventTypes]) > 0 || len(c.chains[strings.ToLower(eventType)]) > 0)
+}
+
+// Apply voert de ketting van eventType uit op payload, stap per stap. Bij
+// een mislukte (niet optionele) lookup stopt het met een fout die
+// ErrNotFound of ErrUnavailable bevat.
+func (c *Chains) Apply(ctx context.Context, eventType string, payload map[string]interface{}) error {
+	if c == nil {
+		return nil
+	}
+	for _, list := range [][]step{c.chains[AllEventTypes], c.chains[strings.ToLower(eventType)]} {
+		for _, s := range list {
+			if err := c.apply(ctx, s, payload); err != nil {
+				return fmt.Errorf("enrichment %s %s: %w", s.Type, s.Field, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Chains) apply(ctx context.Context, s step, payload map[string]interface{}) error {
+	switch s.Type {
+	case "timestamp":
+		set(payload, s.path, c.now().UTC().Format(time.RFC3339Nano))
+	case "case":
+		for _, f := range s.fields {
+			normalize(payload, f, s.Case)
+		}
+	case "lookup":
+		v, ok, err := s.lookup.get(ctx, payload)
+		switch {
+		case err != nil && s.Optional:
+			return nil
+		case err != nil:
+			return err
+		case ok:
+			set(payload, s.path, v)
+		}
+	}
+	return nil
+}
+
+// set zet path in payload op v; ontbrekende objecten onderweg worden
+// aangemaakt, een niet-object onderweg wordt overschreven.
+func set(payload map[string]interface{}, path []string, v interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := payload[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			payload[key] = child
+		}
+		payload = child
+	}
+	payload[path[len(path)-1]] = v
+}
+
+// normalize past de string waarden op path aan; een array onderweg wordt
+// element per element gevolgd.
+func normalize(v interface{}, path []string, mode string) {
+	switch m := v.(type) {
+	case []interface{}:
+		for _, item := range m {
+			normalize(item, path, mode)
+		}
+	case map[string]interface{}:
+		child, ok := m[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) > 1 {
+			normalize(child, path[1:], mode)
+			return
+		}
+		switch s := child.(type) {
+		case string:
+			m[path[0]] = applyCase(s, mode)
+		case []interface{}:
+			for i, item := range s {
+				if str, ok := item.(string); ok {
+					s[i] = applyCase(str, mode)
+				}
+			}
+		}
+	}
+}
+
+func applyCase(s, mode string) string {
+	switch mode {
+	case "upper":
+		return strings.ToUpper(strings.TrimSpace(s))
+	case "lower":
+		return strings.ToLower(strings.TrimSpace(s))
+	}
+	return strings.TrimSpace(s)
+}
+
+// get geeft een waarde uit value op path, zonder arrays.
+func get(value interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// lookup haalt een waarde op bij een externe service, met een cache per URL.
+type lookup struct {
+	step   Step
+	result []string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cached // URL → antwoord
+}
+
+type cached struct {
+	fetched time.Time
+	value   interface{}
+}
+
+func newLookup(s Step) *lookup {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	return &lookup{
+		step:   s,
+		result: fieldpath.Split(s.Result),
+		client: &http.Client{Timeout: timeout},
+		cache:  map[string]cached{},
+	}
+}
+
+// get vult de URL in met de waarden uit payload en doet de lookup; ok is
+// false als een {pad} in de payload ontbreekt (niets op te zoeken).
+func (l *lookup) get(ctx context.Context, payload map[string]interface{}) (v interface{}, ok bool, err error) {
+	missing := false
+	u := placeholder.ReplaceAllStringFunc(l.step.URL, func(m string) string {
+		v, found := get(payload, fieldpath.Split(m[1:len(m)-1]))
+		if !found || v == nil {
+			missing = true
+			return ""
+		}
+		return url.PathEscape(fmt.Sprint(v))
+	})
+	if missing {
+		return nil, false, nil
+	}
+
+	if l.step.CacheTTL > 0 {
+		l.mu.Lock()
+		c, hit := l.cache[u]
+		l.mu.Unlock()
+		if hit && time.Since(c.fetched) < l.step.CacheTTL {
+			return c.value, true, nil
+		}
+	}
+
+	v, err = l.fetch(ctx, u)
+	if err != nil {
+		return nil, false, err
+	}
+	if l.step.CacheTTL > 0 {
+		l.mu.Lock()
+		if len(l.cache) >= maxCacheEntries {
+			l.cache = map[string]cached{}
+		}
+		l.cache[u] = cached{fetched: time.Now(), value: v}
+		l.mu.Unlock()
+	}
+	return v, true, nil
+}
+
+func (l *lookup) fetch(ctx context.Context, u string) (interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if l.step.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+l.step.Token)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: GET %s: %s", ErrNotFound, u, resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%w: GET %s: %s", ErrUnavailable, u, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("%w: GET %s: invalid JSON: %v", ErrUnavailable, u, err)
+	}
+	v, ok := get(doc, l.result)
+	if !ok {
+		return nil, fmt.Errorf("%w: GET %s: no %q in the response", ErrNotFound, u, l.step.Result)
+	}
+	return v, nil
+}
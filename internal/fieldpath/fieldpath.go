@@ -0,0 +1,75 @@
+// Package fieldpath bevat de paden naar payload velden zoals de config ze
+// schrijft (redaction, rules, enrichment, routing): namen gescheiden door een
+// punt, waarbij een array onderweg element per element gevolgd wordt.
+package fieldpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Split splitst een pad op punten. Een "$." prefix en "[*]" (JSON path
+// stijl) mogen, maar zijn niet nodig; een leeg pad geeft nil.
+func Split(p string) []string {
+	p = strings.TrimPrefix(strings.TrimSpace(p), "$.")
+	p = strings.NewReplacer("[*]", "", "[]", "").Replace(p)
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, ".")
+}
+
+// Lookup verzamelt de waarden op path in out en meldt of er minstens één is.
+func Lookup(v interface{}, path []string, out *[]interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) == 1 {
+			*out = append(*out, child)
+			return true
+		}
+		return Lookup(child, path[1:], out)
+	case []interface{}:
+		found := false
+		for _, item := range v {
+			found = Lookup(item, path, out) || found
+		}
+		return found
+	}
+	return false
+}
+
+// Number geeft de waarde van een JSON getal (float64 of json.Number).
+func Number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// JSONType geeft het JSON type van v, in de woorden van jsonschema.
+func JSONType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
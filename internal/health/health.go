@@ -0,0 +1,89 @@
+// Package health verzamelt de toestand van de componenten (Pulsar, spool,
+// config) voor /health, met een globale status waar monitoring op kan
+// alerteren.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Status van een component of van de service; Down is erger dan Degraded.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusDegraded Status = "degraded" // werkt, maar niet volledig (bv. events in de spool)
+	StatusDown     Status = "down"     // events kunnen niet verwerkt worden
+)
+
+func (s Status) rank() int {
+	switch s {
+	case StatusOK:
+		return 0
+	case StatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// Report is de toestand van één component; Info komt naast status in de JSON.
+type Report struct {
+	Status Status
+	Info   map[string]interface{}
+}
+
+func (r Report) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(r.Info)+1)
+	for k, v := range r.Info {
+		m[k] = v
+	}
+	m["status"] = r.Status
+	return json.Marshal(m)
+}
+
+// Checker houdt de checks per component bij.
+type Checker struct {
+	mu     sync.RWMutex
+	checks map[string]func() Report
+}
+
+func New() *Checker {
+	return &Checker{checks: map[string]func() Report{}}
+}
+
+// Register voegt de check van een component toe (of vervangt hem).
+func (h *Checker) Register(name string, check func() Report) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.checks[name] = check
+}
+
+// Check voert alle checks uit; de globale status is de slechtste.
+func (h *Checker) Check() (Status, map[string]Report) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	status := StatusOK
+	reports := make(map[string]Report, len(h.checks))
+	for name, check := range h.checks {
+		r := check()
+		if r.Status.rank() > status.rank() {
+			status = r.Status
+		}
+		reports[name] = r
+	}
+	return status, reports
+}
+
+// Handler geeft altijd 200, ook bij down: een herstart lost een onbereikbare
+// broker niet op. Monitoring alerteert op status.
+func (h *Checker) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, reports := h.Check()
+		c.JSON(http.StatusOK, gin.H{"status": status, "components": reports})
+	}
+}
@@ -0,0 +1,89 @@
+// Package idempotency onthoudt de uitkomst van publish requests, zodat een
+// retry met dezelfde Idempotency-Key (of, met dedup, hetzelfde event) niet
+// opnieuw gepubliceerd wordt maar dezelfde response krijgt. MemoryStore
+// volstaat voor één replica; met meerdere replicas moet het RedisStore zijn,
+// anders komt een retry op een andere replica er toch door.
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Record is wat er onder een key bewaard wordt: eerst een Pending
+// reservatie, daarna de response.
+type Record struct {
+	Pending     bool   `json:"pending,omitempty"`
+	RequestHash string `json:"requestHash"` // om hergebruik van een key voor een ander request te herkennen
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Store bewaart de records, met een TTL per key.
+type Store interface {
+	// Reserve bewaart rec onder key als die er nog niet is (true); anders
+	// geeft het het bestaande record terug (false).
+	Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error)
+	// Complete vervangt het record door de uitkomst.
+	Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error
+	// Release verwijdert het record, zodat een retry opnieuw verwerkt wordt.
+	Release(ctx context.Context, key string) error
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*RedisStore)(nil)
+)
+
+type memoryEntry struct {
+	rec     Record
+	expires time.Time
+}
+
+// MemoryStore houdt de records in het geheugen van deze replica.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	nextGC  time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}}
+}
+
+func (m *MemoryStore) Reserve(_ context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.After(m.nextGC) {
+		for k, e := range m.entries {
+			if now.After(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.nextGC = now.Add(time.Minute)
+	}
+
+	if e, ok := m.entries[key]; ok && now.Before(e.expires) {
+		return e.rec, false, nil
+	}
+	m.entries[key] = memoryEntry{rec: rec, expires: now.Add(ttl)}
+	return rec, true, nil
+}
+
+func (m *MemoryStore) Complete(_ context.Context, key string, rec Record, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryEntry{rec: rec, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *MemoryStore) Release(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
@@ -0,0 +1,92 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions is de verbinding met Redis.
+type RedisOptions struct {
+	Addr      string
+	Username  string
+	Password  string
+	DB        int
+	TLS       bool
+	KeyPrefix string // voor alle keys, om de Redis met andere services te delen
+}
+
+// RedisStore deelt de records tussen de replicas via Redis.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(opts RedisOptions) *RedisStore {
+	o := &redis.Options{
+		Addr:     opts.Addr,
+		Username: opts.Username,
+		Password: opts.Password,
+		DB:       opts.DB,
+	}
+	if opts.TLS {
+		o.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return &RedisStore{client: redis.NewClient(o), prefix: opts.KeyPrefix}
+}
+
+// Ping test de verbinding, voor /health.
+func (r *RedisStore) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *RedisStore) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisStore) Reserve(ctx context.Context, key string, rec Record, ttl time.Duration) (Record, bool, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, false, err
+	}
+	// de key kan verlopen tussen SET NX en GET; dan nog eens proberen
+	for range 3 {
+		ok, err := r.client.SetNX(ctx, r.prefix+key, data, ttl).Result()
+		if err != nil {
+			return Record{}, false, err
+		}
+		if ok {
+			return rec, true, nil
+		}
+		existing, err := r.client.Get(ctx, r.prefix+key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return Record{}, false, err
+		}
+		var stored Record
+		if err := json.Unmarshal(existing, &stored); err != nil {
+			return Record{}, false, fmt.Errorf("idempotency record %s: %w", key, err)
+		}
+		return stored, false, nil
+	}
+	return Record{}, false, fmt.Errorf("idempotency record %s: could not reserve", key)
+}
+
+func (r *RedisStore) Complete(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+key, data, ttl).Err()
+}
+
+func (r *RedisStore) Release(ctx context.Context, key string) error {
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
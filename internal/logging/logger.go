@@ -1,20 +1,183 @@
 package logging
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var Logger *zap.Logger
 
-func Init() {
-	cfg := zap.NewDevelopmentConfig()
-	cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	Logger, _ = cfg.Build()
+// Level is het actieve log level van Logger; het kan zonder herstart wijzigen
+// (config reload of PUT /admin/log-level).
+var Level = zap.NewAtomicLevel()
+
+// Options komt uit de logging sectie van de config.
+type Options struct {
+	Mode     string // development (default) of production
+	Encoding string // console of json; leeg = console in development, json in production
+	Level    string // debug, info, warn of error; leeg = debug in development, info in production
+	Sampling Sampling
+	File     File
+	OTLP     OTLP
+}
+
+// File schrijft de logs ook naar een bestand met rotatie, naast stdout.
+type File struct {
+	Path       string // leeg = geen bestand
+	MaxSizeMB  int    // roteren vanaf deze grootte
+	MaxAgeDays int    // oude bestanden na zoveel dagen verwijderen (0 = nooit)
+	MaxBackups int    // max. aantal oude bestanden (0 = allemaal bewaren)
+	Compress   bool   // oude bestanden gzippen
+}
+
+// Sampling beperkt logs met dezelfde boodschap per tick: de eerste Initial
+// gaan door, daarna elke Thereafter-ste. Enkel debug en info worden gesampled,
+// warnings en errors komen altijd door.
+type Sampling struct {
+	Enabled    bool
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// SetLevel zet Level op level, of op de default van mode als level leeg is.
+func SetLevel(mode, level string) error {
+	if level == "" {
+		level = "debug"
+		if mode == "production" {
+			level = "info"
+		}
+	}
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	Level.SetLevel(l)
+	return nil
+}
+
+// ParseLevel zet een level uit de config om; enkel debug, info, warn en error.
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch level {
+	case "debug", "info", "warn", "error":
+		return zapcore.ParseLevel(level)
+	}
+	return zapcore.InfoLevel, fmt.Errorf("unknown level %q (debug, info, warn or error)", level)
+}
+
+// Init bouwt Logger. Development geeft gekleurde console output met
+// stacktraces vanaf warn, production JSON (voor Elastic/Loki) met ISO8601
+// timestamps en stacktraces vanaf error.
+func Init(opts Options) error {
+	var cfg zap.Config
+	switch opts.Mode {
+	case "", "development":
+		cfg = zap.NewDevelopmentConfig()
+		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	case "production":
+		cfg = zap.NewProductionConfig()
+		cfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		cfg.Sampling = nil
+	default:
+		return fmt.Errorf("unknown mode %q (development or production)", opts.Mode)
+	}
+
+	if err := SetLevel(opts.Mode, opts.Level); err != nil {
+		return err
+	}
+	cfg.Level = Level
+
+	switch opts.Encoding {
+	case "":
+	case "console":
+		cfg.Encoding = "console"
+	case "json":
+		cfg.Encoding = "json"
+		// kleurcodes horen niet in JSON
+		cfg.EncoderConfig.EncodeLevel = zapcore.LowercaseLevelEncoder
+	default:
+		return fmt.Errorf("unknown encoding %q (console or json)", opts.Encoding)
+	}
+
+	var buildOpts []zap.Option
+	if f := opts.File; f.Path != "" {
+		// zelfde encoding als stdout, maar zonder kleurcodes
+		encCfg := cfg.EncoderConfig
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder := zapcore.NewConsoleEncoder(encCfg)
+		if cfg.Encoding == "json" {
+			encCfg.EncodeLevel = zapcore.LowercaseLevelEncoder
+			encoder = zapcore.NewJSONEncoder(encCfg)
+		}
+		file := zapcore.NewCore(encoder, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   f.Path,
+			MaxSize:    f.MaxSizeMB,
+			MaxAge:     f.MaxAgeDays,
+			MaxBackups: f.MaxBackups,
+			Compress:   f.Compress,
+		}), cfg.Level)
+		buildOpts = append(buildOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, file)
+		}))
+	}
+	if opts.OTLP.Enabled {
+		otlp, err := otlpCore(opts.OTLP)
+		if err != nil {
+			return fmt.Errorf("otlp: %w", err)
+		}
+		buildOpts = append(buildOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return zapcore.NewTee(core, otlp)
+		}))
+	}
+	if sm := opts.Sampling; sm.Enabled {
+		buildOpts = append(buildOpts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			sampled := zapcore.NewSamplerWithOptions(levelCore{core, belowWarn}, sm.Tick, sm.Initial, sm.Thereafter)
+			return zapcore.NewTee(sampled, levelCore{core, fromWarn})
+		}))
+	}
+	logger, err := cfg.Build(buildOpts...)
+	if err != nil {
+		return err
+	}
+	Logger = logger
+	zap.ReplaceGlobals(logger) // fallback voor code zonder eigen logger
+	return nil
 }
 
 func Sync() {
 	if Logger != nil {
 		_ = Logger.Sync()
 	}
+	if provider != nil {
+		_ = provider.ForceFlush(context.Background())
+	}
+}
+
+func belowWarn(l zapcore.Level) bool { return l < zapcore.WarnLevel }
+func fromWarn(l zapcore.Level) bool  { return l >= zapcore.WarnLevel }
+
+// levelCore laat van core enkel de levels door waarvoor allow true geeft.
+type levelCore struct {
+	zapcore.Core
+	allow func(zapcore.Level) bool
+}
+
+func (c levelCore) Enabled(l zapcore.Level) bool {
+	return c.allow(l) && c.Core.Enabled(l)
+}
+
+func (c levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return levelCore{c.Core.With(fields), c.allow}
+}
+
+func (c levelCore) Check(e zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.allow(e.Level) {
+		return ce
+	}
+	return c.Core.Check(e, ce)
 }
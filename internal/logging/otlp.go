@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
+)
+
+// OTLP stuurt de logs ook via OTLP/HTTP naar de collector, met dezelfde
+// service resource als de traces.
+type OTLP struct {
+	Enabled     bool
+	Endpoint    string // bv. "otel-collector:4318"
+	Insecure    bool
+	ServiceName string
+}
+
+// provider wordt in Sync geflusht.
+var provider *sdklog.LoggerProvider
+
+func otlpCore(opts OTLP) (zapcore.Core, error) {
+	var clientOpts []otlploghttp.Option
+	if opts.Endpoint != "" {
+		clientOpts = append(clientOpts, otlploghttp.WithEndpoint(opts.Endpoint))
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlploghttp.WithInsecure())
+	}
+	exporter, err := otlploghttp.New(context.Background(), clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tracing.Resource(opts.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+	provider = sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter)),
+		sdklog.WithResource(res),
+	)
+	core := otelzap.NewCore("github.com/rubenclaes/pulsar-api", otelzap.WithLoggerProvider(provider))
+	return levelCore{core, Level.Enabled}, nil
+}
+
+// Context geeft ctx mee aan de OTLP export, zodat log records aan de actieve
+// trace gekoppeld worden. Console en JSON output slaan het veld over.
+func Context(ctx context.Context) zap.Field {
+	return zap.Field{Key: "context", Type: zapcore.SkipType, Interface: ctx}
+}
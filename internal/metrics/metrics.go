@@ -0,0 +1,206 @@
+// Package metrics houdt de Prometheus metrics van de service bij, voor
+// /metrics: per event (eventType, sourceSystem, topic, uitkomst) en per HTTP
+// request.
+package metrics
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+// Results voor Event
+const (
+	ResultSent             = "sent"
+	ResultFallback         = "fallback"
+	ResultDryRun           = "dry-run"
+	ResultSpooled          = "spooled"
+	ResultValidationFailed = "validation-failed" // body of schema ongeldig
+	ResultRejected         = "rejected"          // autorisatie, quota, te veel lopende sends
+	ResultSendFailed       = "send-failed"
+)
+
+// maxLabelValues: eventType en sourceSystem komen van de client; daarboven
+// telt een nieuwe waarde als "other", zodat het aantal series begrensd blijft.
+const maxLabelValues = 200
+
+// OtherLabel vervangt een waarde boven maxLabelValues.
+const OtherLabel = "other"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	events       *prometheus.CounterVec
+	eventBytes   *prometheus.CounterVec
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+	sendDuration *prometheus.HistogramVec
+
+	opts Options
+	mu   sync.Mutex
+	seen map[string]map[string]bool // label → gekende waarden
+}
+
+// Options: met ClientLabel krijgen de event- en request metrics een label
+// "client" met de client identity (API key of certificaat), "anonymous"
+// zonder. Boven MaxClients telt een nieuwe client als "other".
+type Options struct {
+	ClientLabel bool
+	MaxClients  int
+}
+
+func New(opts Options) *Metrics {
+	labels := []string{"event_type", "source_system", "topic", "result"}
+	httpLabels := []string{"method", "route"}
+	if opts.ClientLabel {
+		labels = append(labels, "client")
+		httpLabels = append(httpLabels, "client")
+	}
+	m := &Metrics{
+		opts:     opts,
+		registry: prometheus.NewRegistry(),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pulsar_api_events_total",
+			Help: "Events per eventType, sourceSystem, topic en uitkomst.",
+		}, labels),
+		eventBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pulsar_api_event_bytes_total",
+			Help: "Bytes van de events (zoals naar Pulsar gestuurd) per eventType, sourceSystem, topic en uitkomst.",
+		}, labels),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pulsar_api_http_requests_total",
+			Help: "HTTP requests per route, methode en status.",
+		}, append(slices.Clone(httpLabels), "status")),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pulsar_api_http_request_duration_seconds",
+			Help:    "Duur van de HTTP requests per route en methode.",
+			Buckets: prometheus.DefBuckets,
+		}, httpLabels),
+		sendDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pulsar_api_publish_duration_seconds",
+			Help:    "Duur van de send naar Pulsar per topic en uitkomst, met retries en fallback topic.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"topic", "result"}),
+		seen: map[string]map[string]bool{},
+	}
+	m.registry.MustRegister(
+		m.events, m.eventBytes, m.httpRequests, m.httpDuration, m.sendDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Registry is de registry achter /metrics, om er andere collectors aan toe
+// te voegen.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// AuditDropped publiceert het aantal gedropte audit entries (volle wachtrij)
+// als pulsar_api_audit_dropped_total.
+func (m *Metrics) AuditDropped(dropped func() int64) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Name: "pulsar_api_audit_dropped_total",
+		Help: "Audit entries die gedropt werden omdat de wachtrij naar de audit sink vol was.",
+	}, func() float64 { return float64(dropped()) }))
+}
+
+// Handler serveert de metrics in het Prometheus formaat, of in OpenMetrics
+// (met de exemplars) als de scraper daarom vraagt.
+func (m *Metrics) Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	return gin.WrapH(h)
+}
+
+// Event telt de uitkomst van één event van client ("" = zonder identity);
+// topic is leeg als het geweigerd werd voor de routing. Een nil *Metrics
+// telt niets.
+func (m *Metrics) Event(client, eventType, sourceSystem, topic, result string, bytes int) {
+	if m == nil {
+		return
+	}
+	labels := prometheus.Labels{
+		"event_type":    m.limit("event_type", eventType, maxLabelValues),
+		"source_system": m.limit("source_system", sourceSystem, maxLabelValues),
+		"topic":         topic,
+		"result":        result,
+	}
+	if m.opts.ClientLabel {
+		labels["client"] = m.client(client)
+	}
+	m.events.With(labels).Inc()
+	m.eventBytes.With(labels).Add(float64(bytes))
+}
+
+// Send meet één send naar Pulsar (result sent, fallback of send-failed);
+// met een gesampelde trace in ctx als exemplar. Een nil *Metrics meet niets.
+func (m *Metrics) Send(ctx context.Context, topic, result string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	observe(ctx, m.sendDuration.WithLabelValues(topic, result), d.Seconds())
+}
+
+// client is de waarde van het client label.
+func (m *Metrics) client(id string) string {
+	if id == "" {
+		return "anonymous"
+	}
+	return m.limit("client", id, m.opts.MaxClients)
+}
+
+func (m *Metrics) limit(label, value string, maxValues int) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := m.seen[label]
+	if seen == nil {
+		seen = map[string]bool{}
+		m.seen[label] = seen
+	}
+	if !seen[value] {
+		if len(seen) >= maxValues {
+			return OtherLabel
+		}
+		seen[value] = true
+	}
+	return value
+}
+
+// HTTP meet elk request; de route is het patroon (/admin/failures/:id), niet
+// het pad, en leeg voor onbekende paden. Een request met een gesampelde trace
+// krijgt een exemplar met de trace_id, zodat een piek in de latency naar de
+// trage trace leidt. Moet na tracing.Middleware komen.
+func (m *Metrics) HTTP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		labels := []string{c.Request.Method, c.FullPath()}
+		if m.opts.ClientLabel {
+			// de identity wordt pas in de route group gezet, dus na c.Next
+			labels = append(labels, m.client(middleware.GetClientID(c)))
+		}
+		m.httpRequests.WithLabelValues(append(labels, strconv.Itoa(c.Writer.Status()))...).Inc()
+		observe(c.Request.Context(), m.httpDuration.WithLabelValues(labels...), time.Since(start).Seconds())
+	}
+}
+
+// observe voegt de trace van ctx als exemplar toe, als die gesampled is.
+func observe(ctx context.Context, o prometheus.Observer, value float64) {
+	sc := trace.SpanContextFromContext(ctx)
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && sc.IsValid() && sc.IsSampled() {
+		eo.ObserveWithExemplar(value, prometheus.Labels{"trace_id": sc.TraceID().String()})
+		return
+	}
+	o.Observe(value)
+}
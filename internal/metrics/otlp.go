@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	otelprom "go.opentelemetry.io/contrib/bridges/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
+)
+
+// OTLP komt uit de metrics.otlp config sectie.
+type OTLP struct {
+	Endpoint    string // OTLP/HTTP collector, bv. "otel-collector:4318"
+	Insecure    bool   // plain HTTP naar de collector
+	Interval    time.Duration
+	ServiceName string
+}
+
+// PushOTLP stuurt de metrics van de registry elke Interval via OTLP/HTTP naar
+// de collector, met dezelfde namen en labels als op /metrics. De
+// teruggegeven functie stuurt de laatste waarden en stopt de exporter.
+func (m *Metrics) PushOTLP(ctx context.Context, opts OTLP) (func(context.Context) error, error) {
+	clientOpts := []otlpmetrichttp.Option{}
+	if opts.Endpoint != "" {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithEndpoint(opts.Endpoint))
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlpmetrichttp.WithInsecure())
+	}
+	exporter, err := otlpmetrichttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tracing.Resource(opts.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := sdkmetric.NewPeriodicReader(exporter,
+		sdkmetric.WithInterval(opts.Interval),
+		sdkmetric.WithProducer(otelprom.NewMetricProducer(otelprom.WithGatherer(m.registry))),
+	)
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res))
+	return mp.Shutdown, nil
+}
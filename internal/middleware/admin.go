@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+// RequireClient laat enkel de opgegeven client identities door. Een lege
+// lijst laat niemand door (403): een IP allowlist alleen authenticeert niet.
+func RequireClient(allowed []string) gin.HandlerFunc {
+	set := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		set[strings.ToLower(a)] = true
+	}
+
+	return func(c *gin.Context) {
+		if len(set) == 0 {
+			problem.Abort(c, http.StatusForbidden, "no clients are allowed to use this route", GetCorrelationID(c))
+			return
+		}
+		id := GetClientID(c)
+		if id == "" {
+			problem.Abort(c, http.StatusUnauthorized, "authentication required", GetCorrelationID(c))
+			return
+		}
+		if !set[strings.ToLower(id)] {
+			problem.Abort(c, http.StatusForbidden, "client "+id+" is not allowed to use this route", GetCorrelationID(c))
+			return
+		}
+		c.Next()
+	}
+}
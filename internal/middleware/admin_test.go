@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		allowed []string
+		client  string
+		want    int
+	}{
+		{"empty list refuses", nil, "ops", http.StatusForbidden},
+		{"empty list refuses anonymous", nil, "", http.StatusForbidden},
+		{"allowed client", []string{"Ops"}, "ops", http.StatusOK},
+		{"other client", []string{"ops"}, "payroll", http.StatusForbidden},
+		{"anonymous", []string{"ops"}, "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.GET("/", func(c *gin.Context) {
+				if tt.client != "" {
+					SetClientID(c, tt.client)
+				}
+			}, RequireClient(tt.allowed), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	scopesKey    = "clientScopes"
+)
+
+// APIKey is één entry uit de apiKeys config lijst.
+type APIKey struct {
+	Key    string   `mapstructure:"key"`
+	Client string   `mapstructure:"client"`
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// APIKeyIdentity zet de client identity en scopes van een geldige X-API-Key.
+// Requests zonder key gaan ongewijzigd door; een onbekende key geeft 401.
+func APIKeyIdentity(keys []APIKey) gin.HandlerFunc {
+	lookup := newAPIKeys(keys)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(APIKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		k, ok := lookup.match(key)
+		if !ok {
+			problem.Abort(c, http.StatusUnauthorized, "invalid API key", GetCorrelationID(c))
+			return
+		}
+
+		SetClientID(c, k.Client)
+		c.Set(scopesKey, k.Scopes)
+		c.Next()
+	}
+}
+
+// apiKeys zoekt een key op via zijn sha256 hash, zie match.
+type apiKeys struct {
+	keys   []APIKey
+	hashed [][sha256.Size]byte
+}
+
+func newAPIKeys(keys []APIKey) *apiKeys {
+	hashed := make([][sha256.Size]byte, len(keys))
+	for i, k := range keys {
+		hashed[i] = sha256.Sum256([]byte(k.Key))
+	}
+	return &apiKeys{keys: keys, hashed: hashed}
+}
+
+func (a *apiKeys) match(key string) (APIKey, bool) {
+	// constant-time vergelijken op de hashes, zodat de lengte niet lekt
+	sum := sha256.Sum256([]byte(key))
+	match := -1
+	for i := range a.hashed {
+		if subtle.ConstantTimeCompare(sum[:], a.hashed[i][:]) == 1 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return APIKey{}, false
+	}
+	return a.keys[match], true
+}
+
+func GetClientScopes(c *gin.Context) []string {
+	if v, ok := c.Get(scopesKey); ok {
+		if s, ok := v.([]string); ok {
+			return s
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+)
+
+// maxRedactedBody: een request body tot zoveel bytes wordt volledig
+// bijgehouden om hem te kunnen redacteren (zoals api.batch.streamThreshold).
+const maxRedactedBody = 1 << 20
+
+// BodyLogger logt request en response bodies op debug level, om foute
+// producers te onderzoeken zonder packet capture. Event payloads worden
+// geredacteerd en de bodies afgekapt op maxBytes. Staat enkel aan als het
+// aangezet is én het log level debug is; beide kunnen at runtime wijzigen.
+type BodyLogger struct {
+	mu       sync.RWMutex
+	enabled  bool
+	maxBytes int
+	redactor *redact.Redactor // vast, net als in de handlers
+}
+
+func NewBodyLogger(enabled bool, maxBytes int, redactor *redact.Redactor) *BodyLogger {
+	b := &BodyLogger{redactor: redactor}
+	b.Update(enabled, maxBytes)
+	return b
+}
+
+// Update past de instellingen aan (config reload).
+func (b *BodyLogger) Update(enabled bool, maxBytes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enabled = enabled
+	b.maxBytes = maxBytes
+}
+
+func (b *BodyLogger) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		b.mu.RLock()
+		enabled, maxBytes := b.enabled, b.maxBytes
+		b.mu.RUnlock()
+
+		if !enabled || !Logger(c).Core().Enabled(zapcore.DebugLevel) {
+			c.Next()
+			return
+		}
+
+		// de body niet vooraf inlezen, de handler streamt een grote batch:
+		// enkel wat gelogd kan worden bijhouden terwijl de handler hem leest.
+		// Redacteren kan enkel op de volledige JSON, dus met een redactor tot
+		// maxRedactedBody bytes.
+		limit := maxBytes
+		if b.redactor != nil {
+			limit = max(maxBytes, maxRedactedBody)
+		}
+		req := &capturingBody{ReadCloser: c.Request.Body, max: limit}
+		if c.Request.Body == nil {
+			req.ReadCloser = http.NoBody
+		}
+		c.Request.Body = req
+		w := &capturingWriter{ResponseWriter: c.Writer, max: maxBytes}
+		c.Writer = w
+
+		c.Next()
+
+		reqSize := req.size(c.Request.ContentLength)
+		size := max(w.Size(), 0) // -1 als er niets geschreven is
+		Logger(c).Debug("http bodies",
+			zap.Int("status", c.Writer.Status()),
+			zap.Int("requestBytes", reqSize),
+			zap.String("request", bodyForLog(req.buf.Bytes(), reqSize, maxBytes, b.redactor)),
+			zap.Int("responseBytes", size),
+			// de echo van het event in de response is al geredacteerd
+			zap.String("response", bodyForLog(w.buf.Bytes(), size, maxBytes, nil)),
+		)
+	}
+}
+
+// bodyForLog redacteert een JSON body (als redactor niet nil is) en kapt hem
+// af op maxBytes; size is de volledige grootte, body kan al afgekapt zijn. Een
+// body die geen JSON is, wordt afgekapt maar ongewijzigd gelogd. Een afgekapte
+// body kan niet geredacteerd worden en wordt met een redactor niet gelogd.
+func bodyForLog(body []byte, size, maxBytes int, redactor *redact.Redactor) string {
+	if redactor != nil && size > len(body) {
+		return fmt.Sprintf("… (%d bytes, too large to redact)", size)
+	}
+	var v interface{}
+	if redactor != nil && json.Unmarshal(body, &v) == nil {
+		if out, err := json.Marshal(redactor.Events(v)); err == nil {
+			body, size = out, len(out)
+		}
+	}
+	if size > maxBytes {
+		return fmt.Sprintf("%s… (%d bytes)", body[:min(len(body), maxBytes)], size)
+	}
+	return string(body)
+}
+
+// capturingBody houdt de eerste max bytes van de request body bij terwijl de
+// handler hem leest.
+type capturingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	max  int
+	read int
+	eof  bool
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(room, n)])
+	}
+	b.read += n
+	b.eof = b.eof || err == io.EOF
+	return n, err
+}
+
+// size leest wat de handler niet gelezen heeft (bv. bij een fout vóór de
+// binding) tot max bytes, en geeft de grootte van de body: de gelezen bytes,
+// of de Content-Length als de body niet volledig gelezen is.
+func (b *capturingBody) size(contentLength int64) int {
+	if !b.eof && b.buf.Len() < b.max {
+		_, _ = io.CopyN(io.Discard, b, int64(b.max-b.buf.Len()))
+	}
+	if !b.eof && contentLength > int64(b.read) {
+		return int(contentLength)
+	}
+	return b.read
+}
+
+// capturingWriter houdt de eerste max bytes van de response bij.
+type capturingWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+	max int
+}
+
+func (w *capturingWriter) Write(p []byte) (int, error) {
+	if room := w.max - w.buf.Len(); room > 0 {
+		w.buf.Write(p[:min(room, len(p))])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	if room := w.max - w.buf.Len(); room > 0 {
+		w.buf.WriteString(s[:min(room, len(s))])
+	}
+	return w.ResponseWriter.WriteString(s)
+}
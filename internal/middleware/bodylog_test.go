@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/rubenclaes/pulsar-api/internal/redact"
+)
+
+func TestBodyForLog(t *testing.T) {
+	redactor := redact.New(map[string][]redact.Rule{"WAGE_ERROR": {{Path: "iban"}}}, nil)
+	event := `{"eventType":"WAGE_ERROR","payload":{"iban":"BE68539007547034"}}`
+
+	tests := []struct {
+		name     string
+		body     string
+		size     int
+		redactor *redact.Redactor
+		want     string
+	}{
+		{"redacted", event, len(event), redactor, `{"eventType":"WAGE_ERROR","payload":{"iban":"***"}}`},
+		{"truncated", event, len(event), nil, event[:10] + "… (64 bytes)"},
+		// een niet volledig bijgehouden (gestreamde) body kan niet geredacteerd worden
+		{"too large to redact", event[:20], 2 << 20, redactor, "… (2097152 bytes, too large to redact)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxBytes := 100
+			if tt.redactor == nil {
+				maxBytes = 10
+			}
+			got := bodyForLog([]byte(tt.body), tt.size, maxBytes, tt.redactor)
+			if got != tt.want {
+				t.Errorf("bodyForLog = %s, want %s", got, tt.want)
+			}
+			if strings.Contains(got, "BE68") && tt.redactor != nil {
+				t.Errorf("bodyForLog leaks the IBAN: %s", got)
+			}
+		})
+	}
+}
+
+func TestCapturingBodySize(t *testing.T) {
+	body := strings.Repeat("x", 100)
+	tests := []struct {
+		name          string
+		max, read     int
+		contentLength int64
+		wantSize      int
+		wantCaptured  int
+	}{
+		{"read by the handler", 200, 100, 100, 100, 100},
+		{"not read by the handler", 200, 0, 100, 100, 100},
+		{"larger than max", 10, 5, 100, 100, 10},
+		{"larger than max, chunked", 10, 50, -1, 50, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &capturingBody{ReadCloser: io.NopCloser(strings.NewReader(body)), max: tt.max}
+			_, _ = io.CopyN(io.Discard, b, int64(tt.read))
+			if got := b.size(tt.contentLength); got != tt.wantSize {
+				t.Errorf("size = %d, want %d", got, tt.wantSize)
+			}
+			if got := b.buf.Len(); got != tt.wantCaptured {
+				t.Errorf("captured %d bytes, want %d", got, tt.wantCaptured)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+// ConcurrencyLimiter laat maximaal maxInFlight requests tegelijk door. Een
+// request die geen plaats vindt, wacht maximaal queueWait en krijgt daarna 503
+// met een Retry-After header, zodat de gateway onder piekbelasting voorspelbaar
+// degradeert. reserved plaatsen daarvan zijn enkel voor priority requests
+// (zie PriorityLane). De limieten kunnen at runtime aangepast worden.
+type ConcurrencyLimiter struct {
+	mu         sync.RWMutex
+	sem        chan struct{} // nil = onbeperkt
+	reserved   chan struct{} // enkel priority requests, nil = geen
+	queueWait  time.Duration
+	retryAfter string
+}
+
+func NewConcurrencyLimiter(maxInFlight, reserved int, queueWait, retryAfter time.Duration) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{}
+	l.Update(maxInFlight, reserved, queueWait, retryAfter)
+	return l
+}
+
+// Update past de limieten aan. Requests die al een plaats hebben, geven die
+// terug aan de semaphore waarop ze gestart zijn.
+func (l *ConcurrencyLimiter) Update(maxInFlight, reserved int, queueWait, retryAfter time.Duration) {
+	var sem, res chan struct{}
+	if maxInFlight > 0 {
+		reserved = min(reserved, maxInFlight-1)
+		sem = make(chan struct{}, maxInFlight-max(reserved, 0))
+		if reserved > 0 {
+			res = make(chan struct{}, reserved)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sem != nil && sem != nil && cap(l.sem) == cap(sem) {
+		sem = l.sem // zelfde grootte: bestaande semaphore houden
+	}
+	if l.reserved != nil && res != nil && cap(l.reserved) == cap(res) {
+		res = l.reserved
+	}
+	l.sem, l.reserved = sem, res
+	l.queueWait = queueWait
+	l.retryAfter = strconv.Itoa(max(int(retryAfter.Seconds()), 1))
+}
+
+func (l *ConcurrencyLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l.mu.RLock()
+		sem, reserved, queueWait, retryAfter := l.sem, l.reserved, l.queueWait, l.retryAfter
+		l.mu.RUnlock()
+
+		if sem == nil {
+			c.Next()
+			return
+		}
+		if reserved != nil && IsPriority(c) {
+			select {
+			case reserved <- struct{}{}:
+				defer func() { <-reserved }()
+				c.Next()
+				return
+			default: // gereserveerde plaatsen vol: zoals elke request
+			}
+		}
+
+		select {
+		case sem <- struct{}{}:
+		default:
+			if !acquire(c, sem, queueWait) {
+				c.Header("Retry-After", retryAfter)
+				problem.Abort(c, http.StatusServiceUnavailable,
+					"too many concurrent publish requests, retry later", GetCorrelationID(c))
+				return
+			}
+		}
+		defer func() { <-sem }()
+
+		c.Next()
+	}
+}
+
+func acquire(c *gin.Context, sem chan struct{}, wait time.Duration) bool {
+	if wait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Drain haalt een replica uit de load balancer voor een rolling deployment:
+// na Start geeft /ready 503, zodat de orchestrator geen nieuwe requests meer
+// stuurt, en Status toont hoeveel publish requests en asynchroon werk (sends,
+// spool) er nog lopen. Requests die toch nog binnenkomen, worden gewoon
+// verwerkt.
+type Drain struct {
+	inFlight atomic.Int64
+
+	mu      sync.RWMutex
+	since   time.Time // zero = niet aan het drainen
+	pending []pendingWork
+}
+
+type pendingWork struct {
+	name  string
+	count func() int
+}
+
+type DrainStatus struct {
+	Draining         bool           `json:"draining"`
+	Since            *time.Time     `json:"since,omitempty"`
+	InFlightRequests int            `json:"inFlightRequests"`
+	Pending          map[string]int `json:"pending"`
+	Drained          bool           `json:"drained"` // aan het drainen en niets loopt nog
+}
+
+func NewDrain() *Drain {
+	return &Drain{}
+}
+
+// AddPending registreert asynchroon werk dat af moet zijn voor de replica
+// gedrained is, bv. de lopende sends of de events in de spool.
+func (d *Drain) AddPending(name string, count func() int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending = append(d.pending, pendingWork{name: name, count: count})
+}
+
+// Start begint met drainen; false als dat al bezig was.
+func (d *Drain) Start() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.since.IsZero() {
+		return false
+	}
+	d.since = time.Now().UTC()
+	return true
+}
+
+// Cancel maakt de replica terug ready.
+func (d *Drain) Cancel() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.since = time.Time{}
+}
+
+func (d *Drain) Draining() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return !d.since.IsZero()
+}
+
+func (d *Drain) Status() DrainStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	st := DrainStatus{
+		Draining:         !d.since.IsZero(),
+		InFlightRequests: int(d.inFlight.Load()),
+		Pending:          make(map[string]int, len(d.pending)),
+	}
+	idle := st.InFlightRequests == 0
+	for _, p := range d.pending {
+		n := p.count()
+		st.Pending[p.name] = n
+		idle = idle && n == 0
+	}
+	if st.Draining {
+		since := d.since
+		st.Since = &since
+		st.Drained = idle
+	}
+	return st
+}
+
+// Wait wacht tot de replica gedrained is (of niet meer draint) of ctx afloopt.
+func (d *Drain) Wait(ctx context.Context) DrainStatus {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		st := d.Status()
+		if st.Drained || !st.Draining {
+			return st
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return d.Status()
+		}
+	}
+}
+
+// Track telt de lopende requests van een route.
+func (d *Drain) Track() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d.inFlight.Add(1)
+		defer d.inFlight.Add(-1)
+		c.Next()
+	}
+}
+
+// Ready is de readiness probe: 503 zolang de replica draint.
+func (d *Drain) Ready() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d.Draining() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
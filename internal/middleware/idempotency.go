@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/idempotency"
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentResponseSize = 1 << 20
+	// een reservatie van een replica die crasht, blokkeert de key niet langer dan dit
+	idempotencyPendingTTL = 5 * time.Minute
+)
+
+// Idempotency geeft een retry de response van het eerste request in plaats
+// van het event opnieuw te publiceren: voor requests met een Idempotency-Key
+// (ttl lang), en met dedup ook voor identieke events zonder key (dedupWindow
+// lang). Enkel 2xx responses worden bewaard; na een fout mag de client het
+// opnieuw proberen. Is de store onbereikbaar, dan gaat het request gewoon
+// door (niet gededupliceerd). Enabled, ttl en dedupWindow kunnen at runtime
+// aangepast worden.
+type Idempotency struct {
+	store idempotency.Store
+
+	mu          sync.RWMutex
+	enabled     bool
+	ttl         time.Duration
+	dedupWindow time.Duration // 0 = geen dedup zonder key
+}
+
+func NewIdempotency(store idempotency.Store, enabled bool, ttl, dedupWindow time.Duration) *Idempotency {
+	i := &Idempotency{store: store}
+	i.Update(enabled, ttl, dedupWindow)
+	return i
+}
+
+// Update past de instellingen aan (config reload).
+func (i *Idempotency) Update(enabled bool, ttl, dedupWindow time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.enabled = enabled
+	i.ttl = ttl
+	i.dedupWindow = dedupWindow
+}
+
+// Handler; dedup enkel op routes met één event per request.
+func (i *Idempotency) Handler(dedup bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		i.mu.RLock()
+		enabled, ttl, dedupWindow := i.enabled, i.ttl, i.dedupWindow
+		i.mu.RUnlock()
+
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if !enabled || (key == "" && (!dedup || dedupWindow <= 0)) {
+			c.Next()
+			return
+		}
+		corrID := GetCorrelationID(c)
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Abort(c, http.StatusBadRequest, "Idempotency-Key must not be longer than 255 characters", corrID)
+			return
+		}
+
+		var (
+			body        []byte
+			requestHash string
+			hashing     *hashingBody
+		)
+		if dedup {
+			// de handler leest de body daarna opnieuw
+			if c.Request.Body != nil {
+				body, _ = io.ReadAll(c.Request.Body)
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			}
+			hash := sha256.Sum256(body)
+			requestHash = hex.EncodeToString(hash[:])
+		} else {
+			// een batch niet bufferen, de handler streamt een grote batch: de
+			// hash wordt berekend terwijl de handler de body leest
+			hashing = newHashingBody(c.Request.Body)
+			c.Request.Body = hashing
+		}
+
+		client := GetClientID(c)
+		var storeKey string
+		if key != "" {
+			storeKey = "idem:" + client + ":" + key
+		} else {
+			// dedup: hetzelfde event, ook met andere witruimte of volgorde van de velden
+			var event interface{}
+			if json.Unmarshal(body, &event) != nil {
+				c.Next() // de handler geeft de 400
+				return
+			}
+			canonical, _ := json.Marshal(event)
+			hash := sha256.Sum256(canonical)
+			storeKey = "dedup:" + client + ":" + hex.EncodeToString(hash[:])
+			ttl = dedupWindow
+		}
+
+		log := Logger(c)
+		ctx := context.WithoutCancel(c.Request.Context())
+		rec, reserved, err := i.store.Reserve(ctx, storeKey, idempotency.Record{Pending: true, RequestHash: requestHash}, min(ttl, idempotencyPendingTTL))
+		if err != nil {
+			log.Warn("idempotency store unavailable, request not deduplicated", zap.Error(err))
+			c.Next()
+			return
+		}
+		if !reserved {
+			if hashing != nil && !rec.Pending {
+				requestHash = hashing.sum()
+			}
+			i.replay(c, rec, key, requestHash, corrID)
+			return
+		}
+
+		w := &capturingWriter{ResponseWriter: c.Writer, max: maxIdempotentResponseSize}
+		c.Writer = w
+
+		c.Next()
+
+		status := w.Status()
+		if status < 200 || status >= 300 {
+			if err := i.store.Release(ctx, storeKey); err != nil {
+				log.Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+		if hashing != nil {
+			requestHash = hashing.sum()
+		}
+		rec = idempotency.Record{
+			RequestHash: requestHash,
+			Status:      status,
+			ContentType: w.Header().Get("Content-Type"),
+			Body:        w.buf.Bytes(),
+		}
+		if w.Size() > w.buf.Len() {
+			// te groot om te bewaren (grote batch): enkel de uitkomst
+			rec.ContentType = "application/json; charset=utf-8"
+			rec.Body, _ = json.Marshal(gin.H{"status": "duplicate", "correlationId": corrID})
+		}
+		if err := i.store.Complete(ctx, storeKey, rec, ttl); err != nil {
+			log.Warn("failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+// replay beantwoordt een herhaald request met de bewaarde response.
+func (i *Idempotency) replay(c *gin.Context, rec idempotency.Record, key, requestHash, corrID string) {
+	switch {
+	// een lopende batch heeft nog geen hash
+	case key != "" && rec.RequestHash != "" && rec.RequestHash != requestHash:
+		problem.Abort(c, http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request", corrID)
+	case rec.Pending && key != "":
+		c.Header("Retry-After", "1")
+		problem.Abort(c, http.StatusConflict, "a request with this Idempotency-Key is still being processed", corrID)
+	case rec.Pending:
+		c.Header("Retry-After", "1")
+		problem.Abort(c, http.StatusConflict, "an identical event is still being processed", corrID)
+	default:
+		Logger(c).Info("duplicate request, replaying stored response",
+			zap.Bool("idempotencyKey", key != ""), zap.Int("status", rec.Status))
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Data(rec.Status, rec.ContentType, rec.Body)
+		c.Abort()
+	}
+}
+
+// hashingBody hasht de request body terwijl de handler hem leest, zonder hem
+// in het geheugen te houden.
+type hashingBody struct {
+	io.ReadCloser
+	hash hash.Hash
+}
+
+func newHashingBody(body io.ReadCloser) *hashingBody {
+	if body == nil {
+		body = http.NoBody
+	}
+	return &hashingBody{ReadCloser: body, hash: sha256.New()}
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.hash.Write(p[:n])
+	return n, err
+}
+
+// sum geeft de hash van de volledige body, ook van wat de handler niet
+// gelezen heeft.
+func (b *hashingBody) sum() string {
+	_, _ = io.Copy(b.hash, b.ReadCloser)
+	return hex.EncodeToString(b.hash.Sum(nil))
+}
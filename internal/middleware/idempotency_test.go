@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/idempotency"
+)
+
+func TestIdempotencyBatchNotBuffered(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	idem := NewIdempotency(idempotency.NewMemoryStore(), true, time.Hour, 0)
+
+	calls := 0
+	r := gin.New()
+	r.POST("/batch", idem.Handler(false), func(c *gin.Context) {
+		calls++
+		// de handler moet de body zelf lezen, niet een kopie in het geheugen
+		if _, ok := c.Request.Body.(*hashingBody); !ok {
+			t.Errorf("request body is %T, want it read through", c.Request.Body)
+		}
+		// een gestreamde batch leest niet noodzakelijk tot het einde
+		buf := make([]byte, 4)
+		_, _ = io.ReadFull(c.Request.Body, buf)
+		c.JSON(http.StatusOK, gin.H{"status": "sent"})
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(`[{"n":1}]`); w.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d", w.Code)
+	}
+	if w := post(`[{"n":1}]`); w.Code != http.StatusOK || w.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("retry: status = %d, replayed = %q", w.Code, w.Header().Get(IdempotentReplayedHeader))
+	}
+	// zelfde begin, andere body: de hash dekt ook wat de handler niet las
+	if w := post(`[{"n":2}]`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("other body: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const clientIDKey = "clientId"
+
+// ClientCertIdentity mapt het (geverifieerde) client certificaat naar een
+// client identity. Eerst wordt de CN opgezocht in de mapping, daarna elke SAN;
+// zonder match wordt de CN (of eerste SAN) zelf de identity.
+func ClientCertIdentity(mapping map[string]string) gin.HandlerFunc {
+	// viper lowercased map keys, dus we matchen case-insensitive
+	lookup := make(map[string]string, len(mapping))
+	for k, v := range mapping {
+		lookup[strings.ToLower(k)] = v
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil && len(c.Request.TLS.PeerCertificates) > 0 {
+			if id := certIdentity(c.Request.TLS.PeerCertificates[0], lookup); id != "" {
+				SetClientID(c, id)
+			}
+		}
+		c.Next()
+	}
+}
+
+func certIdentity(cert *x509.Certificate, lookup map[string]string) string {
+	names := make([]string, 0, 1+len(cert.DNSNames)+len(cert.EmailAddresses)+len(cert.URIs))
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+
+	for _, n := range names {
+		if id, ok := lookup[strings.ToLower(n)]; ok {
+			return id
+		}
+	}
+	if len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+func SetClientID(c *gin.Context, id string) {
+	c.Set(clientIDKey, id)
+}
+
+func GetClientID(c *gin.Context) string {
+	if v, ok := c.Get(clientIDKey); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+// IPFilter laat enkel requests door waarvan het client IP niet in deny zit en,
+// als allow niet leeg is, wel in allow. Het client IP komt van c.ClientIP(),
+// dus na trusted-proxy resolutie door gin.
+func IPFilter(allow, deny []string) (gin.HandlerFunc, error) {
+	allowed, err := parsePrefixes(allow)
+	if err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	}
+	denied, err := parsePrefixes(deny)
+	if err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+
+	return func(c *gin.Context) {
+		ip, err := netip.ParseAddr(c.ClientIP())
+		if err != nil || !ipPermitted(ip.Unmap(), allowed, denied) {
+			problem.Abort(c, http.StatusForbidden,
+				"source address "+c.ClientIP()+" is not allowed for this route",
+				GetCorrelationID(c),
+			)
+			return
+		}
+		c.Next()
+	}, nil
+}
+
+func ipPermitted(ip netip.Addr, allow, deny []netip.Prefix) bool {
+	for _, p := range deny {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, p := range allow {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrefixes accepteert zowel CIDR's als losse IP adressen.
+func parsePrefixes(entries []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		if strings.Contains(e, "/") {
+			p, err := netip.ParsePrefix(e)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		a, err := netip.ParseAddr(e)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIPFilterForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		trusted []string
+		remote  string
+		xff     string
+		want    int
+	}{
+		{"allowed peer", nil, "10.1.2.3:1234", "", http.StatusOK},
+		{"denied peer", nil, "203.0.113.5:1234", "", http.StatusForbidden},
+		{"spoofed header without trusted proxies", nil, "203.0.113.5:1234", "10.1.2.3", http.StatusForbidden},
+		{"spoofed header from untrusted peer", []string{"192.0.2.1"}, "203.0.113.5:1234", "10.1.2.3", http.StatusForbidden},
+		{"header from trusted proxy", []string{"203.0.113.0/24"}, "203.0.113.5:1234", "10.1.2.3", http.StatusOK},
+		{"denied client behind trusted proxy", []string{"10.0.0.0/8"}, "10.9.9.9:1234", "203.0.113.7", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := IPFilter([]string{"10.0.0.0/8"}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := gin.New()
+			if err := r.SetTrustedProxies(tt.trusted); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/", filter, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/logging"
+)
+
+const loggerKey = "logger"
+
+// RequestLogger zet per request een logger in de context met correlationId,
+// method, route en traceId, zodat handlers die velden niet bij elke log call
+// moeten meegeven. Registreren na CorrelationID en tracing.Middleware.
+func RequestLogger(base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		fields := []zap.Field{
+			zap.String("correlationId", GetCorrelationID(c)),
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+			logging.Context(ctx),
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields = append(fields, zap.String("traceId", sc.TraceID().String()))
+		}
+		c.Set(loggerKey, base.With(fields...))
+		c.Next()
+	}
+}
+
+// Logger geeft de request logger, met de client identity als die al gekend
+// is (die zetten pas de middlewares van de route group), of de globale zap
+// logger buiten een request.
+func Logger(c *gin.Context) *zap.Logger {
+	log, ok := c.Value(loggerKey).(*zap.Logger)
+	if !ok {
+		return zap.L()
+	}
+	if id := GetClientID(c); id != "" {
+		return log.With(zap.String("clientId", id))
+	}
+	return log
+}
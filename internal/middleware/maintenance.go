@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+const defaultMaintenanceMessage = "publishing is temporarily disabled for planned maintenance"
+
+// Maintenance is de maintenance vlag die via config en /admin/maintenance
+// gezet wordt. Enkel routes met Maintenance.Guard() worden erdoor geblokkeerd.
+type Maintenance struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+}
+
+type MaintenanceStatus struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+func NewMaintenance(enabled bool, message string) *Maintenance {
+	m := &Maintenance{}
+	m.Set(enabled, message)
+	return m
+}
+
+func (m *Maintenance) Set(enabled bool, message string) {
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled, m.message = enabled, message
+}
+
+func (m *Maintenance) Status() MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return MaintenanceStatus{Enabled: m.enabled, Message: m.message}
+}
+
+// Guard antwoordt 503 met de maintenance boodschap zolang de vlag aan staat.
+func (m *Maintenance) Guard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if st := m.Status(); st.Enabled {
+			problem.Abort(c, http.StatusServiceUnavailable, st.Message, GetCorrelationID(c))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// nonceCache onthoudt gebruikte nonces tot hun TTL verloopt.
+type nonceCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	seen    map[string]time.Time // nonce → vervaltijd
+	nextGC  time.Time
+	nowFunc func() time.Time
+}
+
+func newNonceCache(ttl time.Duration) *nonceCache {
+	return &nonceCache{
+		ttl:     ttl,
+		seen:    make(map[string]time.Time),
+		nowFunc: time.Now,
+	}
+}
+
+// add registreert nonce en geeft false terug als die nog niet verlopen was,
+// m.a.w. als het een replay is.
+func (n *nonceCache) add(nonce string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := n.nowFunc()
+	if now.After(n.nextGC) {
+		for k, exp := range n.seen {
+			if now.After(exp) {
+				delete(n.seen, k)
+			}
+		}
+		n.nextGC = now.Add(n.ttl)
+	}
+
+	if exp, ok := n.seen[nonce]; ok && now.Before(exp) {
+		return false
+	}
+	n.seen[nonce] = now.Add(n.ttl)
+	return true
+}
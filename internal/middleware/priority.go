@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const priorityKey = "priority"
+
+// PriorityLane herkent dringende events (bv. kritieke fouten) aan een header
+// zoals X-Priority: high, zodat ze voorrang krijgen op bulk imports. De
+// instellingen kunnen at runtime aangepast worden.
+type PriorityLane struct {
+	mu      sync.RWMutex
+	enabled bool
+	header  string
+	values  []string // lowercase
+}
+
+func NewPriorityLane(enabled bool, header string, values []string) *PriorityLane {
+	p := &PriorityLane{}
+	p.Update(enabled, header, values)
+	return p
+}
+
+func (p *PriorityLane) Update(enabled bool, header string, values []string) {
+	lower := make([]string, len(values))
+	for i, v := range values {
+		lower[i] = strings.ToLower(strings.TrimSpace(v))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enabled, p.header, p.values = enabled, header, lower
+}
+
+// Matches meldt of value (van de header of het priority veld van een event)
+// voorrang geeft; altijd false als de lane uit staat.
+func (p *PriorityLane) Matches(value string) bool {
+	if p == nil || value == "" {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.enabled && slices.Contains(p.values, strings.ToLower(strings.TrimSpace(value)))
+}
+
+// Handler markeert een request met een priority header, voor de
+// ConcurrencyLimiter en de handlers (zie IsPriority).
+func (p *PriorityLane) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p.mu.RLock()
+		header := p.header
+		p.mu.RUnlock()
+		if p.Matches(c.GetHeader(header)) {
+			c.Set(priorityKey, true)
+		}
+		c.Next()
+	}
+}
+
+// IsPriority meldt of de request een priority header heeft.
+func IsPriority(c *gin.Context) bool {
+	return c.GetBool(priorityKey)
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+const (
+	SessionCookie = "pulsar_session"
+	CSRFCookie    = "pulsar_csrf"
+	CSRFHeader    = "X-CSRF-Token"
+	sessionKey    = "uiSession"
+)
+
+// Sessions zijn de logins van de tester UI: POST /ui/login ruilt een API key
+// voor een HttpOnly session cookie, waarmee de UI dezelfde client identity en
+// scopes krijgt als met X-API-Key. De sessies zitten in het geheugen: na een
+// herstart of op een andere replica moet je opnieuw inloggen.
+//
+// Tegen CSRF is de cookie SameSite=Strict en moet een request met een
+// sessie, behalve GET en HEAD, de waarde van de CSRFCookie terugsturen in
+// X-CSRF-Token (double submit); een andere site kan die cookie niet lezen.
+type Sessions struct {
+	enabled bool
+	keys    *apiKeys
+	ttl     time.Duration
+	secure  bool // cookies enkel over HTTPS
+
+	mu       sync.Mutex
+	sessions map[[sha256.Size]byte]session // hash van de session cookie → sessie
+	nextGC   time.Time
+	nowFunc  func() time.Time
+}
+
+type session struct {
+	client  string
+	scopes  []string
+	csrf    string
+	expires time.Time
+}
+
+// NewSessions geeft de UI sessies; uitgeschakeld laat Identity alles
+// ongewijzigd door.
+func NewSessions(enabled bool, keys []APIKey, ttl time.Duration, secure bool) *Sessions {
+	return &Sessions{
+		enabled:  enabled,
+		keys:     newAPIKeys(keys),
+		ttl:      ttl,
+		secure:   secure,
+		sessions: make(map[[sha256.Size]byte]session),
+		nowFunc:  time.Now,
+	}
+}
+
+func (s *Sessions) Enabled() bool {
+	return s.enabled
+}
+
+type loginRequest struct {
+	APIKey string `json:"apiKey"`
+}
+
+type loginResponse struct {
+	Client    string    `json:"client"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Login (POST /ui/login, body {"apiKey": "..."}) start een sessie voor de
+// client van de key en zet de session en CSRF cookies; een onbekende key
+// geeft 401.
+func (s *Sessions) Login(c *gin.Context) {
+	corrID := GetCorrelationID(c)
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.APIKey == "" {
+		problem.Abort(c, http.StatusBadRequest, "body must be {\"apiKey\": \"...\"}", corrID)
+		return
+	}
+	k, ok := s.keys.match(req.APIKey)
+	if !ok {
+		problem.Abort(c, http.StatusUnauthorized, "invalid API key", corrID)
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, "could not start a session", corrID)
+		return
+	}
+	csrf, err := randomToken()
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, "could not start a session", corrID)
+		return
+	}
+
+	now := s.nowFunc()
+	expires := now.Add(s.ttl)
+	s.mu.Lock()
+	s.gc(now)
+	s.sessions[sha256.Sum256([]byte(token))] = session{client: k.Client, scopes: k.Scopes, csrf: csrf, expires: expires}
+	s.mu.Unlock()
+
+	maxAge := int(s.ttl / time.Second)
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, token, maxAge, "/", "", s.secure, true)
+	c.SetCookie(CSRFCookie, csrf, maxAge, "/", "", s.secure, false)
+	c.JSON(http.StatusOK, loginResponse{Client: k.Client, ExpiresAt: expires.UTC()})
+}
+
+// Logout (POST /ui/logout) beëindigt de sessie en wist de cookies.
+func (s *Sessions) Logout(c *gin.Context) {
+	if token, err := c.Cookie(SessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, sha256.Sum256([]byte(token)))
+		s.mu.Unlock()
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(SessionCookie, "", -1, "/", "", s.secure, true)
+	c.SetCookie(CSRFCookie, "", -1, "/", "", s.secure, false)
+	c.Status(http.StatusNoContent)
+}
+
+// Identity zet de client identity en scopes van een geldige session cookie.
+// Een request met X-API-Key, zonder cookie of met een verlopen sessie gaat
+// ongewijzigd door; een sessie zonder geldige X-CSRF-Token geeft 403.
+func (s *Sessions) Identity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.enabled || c.GetHeader(APIKeyHeader) != "" {
+			c.Next()
+			return
+		}
+		token, err := c.Cookie(SessionCookie)
+		if err != nil || token == "" {
+			c.Next()
+			return
+		}
+
+		now := s.nowFunc()
+		s.mu.Lock()
+		sess, ok := s.sessions[sha256.Sum256([]byte(token))]
+		s.mu.Unlock()
+		if !ok || now.After(sess.expires) {
+			c.Next()
+			return
+		}
+
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead &&
+			subtle.ConstantTimeCompare([]byte(c.GetHeader(CSRFHeader)), []byte(sess.csrf)) != 1 {
+			problem.Abort(c, http.StatusForbidden, "missing or invalid "+CSRFHeader+" header", GetCorrelationID(c))
+			return
+		}
+
+		SetClientID(c, sess.client)
+		c.Set(scopesKey, sess.scopes)
+		c.Set(sessionKey, true)
+		c.Next()
+	}
+}
+
+// HasSession: de client identity komt van een UI sessie.
+func HasSession(c *gin.Context) bool {
+	return c.GetBool(sessionKey)
+}
+
+// gc verwijdert de verlopen sessies, hoogstens eens per ttl; s.mu moet
+// gelockt zijn.
+func (s *Sessions) gc(now time.Time) {
+	if now.Before(s.nextGC) {
+		return
+	}
+	for k, sess := range s.sessions {
+		if now.After(sess.expires) {
+			delete(s.sessions, k)
+		}
+	}
+	s.nextGC = now.Add(s.ttl)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
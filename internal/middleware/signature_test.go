@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVerifySignatureSignedSourceSystem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/", VerifySignature(SignatureOptions{
+		Required: true,
+		Secrets:  map[string]string{"EverESSt": "secret"},
+		Window:   time.Minute,
+	}), func(c *gin.Context) {
+		c.String(http.StatusOK, GetSignedSourceSystem(c))
+	})
+
+	body := `{"sourceSystem":"EverESSt"}`
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set(SourceSystemHeader, "everesst")
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(NonceHeader, "n1")
+	req.Header.Set(SignatureHeader, signaturePrefix+hex.EncodeToString(computeSignature([]byte("secret"), ts, "n1", []byte(body))))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "everesst" {
+		t.Fatalf("got %d %q, want 200 with the signed X-Source-System", w.Code, w.Body.String())
+	}
+}
+
+func TestVerifySignatureRequiredWithSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := []APIKey{{Key: "k1", Client: "c1"}}
+
+	for _, allow := range []bool{false, true} {
+		sessions := NewSessions(true, keys, time.Hour, false)
+		r := gin.New()
+		r.POST("/ui/login", sessions.Login)
+		r.POST("/events", sessions.Identity(), VerifySignature(SignatureOptions{
+			Required:              true,
+			Window:                time.Minute,
+			AllowUnsignedSessions: allow,
+		}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/ui/login", strings.NewReader(`{"apiKey":"k1"}`)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("login: status = %d", w.Code)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader("{}"))
+		for _, ck := range w.Result().Cookies() {
+			req.AddCookie(ck)
+			if ck.Name == CSRFCookie {
+				req.Header.Set(CSRFHeader, ck.Value)
+			}
+		}
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		want := http.StatusUnauthorized
+		if allow {
+			want = http.StatusOK
+		}
+		if w.Code != want {
+			t.Errorf("allowUnsignedSessions=%v: status = %d, want %d", allow, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/problem"
+)
+
+// Timeout geeft elke request een deadline op de request context. Handlers en
+// de producer geven die context door, zodat een trage publish afgebroken wordt;
+// heeft de handler dan nog niets geschreven, dan antwoorden we met 504.
+func Timeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+			problem.Abort(c, http.StatusGatewayTimeout,
+				"request did not complete within "+d.String(), GetCorrelationID(c))
+		}
+	}
+}
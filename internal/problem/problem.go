@@ -0,0 +1,32 @@
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Details is een RFC 7807 problem response, aangevuld met het correlation ID.
+type Details struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail,omitempty"`
+	Instance      string `json:"instance,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+}
+
+// Abort stopt de request chain en schrijft een problem response.
+func Abort(c *gin.Context, status int, detail string, corrID string) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(status, Details{
+		Type:          "about:blank",
+		Title:         http.StatusText(status),
+		Status:        status,
+		Detail:        detail,
+		Instance:      c.Request.URL.Path,
+		CorrelationID: corrID,
+	})
+}
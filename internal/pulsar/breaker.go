@@ -0,0 +1,199 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen: de breaker is open, de send is niet geprobeerd. De fout
+// van Send is een *CircuitOpenError.
+var ErrCircuitOpen = errors.New("circuit open: pulsar unavailable")
+
+type CircuitOpenError struct {
+	RetryAfter time.Duration // tot de volgende proef-send
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%v, retry after %s", ErrCircuitOpen, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *CircuitOpenError) Is(target error) bool { return target == ErrCircuitOpen }
+
+// Circuit states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// BreakerOptions bepalen wanneer de breaker opengaat. Enkel fouten die op een
+// onbereikbare broker wijzen (IsUnavailable) tellen mee.
+type BreakerOptions struct {
+	Enabled             bool          `mapstructure:"enabled" json:"enabled"`
+	ConsecutiveFailures int           `mapstructure:"consecutiveFailures" json:"consecutiveFailures"` // 0 = niet gebruiken
+	ErrorRate           float64       `mapstructure:"errorRate" json:"errorRate"`                     // 0..1, 0 = niet gebruiken
+	MinRequests         int           `mapstructure:"minRequests" json:"minRequests"`                 // min. sends in window voor errorRate
+	Window              time.Duration `mapstructure:"window" json:"window"`
+	OpenTimeout         time.Duration `mapstructure:"openTimeout" json:"openTimeout"` // daarna één proef-send (half-open)
+}
+
+func (o BreakerOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	switch {
+	case o.ConsecutiveFailures < 0:
+		return fmt.Errorf("consecutiveFailures must not be negative")
+	case o.ErrorRate < 0 || o.ErrorRate > 1:
+		return fmt.Errorf("errorRate %v must be between 0 and 1", o.ErrorRate)
+	case o.ConsecutiveFailures == 0 && o.ErrorRate == 0:
+		return fmt.Errorf("set consecutiveFailures and/or errorRate")
+	case o.ErrorRate > 0 && o.Window <= 0:
+		return fmt.Errorf("window must be positive when errorRate is set")
+	case o.OpenTimeout <= 0:
+		return fmt.Errorf("openTimeout must be positive")
+	}
+	return nil
+}
+
+// Breaker is een Publisher die sends kortsluit (ErrCircuitOpen) zolang de
+// broker onbereikbaar lijkt, zodat requests niet elk op een timeout wachten
+// en zich opstapelen.
+type Breaker struct {
+	next Publisher
+
+	mu          sync.Mutex
+	opts        BreakerOptions
+	state       string
+	openedAt    time.Time
+	probing     bool // half-open: er loopt een proef-send
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+}
+
+func NewBreaker(next Publisher, opts BreakerOptions) *Breaker {
+	return &Breaker{next: next, opts: opts, state: CircuitClosed}
+}
+
+// Update past de opties aan (config reload); uitzetten sluit de breaker.
+func (b *Breaker) Update(opts BreakerOptions) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.opts = opts
+	if !opts.Enabled {
+		b.reset()
+	}
+}
+
+// State geeft de huidige state en, als hij open is, wanneer de volgende
+// proef-send mag.
+func (b *Breaker) State() (string, time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen {
+		retryAt := b.openedAt.Add(b.opts.OpenTimeout)
+		if !time.Now().Before(retryAt) {
+			return CircuitHalfOpen, time.Time{}
+		}
+		return b.state, retryAt
+	}
+	return b.state, time.Time{}
+}
+
+func (b *Breaker) Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error) {
+	if err := b.allow(); err != nil {
+		return "", err
+	}
+	id, err := b.next.Send(ctx, topic, msg, opts)
+	b.record(err)
+	return id, err
+}
+
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.opts.Enabled {
+		return nil
+	}
+	switch b.state {
+	case CircuitOpen:
+		if wait := b.opts.OpenTimeout - time.Since(b.openedAt); wait > 0 {
+			return &CircuitOpenError{RetryAfter: wait}
+		}
+		b.state = CircuitHalfOpen
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probing {
+			return &CircuitOpenError{RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.opts.Enabled {
+		return
+	}
+	failed := err != nil && IsUnavailable(err)
+
+	if b.state == CircuitHalfOpen {
+		b.probing = false
+		if failed {
+			b.open()
+		} else {
+			b.reset()
+			zap.L().Named("pulsar").Info("circuit closed, pulsar reachable again")
+		}
+		return
+	}
+
+	now := time.Now()
+	if b.opts.Window > 0 && now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+	if !failed {
+		b.consecutive = 0
+		return
+	}
+	b.failures++
+	b.consecutive++
+	if b.opts.ConsecutiveFailures > 0 && b.consecutive >= b.opts.ConsecutiveFailures {
+		b.open()
+		return
+	}
+	if b.opts.ErrorRate > 0 && b.requests >= b.opts.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.opts.ErrorRate {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	if b.state == CircuitClosed {
+		zap.L().Named("pulsar").Warn("circuit open, publishes are short-circuited",
+			zap.Int("consecutiveFailures", b.consecutive),
+			zap.Int("failures", b.failures),
+			zap.Int("requests", b.requests),
+			zap.Duration("openTimeout", b.opts.OpenTimeout),
+		)
+	}
+	b.state = CircuitOpen
+	b.openedAt = time.Now()
+}
+
+func (b *Breaker) reset() {
+	b.state = CircuitClosed
+	b.probing = false
+	b.consecutive, b.requests, b.failures = 0, 0, 0
+	b.windowStart = time.Now()
+}
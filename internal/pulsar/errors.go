@@ -0,0 +1,34 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+
+	pulsargo "github.com/apache/pulsar-client-go/pulsar"
+)
+
+// IsUnavailable zegt of err op een onbereikbare of overbelaste broker wijst
+// (geen verbinding, timeout, volle send queue), zodat het later opnieuw
+// proberen zin heeft. Fouten in het bericht, de topic of de autorisatie zijn
+// dat niet.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	var pe *pulsargo.Error
+	if !errors.As(err, &pe) {
+		return false
+	}
+	switch pe.Result() {
+	case pulsargo.TimeoutError,
+		pulsargo.LookupError,
+		pulsargo.ConnectError,
+		pulsargo.NotConnectedError,
+		pulsargo.TooManyLookupRequestException,
+		pulsargo.ServiceUnitNotReady,
+		pulsargo.ProducerQueueIsFull,
+		pulsargo.ClientMemoryBufferIsFull:
+		return true
+	}
+	return false
+}
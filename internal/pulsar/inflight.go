@@ -0,0 +1,114 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTooManyInFlight: er lopen al te veel sends, de send is niet geprobeerd.
+// De fout van Send is een *InFlightError.
+var ErrTooManyInFlight = errors.New("too many in-flight publishes")
+
+type InFlightError struct {
+	Topic string // leeg als de globale limiet bereikt is
+	Limit int
+}
+
+func (e *InFlightError) Error() string {
+	if e.Topic != "" {
+		return fmt.Sprintf("%v on topic %s (limit %d)", ErrTooManyInFlight, e.Topic, e.Limit)
+	}
+	return fmt.Sprintf("%v (limit %d)", ErrTooManyInFlight, e.Limit)
+}
+
+func (e *InFlightError) Is(target error) bool { return target == ErrTooManyInFlight }
+
+// InFlightLimits begrenzen het aantal sends dat tegelijk op een antwoord van
+// de broker wacht; 0 = onbeperkt.
+type InFlightLimits struct {
+	Max      int `mapstructure:"max" json:"max"`                     // over alle topics en clusters
+	PerTopic int `mapstructure:"perTopic" json:"perTopic"`           // per topic
+	Reserved int `mapstructure:"reserved" json:"reserved,omitempty"` // van Max, enkel voor priority sends
+}
+
+func (l InFlightLimits) Validate() error {
+	switch {
+	case l.Max < 0:
+		return fmt.Errorf("max must not be negative")
+	case l.PerTopic < 0:
+		return fmt.Errorf("perTopic must not be negative")
+	case l.Reserved < 0:
+		return fmt.Errorf("reserved must not be negative")
+	case l.Reserved > 0 && l.Reserved >= l.Max:
+		return fmt.Errorf("reserved must be less than max")
+	}
+	return nil
+}
+
+// InFlight is een Publisher die nieuwe sends weigert (ErrTooManyInFlight)
+// zolang er al te veel lopen, zodat een trage broker niet tot steeds meer
+// wachtende requests en geheugen leidt.
+type InFlight struct {
+	next Publisher
+
+	mu      sync.Mutex
+	limits  InFlightLimits
+	total   int
+	byTopic map[string]int
+}
+
+func NewInFlight(next Publisher, limits InFlightLimits) *InFlight {
+	return &InFlight{next: next, limits: limits, byTopic: map[string]int{}}
+}
+
+// Update past de limieten aan (config reload); lopende sends tellen mee.
+func (f *InFlight) Update(limits InFlightLimits) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.limits = limits
+}
+
+// Count geeft het aantal lopende sends.
+func (f *InFlight) Count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.total
+}
+
+func (f *InFlight) Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error) {
+	if err := f.acquire(topic, opts.Priority); err != nil {
+		return "", err
+	}
+	defer f.release(topic)
+	return f.next.Send(ctx, topic, msg, opts)
+}
+
+// acquire: een send zonder priority laat de Reserved plaatsen van Max vrij.
+func (f *InFlight) acquire(topic string, priority bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	limit := f.limits.Max
+	if !priority {
+		limit -= f.limits.Reserved
+	}
+	switch {
+	case f.limits.Max > 0 && f.total >= limit:
+		return &InFlightError{Limit: limit}
+	case f.limits.PerTopic > 0 && f.byTopic[topic] >= f.limits.PerTopic:
+		return &InFlightError{Topic: topic, Limit: f.limits.PerTopic}
+	}
+	f.total++
+	f.byTopic[topic]++
+	return nil
+}
+
+func (f *InFlight) release(topic string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.total--
+	if f.byTopic[topic]--; f.byTopic[topic] == 0 {
+		delete(f.byTopic, topic)
+	}
+}
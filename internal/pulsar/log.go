@@ -0,0 +1,56 @@
+package pulsar
+
+import (
+	"sort"
+
+	pulsarlog "github.com/apache/pulsar-client-go/pulsar/log"
+	"go.uber.org/zap"
+)
+
+// zapLogger stuurt de logs van pulsar-client-go (reconnects, producer state)
+// naar zap, zodat ze in dezelfde gestructureerde stream komen als de rest.
+// Implementeert zowel pulsarlog.Logger als pulsarlog.Entry.
+type zapLogger struct {
+	s *zap.SugaredLogger
+}
+
+// newLogger gebruikt de globale zap logger (zie logging.Init) met name
+// "pulsar"; het log level volgt dus ook logging.level.
+func newLogger() pulsarlog.Logger {
+	return zapLogger{s: zap.L().Named("pulsar").WithOptions(zap.AddCallerSkip(1)).Sugar()}
+}
+
+func (l zapLogger) with(fields pulsarlog.Fields) zapLogger {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys) // stabiele volgorde in de output
+	args := make([]interface{}, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, k, fields[k])
+	}
+	return zapLogger{s: l.s.With(args...)}
+}
+
+func (l zapLogger) SubLogger(fields pulsarlog.Fields) pulsarlog.Logger { return l.with(fields) }
+
+func (l zapLogger) WithFields(fields pulsarlog.Fields) pulsarlog.Entry { return l.with(fields) }
+
+func (l zapLogger) WithField(name string, value interface{}) pulsarlog.Entry {
+	return zapLogger{s: l.s.With(name, value)}
+}
+
+func (l zapLogger) WithError(err error) pulsarlog.Entry {
+	return zapLogger{s: l.s.With(zap.Error(err))}
+}
+
+func (l zapLogger) Debug(args ...interface{}) { l.s.Debug(args...) }
+func (l zapLogger) Info(args ...interface{})  { l.s.Info(args...) }
+func (l zapLogger) Warn(args ...interface{})  { l.s.Warn(args...) }
+func (l zapLogger) Error(args ...interface{}) { l.s.Error(args...) }
+
+func (l zapLogger) Debugf(format string, args ...interface{}) { l.s.Debugf(format, args...) }
+func (l zapLogger) Infof(format string, args ...interface{})  { l.s.Infof(format, args...) }
+func (l zapLogger) Warnf(format string, args ...interface{})  { l.s.Warnf(format, args...) }
+func (l zapLogger) Errorf(format string, args ...interface{}) { l.s.Errorf(format, args...) }
@@ -0,0 +1,76 @@
+package pulsar
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	pulsargo "github.com/apache/pulsar-client-go/pulsar"
+)
+
+// ProducerOptions zijn de producer instellingen per eventType (zie routes in
+// de config). De nulwaarde geeft de defaults van de Pulsar client; de struct
+// is vergelijkbaar met ==, zodat de Pool een gewijzigde producer herkent.
+type ProducerOptions struct {
+	Compression         string        `mapstructure:"compression" json:"compression,omitempty"` // none, lz4, zlib of zstd
+	DisableBatching     bool          `mapstructure:"disableBatching" json:"disableBatching,omitempty"`
+	BatchingMaxDelay    time.Duration `mapstructure:"batchingMaxDelay" json:"batchingMaxDelay,omitempty"`
+	BatchingMaxMessages uint          `mapstructure:"batchingMaxMessages" json:"batchingMaxMessages,omitempty"`
+	SendTimeout         time.Duration `mapstructure:"sendTimeout" json:"sendTimeout,omitempty"`
+	Schema              string        `mapstructure:"schema" json:"schema,omitempty"`                     // Pulsar schema: bytes, string of json
+	SchemaDefinition    string        `mapstructure:"schemaDefinition" json:"schemaDefinition,omitempty"` // Avro definitie (bestand) voor schema json
+}
+
+var compressionTypes = map[string]pulsargo.CompressionType{
+	"":     pulsargo.NoCompression,
+	"none": pulsargo.NoCompression,
+	"lz4":  pulsargo.LZ4,
+	"zlib": pulsargo.ZLib,
+	"zstd": pulsargo.ZSTD,
+}
+
+// Validate controleert de opties zonder een producer te maken.
+func (o ProducerOptions) Validate() error {
+	_, err := o.producerOptions("")
+	return err
+}
+
+func (o ProducerOptions) producerOptions(topic string) (pulsargo.ProducerOptions, error) {
+	opts := pulsargo.ProducerOptions{
+		Topic:                   topic,
+		DisableBatching:         o.DisableBatching,
+		BatchingMaxPublishDelay: o.BatchingMaxDelay,
+		BatchingMaxMessages:     o.BatchingMaxMessages,
+		SendTimeout:             o.SendTimeout,
+	}
+	compression, ok := compressionTypes[o.Compression]
+	if !ok {
+		return opts, fmt.Errorf("compression: unknown type %q (none, lz4, zlib or zstd)", o.Compression)
+	}
+	opts.CompressionType = compression
+	if o.BatchingMaxDelay < 0 || o.SendTimeout < 0 {
+		return opts, fmt.Errorf("batchingMaxDelay and sendTimeout must not be negative")
+	}
+
+	switch o.Schema {
+	case "", "bytes":
+	case "string":
+		opts.Schema = pulsargo.NewStringSchema(nil)
+	case "json":
+		if o.SchemaDefinition == "" {
+			return opts, fmt.Errorf("schemaDefinition: is required for schema json")
+		}
+		def, err := os.ReadFile(o.SchemaDefinition)
+		if err != nil {
+			return opts, fmt.Errorf("schemaDefinition: %w", err)
+		}
+		s, err := pulsargo.NewJSONSchemaWithValidation(string(def), nil)
+		if err != nil {
+			return opts, fmt.Errorf("schemaDefinition: %s: %w", o.SchemaDefinition, err)
+		}
+		opts.Schema = s
+	default:
+		return opts, fmt.Errorf("schema: unknown type %q (bytes, string or json)", o.Schema)
+	}
+	return opts, nil
+}
@@ -0,0 +1,297 @@
+package pulsar
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pulsargo "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Pool houdt één producer per topic bij, op één gedeelde client. Producers
+// worden bij de eerste send naar hun topic aangemaakt met de opties van die
+// topic (zie SetOptions).
+type Pool struct {
+	client    pulsargo.Client
+	mu        sync.Mutex
+	options   map[string]ProducerOptions // topic → opties, ontbrekend = defaults
+	provision *Provisioner               // nil = ontbrekende topics niet aanmaken
+	retry     RetryPolicy
+	producers map[string]*pooled
+	connected atomic.Bool // er is al eens een producer gemaakt
+	failing   atomic.Bool // de laatste poging faalde omdat de broker onbereikbaar is
+	sent      atomic.Int64
+	failed    atomic.Int64
+}
+
+// Verbindingsstates van een Pool, zie State.
+const (
+	PoolConnecting   = "connecting" // nog nooit verbonden
+	PoolConnected    = "connected"
+	PoolReconnecting = "reconnecting" // was verbonden, de broker is nu onbereikbaar
+)
+
+// pooled telt de lopende sends, zodat een vervangen producer pas sluit als
+// die klaar zijn. ready gaat dicht zodra de producer gemaakt is of dat
+// mislukte (err).
+type pooled struct {
+	*Producer
+	opts     ProducerOptions
+	inflight sync.WaitGroup
+	ready    chan struct{}
+	err      error
+	failedAt time.Time
+}
+
+// retryCreate: zo lang na een mislukte poging geeft een send meteen dezelfde
+// fout, i.p.v. elke request opnieuw op een onbereikbare broker te laten wachten.
+// maxConnectDelay is de langste wachttijd van KeepConnecting.
+const (
+	retryCreate     = 5 * time.Second
+	maxConnectDelay = time.Minute
+)
+
+// ClientMetrics: waar de client zijn metrics (pending messages, send errors,
+// verbindingen) registreert, per topic en met een cluster label.
+type ClientMetrics struct {
+	Registerer prometheus.Registerer // nil = de default registry van Prometheus
+	Cluster    string
+}
+
+// NewPool maakt de gedeelde client; er wordt nog geen verbinding gemaakt.
+// token levert het Pulsar auth token (nil = geen authenticatie); het wordt
+// bij elke (re)connect opnieuw opgevraagd, zodat een geroteerd token zonder
+// herstart gebruikt wordt. Een fout komt enkel van ongeldige opties (bv. de
+// URL), niet van een onbereikbare broker.
+func NewPool(brokerURL string, token func() (string, error), options map[string]ProducerOptions, retry RetryPolicy, metrics ClientMetrics) (*Pool, error) {
+	opts := pulsargo.ClientOptions{URL: brokerURL, MetricsRegisterer: metrics.Registerer}
+	if metrics.Registerer != nil {
+		opts.MetricsCardinality = pulsargo.MetricsCardinalityTopic
+		opts.CustomMetricsLabels = map[string]string{"cluster": metrics.Cluster}
+	}
+	client, err := newClient(opts, token)
+	if err != nil {
+		return nil, fmt.Errorf("create pulsar client: %w", err)
+	}
+	return &Pool{
+		client:    client,
+		options:   options,
+		retry:     retry,
+		producers: map[string]*pooled{},
+	}, nil
+}
+
+// SetProvisioner laat de pool een ontbrekende topic aanmaken vóór de eerste
+// producer erop; enkel bij het opstarten, vóór de eerste send.
+func (p *Pool) SetProvisioner(pv *Provisioner) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.provision = pv
+}
+
+// SetRetry zet de retry policy na een config reload.
+func (p *Pool) SetRetry(retry RetryPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.retry = retry
+}
+
+// SetOptions zet de opties per topic na een config reload. Producers waarvan
+// de opties wijzigen worden gesloten en bij de volgende send opnieuw gemaakt.
+func (p *Pool) SetOptions(options map[string]ProducerOptions) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.options = options
+	for topic, pr := range p.producers {
+		if pr.opts != p.optionsFor(topic) {
+			delete(p.producers, topic)
+			go pr.close()
+		}
+	}
+}
+
+// optionsFor geeft de opties van topic; die van een topic met {tenant}
+// gelden voor de topic van elke tenant. p.mu moet gelockt zijn.
+func (p *Pool) optionsFor(topic string) ProducerOptions {
+	if opts, ok := p.options[topic]; ok {
+		return opts
+	}
+	for pattern, opts := range p.options {
+		before, after, ok := strings.Cut(pattern, "{tenant}")
+		if ok && len(topic) > len(before)+len(after) && strings.HasPrefix(topic, before) && strings.HasSuffix(topic, after) &&
+			!strings.Contains(topic[len(before):len(topic)-len(after)], "/") {
+			return opts
+		}
+	}
+	return ProducerOptions{}
+}
+
+// Connect maakt de producer voor topic meteen, bv. om bij het opstarten te
+// controleren dat Pulsar bereikbaar is.
+func (p *Pool) Connect(ctx context.Context, topic string) error {
+	pr, err := p.acquire(ctx, topic)
+	if err != nil {
+		return err
+	}
+	pr.inflight.Done()
+	return nil
+}
+
+// State geeft de verbindingsstate op basis van de laatste send of producer.
+func (p *Pool) State() string {
+	switch {
+	case !p.connected.Load():
+		return PoolConnecting
+	case p.failing.Load():
+		return PoolReconnecting
+	}
+	return PoolConnected
+}
+
+// Stats geeft het aantal geslaagde en mislukte sends (na de retries) sinds
+// het opstarten.
+func (p *Pool) Stats() (sent, failed int64) {
+	return p.sent.Load(), p.failed.Load()
+}
+
+// observe houdt bij of de broker bereikbaar was.
+func (p *Pool) observe(err error) {
+	if err == nil {
+		p.failing.Store(false)
+	} else if IsUnavailable(err) {
+		p.failing.Store(true)
+	}
+}
+
+// KeepConnecting probeert Connect tot het lukt of ctx afloopt, met een
+// verdubbelende wachttijd. Zo kan de service starten terwijl de broker
+// (even) plat ligt, bv. tijdens een rolling restart.
+func (p *Pool) KeepConnecting(ctx context.Context, topic string, log *zap.Logger) {
+	delay := retryCreate
+	for attempt := 1; ; attempt++ {
+		err := p.Connect(ctx, topic)
+		if err == nil {
+			log.Info("Connected to Pulsar", zap.String("topic", topic), zap.Int("attempt", attempt))
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if IsUnavailable(err) {
+			log.Warn("Pulsar unavailable, retrying connect", zap.String("topic", topic), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		} else {
+			// bv. authenticatie of een onbekende topic: lost zichzelf
+			// misschien niet op, maar een crash loop ook niet
+			log.Error("Failed to create Pulsar producer, retrying", zap.String("topic", topic), zap.Int("attempt", attempt), zap.Duration("delay", delay), zap.Error(err))
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		delay = min(2*delay, maxConnectDelay)
+	}
+}
+
+// acquire geeft de producer voor topic en maakt hem zo nodig aan, zonder de
+// pool te blokkeren: sends naar andere topics en ctx lopen gewoon door.
+func (p *Pool) acquire(ctx context.Context, topic string) (*pooled, error) {
+	p.mu.Lock()
+	pr, ok := p.producers[topic]
+	if ok && pr.err != nil && time.Since(pr.failedAt) < retryCreate {
+		p.mu.Unlock()
+		return nil, pr.err
+	}
+	if !ok || pr.err != nil {
+		pr = &pooled{opts: p.optionsFor(topic), ready: make(chan struct{})}
+		p.producers[topic] = pr
+		go p.create(topic, pr)
+	}
+	pr.inflight.Add(1)
+	p.mu.Unlock()
+
+	select {
+	case <-pr.ready:
+		if pr.err != nil {
+			pr.inflight.Done()
+			return nil, pr.err
+		}
+		return pr, nil
+	case <-ctx.Done():
+		pr.inflight.Done()
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Pool) create(topic string, pr *pooled) {
+	p.mu.Lock()
+	provision := p.provision
+	p.mu.Unlock()
+	if provision != nil {
+		provision.ensure(topic, pr.opts)
+	}
+	producer, err := newProducer(p.client, topic, pr.opts)
+	if err == nil {
+		p.connected.Store(true)
+	}
+	p.observe(err)
+	p.mu.Lock()
+	pr.Producer, pr.err, pr.failedAt = producer, err, time.Now()
+	p.mu.Unlock()
+	close(pr.ready)
+}
+
+// close sluit de producer als de lopende sends klaar zijn.
+func (pr *pooled) close() {
+	pr.inflight.Wait()
+	<-pr.ready
+	if pr.Producer != nil {
+		pr.Producer.Close()
+	}
+}
+
+// Send publiceert msg op topic, zie Producer.Send, en probeert opnieuw
+// volgens de RetryPolicy.
+func (p *Pool) Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error) {
+	p.mu.Lock()
+	retry := p.retry
+	p.mu.Unlock()
+	id, err := retry.withRetry(ctx, topic, func() (MessageID, error) {
+		pr, err := p.acquire(ctx, topic)
+		if err != nil {
+			return "", err
+		}
+		defer pr.inflight.Done()
+		id, err := pr.Send(ctx, msg, opts.Key, opts.Properties)
+		if ctx.Err() == nil { // een afgelopen request zegt niets over de broker
+			p.observe(err)
+		}
+		return MessageID(id), err
+	})
+	if err != nil {
+		p.failed.Add(1)
+	} else {
+		p.sent.Add(1)
+	}
+	return id, err
+}
+
+// Close sluit alle producers en de client.
+func (p *Pool) Close() {
+	// niet onder mu wachten: create heeft mu nodig om klaar te komen
+	p.mu.Lock()
+	producers := p.producers
+	p.producers = map[string]*pooled{}
+	p.mu.Unlock()
+	for _, pr := range producers {
+		pr.close()
+	}
+	p.client.Close()
+}
@@ -2,49 +2,112 @@ package pulsar
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"time"
 
 	pulsargo "github.com/apache/pulsar-client-go/pulsar"
+	pulsarlog "github.com/apache/pulsar-client-go/pulsar/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/rubenclaes/pulsar-api/internal/tracing"
 )
 
+// CorrelationIDProperty is de message property met het correlation ID van de
+// oorspronkelijke HTTP request.
+const CorrelationIDProperty = "correlationId"
+
 type Producer struct {
-	client   pulsargo.Client
 	producer pulsargo.Producer
+	topic    string
 }
 
-func NewProducer(brokerURL, topic string) *Producer {
-	client, err := pulsargo.NewClient(pulsargo.ClientOptions{
-		URL: brokerURL,
-	})
-	if err != nil {
-		log.Fatalf("failed to create pulsar client: %v", err)
+func newClient(opts pulsargo.ClientOptions, token func() (string, error)) (pulsargo.Client, error) {
+	if opts.Logger == nil {
+		opts.Logger = newLogger()
+	}
+	if token != nil {
+		opts.Authentication = pulsargo.NewAuthenticationTokenFromSupplier(token)
 	}
+	return pulsargo.NewClient(opts)
+}
 
-	producer, err := client.CreateProducer(pulsargo.ProducerOptions{
-		Topic: topic,
-	})
+// Ping controleert dat de broker bereikbaar is en de topics kent (een lookup
+// per topic, dus ook authenticatie en autorisatie), binnen timeout.
+func Ping(brokerURL string, token func() (string, error), topics []string, timeout time.Duration) error {
+	client, err := newClient(pulsargo.ClientOptions{
+		URL:               brokerURL,
+		ConnectionTimeout: timeout,
+		OperationTimeout:  timeout,
+		Logger:            pulsarlog.DefaultNopLogger(),
+	}, token)
 	if err != nil {
-		log.Fatalf("failed to create pulsar producer: %v", err)
+		return err
 	}
+	defer client.Close()
+	for _, topic := range topics {
+		if _, err := client.TopicPartitions(topic); err != nil {
+			return fmt.Errorf("%s: %w", topic, err)
+		}
+	}
+	return nil
+}
 
+// newProducer maakt een producer op topic met een gedeelde client.
+func newProducer(client pulsargo.Client, topic string, o ProducerOptions) (*Producer, error) {
+	opts, err := o.producerOptions(topic)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := client.CreateProducer(opts)
+	if err != nil {
+		return nil, err
+	}
 	return &Producer{
-		client:   client,
 		producer: producer,
-	}
+		topic:    topic,
+	}, nil
 }
 
 // returns Pulsar message ID as string
-func (p *Producer) Send(msg []byte) (string, error) {
-	msgID, err := p.producer.Send(context.Background(), &pulsargo.ProducerMessage{
-		Payload: msg,
+// props gaan mee als message properties, aangevuld met de W3C trace context
+// (traceparent/tracestate) zodat consumers dezelfde trace kunnen verderzetten.
+// key is de message key, "" = geen.
+func (p *Producer) Send(ctx context.Context, msg []byte, key string, props map[string]string) (string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "pulsar.send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "pulsar"),
+			attribute.String("messaging.destination.name", p.topic),
+			attribute.Int("messaging.message.body.size", len(msg)),
+		),
+	)
+	defer span.End()
+
+	// trace context van de producer span injecteren, zonder props van de caller te wijzigen
+	properties := make(map[string]string, len(props)+2)
+	for k, v := range props {
+		properties[k] = v
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(properties))
+
+	msgID, err := p.producer.Send(ctx, &pulsargo.ProducerMessage{
+		Payload:    msg,
+		Key:        key,
+		Properties: properties,
 	})
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "send failed")
 		return "", err
 	}
+	span.SetAttributes(attribute.String("messaging.message.id", msgID.String()))
 	return msgID.String(), nil
 }
 
 func (p *Producer) Close() {
 	p.producer.Close()
-	p.client.Close()
 }
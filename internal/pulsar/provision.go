@@ -0,0 +1,263 @@
+package pulsar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ProvisionOptions: wat de gateway via de admin API aanmaakt als een topic
+// nog niet bestaat (pulsar.provision in de config).
+type ProvisionOptions struct {
+	Enabled          bool          `mapstructure:"enabled" json:"enabled"`
+	Namespaces       []string      `mapstructure:"namespaces" json:"namespaces,omitempty"`             // tenant/namespace die aangemaakt mogen worden, leeg = geen
+	AllowedClusters  []string      `mapstructure:"allowedClusters" json:"allowedClusters,omitempty"`   // Pulsar clusters van een nieuwe tenant, bv. [standalone]
+	Partitions       int           `mapstructure:"partitions" json:"partitions,omitempty"`             // van een nieuwe topic, 0 = niet gepartitioneerd
+	RetentionMinutes int           `mapstructure:"retentionMinutes" json:"retentionMinutes,omitempty"` // van een nieuwe namespace, -1 = oneindig, 0 = default van de broker
+	RetentionSizeMB  int           `mapstructure:"retentionSizeMB" json:"retentionSizeMB,omitempty"`   // idem
+	Timeout          time.Duration `mapstructure:"timeout" json:"timeout"`                             // per request naar de admin API
+}
+
+func (o ProvisionOptions) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	var errs []error
+	if len(o.Namespaces) > 0 && len(o.AllowedClusters) == 0 {
+		errs = append(errs, errors.New("allowedClusters is required to create tenants"))
+	}
+	for i, ns := range o.Namespaces {
+		if tenant, namespace, ok := strings.Cut(ns, "/"); !ok || tenant == "" || namespace == "" || strings.Contains(namespace, "/") {
+			errs = append(errs, fmt.Errorf("namespaces[%d]: %q must be tenant/namespace", i, ns))
+		}
+	}
+	if o.Partitions < 0 {
+		errs = append(errs, errors.New("partitions must not be negative"))
+	}
+	if o.RetentionMinutes < -1 || o.RetentionSizeMB < -1 {
+		errs = append(errs, errors.New("retentionMinutes and retentionSizeMB must be -1 (infinite) or more"))
+	}
+	if o.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// Provisioner maakt ontbrekende topics aan via de admin API van één
+// cluster, vóór de eerste producer op een topic, en hun tenant en namespace
+// als die in Namespaces staan. Een topic kan van de request komen ({tenant}):
+// andere namespaces maakt hij nooit aan, zodat een caller de gateway geen
+// willekeurige tenants laat maken. Een topic die al bestaat blijft
+// ongewijzigd, net als de retention van een bestaande namespace.
+type Provisioner struct {
+	adminURL string
+	token    func() (string, error) // nil = zonder auth
+	opts     ProvisionOptions
+	client   *http.Client
+	log      *zap.Logger
+
+	mu   sync.Mutex
+	done map[string]bool // topics die bestaan
+}
+
+func NewProvisioner(adminURL string, token func() (string, error), opts ProvisionOptions, log *zap.Logger) *Provisioner {
+	return &Provisioner{
+		adminURL: strings.TrimSuffix(adminURL, "/"),
+		token:    token,
+		opts:     opts,
+		client:   &http.Client{Timeout: opts.Timeout},
+		log:      log,
+		done:     map[string]bool{},
+	}
+}
+
+// errMissingNamespace: de tenant of namespace van de topic bestaat niet.
+var errMissingNamespace = errors.New("tenant or namespace does not exist")
+
+// Ensure zorgt dat topic bestaat, met het schema uit schema (de producer
+// opties van de topic) als hij nieuw is.
+func (p *Provisioner) Ensure(ctx context.Context, topic string, schema ProducerOptions) error {
+	p.mu.Lock()
+	done := p.done[topic]
+	p.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	t := parseTopic(topic)
+	created, err := p.createTopic(ctx, t)
+	if errors.Is(err, errMissingNamespace) {
+		if err := p.createNamespace(ctx, t); err != nil {
+			return err
+		}
+		created, err = p.createTopic(ctx, t)
+	}
+	if err != nil {
+		return err
+	}
+	if created {
+		if err := p.uploadSchema(ctx, t, schema); err != nil {
+			return err
+		}
+		p.log.Info("Provisioned Pulsar topic", zap.String("topic", topic), zap.Int("partitions", p.opts.Partitions))
+	}
+	p.mu.Lock()
+	p.done[topic] = true
+	p.mu.Unlock()
+	return nil
+}
+
+// ensure is Ensure voor de Pool: een mislukte provisioning wordt gelogd en
+// de producer toch geprobeerd, want de topic kan al bestaan.
+func (p *Provisioner) ensure(topic string, schema ProducerOptions) {
+	if err := p.Ensure(context.Background(), topic, schema); err != nil {
+		p.log.Warn("Failed to provision Pulsar topic", zap.String("topic", topic), zap.Error(err))
+	}
+}
+
+// adminTopic is een topic opgesplitst zoals in de admin API.
+type adminTopic struct {
+	domain, tenant, namespace, name string
+}
+
+// parseTopic splitst persistent://tenant/ns/topic; een korte naam ligt in
+// public/default, zoals bij de Pulsar client.
+func parseTopic(topic string) adminTopic {
+	t := adminTopic{domain: "persistent", tenant: "public", namespace: "default", name: topic}
+	if domain, rest, ok := strings.Cut(topic, "://"); ok {
+		t.domain = domain
+		if parts := strings.SplitN(rest, "/", 3); len(parts) == 3 {
+			t.tenant, t.namespace, t.name = parts[0], parts[1], parts[2]
+		}
+	}
+	return t
+}
+
+func (t adminTopic) path() string {
+	return url.PathEscape(t.tenant) + "/" + url.PathEscape(t.namespace) + "/" + url.PathEscape(t.name)
+}
+
+// createTopic maakt de topic aan; created is false als hij al bestond. Een
+// niet gepartitioneerde non-persistent topic maakt de broker zelf.
+func (p *Provisioner) createTopic(ctx context.Context, t adminTopic) (created bool, err error) {
+	path := "/admin/v2/" + t.domain + "/" + t.path()
+	var body interface{}
+	switch {
+	case p.opts.Partitions > 0:
+		path += "/partitions"
+		body = p.opts.Partitions
+	case t.domain != "persistent":
+		return false, nil
+	}
+	status, err := p.do(ctx, http.MethodPut, path, body)
+	switch {
+	case err != nil:
+		return false, err
+	case status == http.StatusConflict:
+		return false, nil
+	case status == http.StatusNotFound:
+		return false, errMissingNamespace
+	}
+	return true, nil
+}
+
+// errNamespaceNotAllowed: de namespace staat niet in Namespaces.
+var errNamespaceNotAllowed = errors.New("namespace is not in pulsar.provision.namespaces")
+
+// createNamespace maakt de tenant en de namespace aan als ze ontbreken en in
+// Namespaces staan, en zet de retention van een nieuwe namespace.
+func (p *Provisioner) createNamespace(ctx context.Context, t adminTopic) error {
+	if !slices.Contains(p.opts.Namespaces, t.tenant+"/"+t.namespace) {
+		return fmt.Errorf("%w: %s/%s", errNamespaceNotAllowed, t.tenant, t.namespace)
+	}
+	tenant := map[string]interface{}{"adminRoles": []string{}, "allowedClusters": p.opts.AllowedClusters}
+	if _, err := p.do(ctx, http.MethodPut, "/admin/v2/tenants/"+url.PathEscape(t.tenant), tenant); err != nil {
+		return err
+	}
+	ns := "/admin/v2/namespaces/" + url.PathEscape(t.tenant) + "/" + url.PathEscape(t.namespace)
+	status, err := p.do(ctx, http.MethodPut, ns, map[string]interface{}{})
+	switch {
+	case err != nil || status == http.StatusConflict:
+		return err
+	case status == http.StatusNotFound:
+		return fmt.Errorf("PUT %s: tenant %s does not exist", ns, t.tenant)
+	}
+	p.log.Info("Provisioned Pulsar namespace", zap.String("namespace", t.tenant+"/"+t.namespace))
+	if p.opts.RetentionMinutes == 0 && p.opts.RetentionSizeMB == 0 {
+		return nil
+	}
+	retention := map[string]int{"retentionTimeInMinutes": p.opts.RetentionMinutes, "retentionSizeInMB": p.opts.RetentionSizeMB}
+	_, err = p.do(ctx, http.MethodPost, ns+"/retention", retention)
+	return err
+}
+
+// uploadSchema registreert het schema van de producer opties (string of
+// json) op een nieuwe topic, ook als de namespace geen automatische schema
+// updates toelaat.
+func (p *Provisioner) uploadSchema(ctx context.Context, t adminTopic, o ProducerOptions) error {
+	body := map[string]interface{}{"properties": map[string]string{}}
+	switch o.Schema {
+	case "string":
+		body["type"], body["schema"] = "STRING", ""
+	case "json":
+		def, err := os.ReadFile(o.SchemaDefinition)
+		if err != nil {
+			return fmt.Errorf("schemaDefinition: %w", err)
+		}
+		body["type"], body["schema"] = "JSON", string(def)
+	default:
+		return nil
+	}
+	_, err := p.do(ctx, http.MethodPost, "/admin/v2/schemas/"+t.path()+"/schema", body)
+	return err
+}
+
+// do stuurt een request naar de admin API en geeft de status; een andere
+// status dan 2xx, 404 (bestaat niet) of 409 (bestaat al) is een fout.
+func (p *Provisioner) do(ctx context.Context, method, path string, body interface{}) (int, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.adminURL+path, r)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != nil {
+		token, err := p.token()
+		if err != nil {
+			return 0, fmt.Errorf("auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300,
+		resp.StatusCode == http.StatusNotFound,
+		resp.StatusCode == http.StatusConflict:
+		return resp.StatusCode, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+	return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,63 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestProvisionerOnlyConfiguredNamespaces(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+		created  = map[string]bool{}
+	)
+	admin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.URL.Path {
+		case "/admin/v2/persistent/acerta/payroll/wage-errors", "/admin/v2/persistent/evil/ns/topic":
+			if !created[r.URL.Path] {
+				created[r.URL.Path] = true
+				w.WriteHeader(http.StatusNotFound) // de namespace ontbreekt
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer admin.Close()
+
+	p := NewProvisioner(admin.URL, nil, ProvisionOptions{
+		Enabled:         true,
+		Namespaces:      []string{"acerta/payroll"},
+		AllowedClusters: []string{"standalone"},
+		Timeout:         time.Second,
+	}, zap.NewNop())
+
+	if err := p.Ensure(context.Background(), "persistent://acerta/payroll/wage-errors", ProducerOptions{}); err != nil {
+		t.Fatalf("Ensure(configured namespace) = %v", err)
+	}
+	err := p.Ensure(context.Background(), "persistent://evil/ns/topic", ProducerOptions{})
+	if !errors.Is(err, errNamespaceNotAllowed) {
+		t.Errorf("Ensure(other namespace) = %v, want %v", err, errNamespaceNotAllowed)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Contains(requests, "PUT /admin/v2/tenants/acerta") {
+		t.Errorf("configured tenant was not created: %v", requests)
+	}
+	for _, req := range requests {
+		if req == "PUT /admin/v2/tenants/evil" || req == "PUT /admin/v2/namespaces/evil/ns" {
+			t.Errorf("unconfigured namespace was created: %s", req)
+		}
+	}
+}
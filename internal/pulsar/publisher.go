@@ -0,0 +1,48 @@
+package pulsar
+
+import (
+	"context"
+	"fmt"
+)
+
+// MessageID is het Pulsar message ID in tekstvorm (ledger:entry:partition).
+type MessageID string
+
+// SendOptions zijn de opties per bericht.
+type SendOptions struct {
+	Properties map[string]string // message properties, aangevuld met de trace context
+	Cluster    string            // enkel voor Clusters, leeg = DefaultCluster
+	Key        string            // message key (bv. voor Key_Shared subscriptions), leeg = geen
+	Priority   bool              // priority lane: mag de gereserveerde plaatsen van InFlight gebruiken
+}
+
+// Publisher publiceert een bericht op een topic. Pool is de Pulsar
+// implementatie; pulsartest.Publisher houdt de berichten in het geheugen bij,
+// zodat de handlers zonder broker te testen zijn.
+type Publisher interface {
+	Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error)
+}
+
+var (
+	_ Publisher = (*Pool)(nil)
+	_ Publisher = Clusters(nil)
+)
+
+// DefaultCluster is de naam van de cluster uit pulsar.url.
+const DefaultCluster = "default"
+
+// Clusters is een Publisher die elk bericht naar de Publisher van
+// SendOptions.Cluster stuurt; elke cluster heeft een eigen client.
+type Clusters map[string]Publisher
+
+func (c Clusters) Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error) {
+	name := opts.Cluster
+	if name == "" {
+		name = DefaultCluster
+	}
+	p, ok := c[name]
+	if !ok {
+		return "", fmt.Errorf("unknown pulsar cluster %q", name)
+	}
+	return p.Send(ctx, topic, msg, opts)
+}
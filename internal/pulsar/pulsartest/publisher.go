@@ -0,0 +1,91 @@
+// Package pulsartest bevat een in-memory pulsar.Publisher voor tests.
+package pulsartest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+)
+
+// Message is een bericht zoals het naar Pulsar gestuurd zou zijn.
+type Message struct {
+	ID         pulsar.MessageID
+	Topic      string
+	Cluster    string
+	Key        string
+	Payload    []byte
+	Properties map[string]string
+}
+
+// Publisher houdt de verstuurde berichten bij in plaats van ze te publiceren.
+type Publisher struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+	topicErr map[string]error // topic → fout, zie FailTopic
+}
+
+func NewPublisher() *Publisher {
+	return &Publisher{}
+}
+
+// Send bewaart het bericht, of geeft de fout van FailWith of FailTopic (of
+// van een afgelopen context) terug.
+func (p *Publisher) Send(ctx context.Context, topic string, msg []byte, opts pulsar.SendOptions) (pulsar.MessageID, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return "", p.err
+	}
+	if err := p.topicErr[topic]; err != nil {
+		return "", err
+	}
+	id := pulsar.MessageID(fmt.Sprintf("%d:0:-1", len(p.messages)))
+	p.messages = append(p.messages, Message{
+		ID:         id,
+		Topic:      topic,
+		Cluster:    opts.Cluster,
+		Key:        opts.Key,
+		Payload:    append([]byte(nil), msg...),
+		Properties: maps.Clone(opts.Properties),
+	})
+	return id, nil
+}
+
+// FailWith laat volgende sends falen met err; nil publiceert weer.
+func (p *Publisher) FailWith(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// FailTopic laat volgende sends naar topic falen met err, bv. om een
+// fallback topic te testen; nil publiceert weer.
+func (p *Publisher) FailTopic(topic string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.topicErr == nil {
+		p.topicErr = map[string]error{}
+	}
+	p.topicErr[topic] = err
+}
+
+// Messages geeft de verstuurde berichten, in volgorde.
+func (p *Publisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+// Reset vergeet de verstuurde berichten.
+func (p *Publisher) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = nil
+}
@@ -0,0 +1,80 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	pulsargo "github.com/apache/pulsar-client-go/pulsar"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy bepaalt hoe vaak de Pool een send opnieuw probeert bij een
+// tijdelijke fout (zie retryable). Tussen twee pogingen wacht hij BaseDelay,
+// telkens verdubbeld tot MaxDelay, min. een willekeurige fractie Jitter
+// daarvan, zodat callers niet allemaal tegelijk terugkomen.
+type RetryPolicy struct {
+	Attempts  int           `mapstructure:"attempts" json:"attempts"` // totaal, 1 = niet opnieuw proberen
+	BaseDelay time.Duration `mapstructure:"baseDelay" json:"baseDelay"`
+	MaxDelay  time.Duration `mapstructure:"maxDelay" json:"maxDelay"`
+	Jitter    float64       `mapstructure:"jitter" json:"jitter"` // 0..1
+}
+
+func (r RetryPolicy) Validate() error {
+	switch {
+	case r.Attempts < 1:
+		return fmt.Errorf("attempts must be at least 1")
+	case r.BaseDelay < 0 || r.MaxDelay < 0:
+		return fmt.Errorf("baseDelay and maxDelay must not be negative")
+	case r.MaxDelay < r.BaseDelay:
+		return fmt.Errorf("maxDelay (%s) must not be smaller than baseDelay (%s)", r.MaxDelay, r.BaseDelay)
+	case r.Jitter < 0 || r.Jitter > 1:
+		return fmt.Errorf("jitter %v must be between 0 and 1", r.Jitter)
+	}
+	return nil
+}
+
+// delay is de wachttijd na poging attempt (1 = de eerste).
+func (r RetryPolicy) delay(attempt int) time.Duration {
+	d := r.BaseDelay << (attempt - 1)
+	if d > r.MaxDelay || d <= 0 { // <= 0: overflow
+		d = r.MaxDelay
+	}
+	return d - time.Duration(r.Jitter*rand.Float64()*float64(d))
+}
+
+// retryable: de broker is (even) onbereikbaar of overbelast, of de producer
+// werd net gesloten door een config reload. Een afgelopen request context
+// is dat niet meer: de caller wacht niet langer.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return IsUnavailable(err) || errors.Is(err, pulsargo.ErrProducerClosed)
+}
+
+// withRetry voert send uit volgens de policy.
+func (r RetryPolicy) withRetry(ctx context.Context, topic string, send func() (MessageID, error)) (MessageID, error) {
+	for attempt := 1; ; attempt++ {
+		id, err := send()
+		if err == nil || attempt >= r.Attempts || !retryable(ctx, err) {
+			return id, err
+		}
+		d := r.delay(attempt)
+		zap.L().Named("pulsar").Warn("send failed, retrying",
+			zap.String("topic", topic),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", d),
+			zap.Error(err),
+		)
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package pulsar
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ShadowOptions: elk gepubliceerd bericht ook naar een tweede cluster
+// sturen, bv. om een migratie naar een nieuwe Pulsar omgeving te valideren.
+type ShadowOptions struct {
+	Enabled   bool          `mapstructure:"enabled" json:"enabled"`
+	Cluster   string        `mapstructure:"cluster" json:"cluster"`     // uit pulsar.clusters
+	QueueSize int           `mapstructure:"queueSize" json:"queueSize"` // wachtende kopieën, daarboven gedropt
+	Timeout   time.Duration `mapstructure:"timeout" json:"timeout"`     // per send naar de shadow cluster
+}
+
+const shadowWorkers = 4
+
+// ShadowStats tellen de kopieën; Failed en Dropped zijn berichten die op de
+// primaire cluster staan maar niet op de shadow cluster (divergentie).
+type ShadowStats struct {
+	Enabled   bool       `json:"enabled"`
+	Cluster   string     `json:"cluster,omitempty"`
+	Queued    int        `json:"queued"`
+	Mirrored  int64      `json:"mirrored"`
+	Failed    int64      `json:"failed"`
+	Dropped   int64      `json:"dropped"`
+	LastError string     `json:"lastError,omitempty"`
+	FailedAt  *time.Time `json:"failedAt,omitempty"`
+}
+
+type shadowMessage struct {
+	ctx   context.Context // enkel de trace van het origineel
+	topic string
+	msg   []byte
+	opts  SendOptions
+}
+
+// Shadow is een Publisher die na elke geslaagde send een kopie van het
+// bericht asynchroon naar de shadow cluster stuurt. Best-effort: een
+// mislukte of gedropte kopie heeft geen invloed op de response, enkel op de
+// ShadowStats. Berichten die al naar de shadow cluster gaan, worden niet
+// gekopieerd.
+type Shadow struct {
+	next   Clusters
+	queue  chan shadowMessage
+	wg     sync.WaitGroup
+	log    *zap.Logger
+	closed chan struct{}
+
+	mu      sync.RWMutex
+	opts    ShadowOptions
+	topics  map[string]string // primaire topic → shadow topic, ontbrekend = dezelfde naam
+	lastErr string
+	failAt  time.Time
+	failing bool
+
+	mirrored, failed, dropped atomic.Int64
+}
+
+// NewShadow start de workers; QueueSize wordt enkel hier gelezen.
+func NewShadow(next Clusters, opts ShadowOptions, topics map[string]string, log *zap.Logger) *Shadow {
+	s := &Shadow{
+		next:   next,
+		queue:  make(chan shadowMessage, max(opts.QueueSize, 1)),
+		log:    log,
+		closed: make(chan struct{}),
+	}
+	s.Update(opts, topics)
+	for range shadowWorkers {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+// Update past de opties en topics aan (config reload); QueueSize niet.
+func (s *Shadow) Update(opts ShadowOptions, topics map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opts = opts
+	s.topics = topics
+}
+
+func (s *Shadow) Send(ctx context.Context, topic string, msg []byte, opts SendOptions) (MessageID, error) {
+	id, err := s.next.Send(ctx, topic, msg, opts)
+	if err != nil {
+		return id, err
+	}
+
+	s.mu.RLock()
+	shadow := s.opts
+	shadowTopic, ok := s.topics[topic]
+	s.mu.RUnlock()
+	if !shadow.Enabled || clusterName(opts) == shadow.Cluster {
+		return id, nil
+	}
+	if !ok {
+		shadowTopic = topic
+	}
+
+	// msg en de properties zijn van de caller (msg kan een buffer uit een pool zijn)
+	m := shadowMessage{
+		ctx:   trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx)),
+		topic: shadowTopic,
+		msg:   append([]byte(nil), msg...),
+		opts:  SendOptions{Properties: maps.Clone(opts.Properties), Cluster: shadow.Cluster},
+	}
+	select {
+	case s.queue <- m:
+	default:
+		s.dropped.Add(1)
+	}
+	return id, nil
+}
+
+func clusterName(opts SendOptions) string {
+	if opts.Cluster == "" {
+		return DefaultCluster
+	}
+	return opts.Cluster
+}
+
+func (s *Shadow) work() {
+	defer s.wg.Done()
+	for {
+		select {
+		case m := <-s.queue:
+			s.mirror(m)
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *Shadow) mirror(m shadowMessage) {
+	s.mu.RLock()
+	timeout := s.opts.Timeout
+	s.mu.RUnlock()
+	ctx := m.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	_, err := s.next.Send(ctx, m.topic, m.msg, m.opts)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failed.Add(1)
+		s.lastErr, s.failAt = err.Error(), time.Now().UTC()
+		if !s.failing {
+			s.failing = true
+			s.log.Warn("shadow publish failed, cluster is diverging",
+				zap.String("cluster", m.opts.Cluster), zap.String("topic", m.topic), zap.Error(err))
+		}
+		return
+	}
+	s.mirrored.Add(1)
+	if s.failing {
+		s.failing = false
+		s.log.Info("shadow publish succeeded again", zap.String("cluster", m.opts.Cluster))
+	}
+}
+
+// Queued geeft het aantal kopieën dat nog verstuurd moet worden.
+func (s *Shadow) Queued() int {
+	return len(s.queue)
+}
+
+func (s *Shadow) Stats() ShadowStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	st := ShadowStats{
+		Enabled:   s.opts.Enabled,
+		Queued:    len(s.queue),
+		Mirrored:  s.mirrored.Load(),
+		Failed:    s.failed.Load(),
+		Dropped:   s.dropped.Load(),
+		LastError: s.lastErr,
+	}
+	if !s.failAt.IsZero() {
+		failAt := s.failAt
+		st.FailedAt = &failAt
+	}
+	if st.Enabled {
+		st.Cluster = s.opts.Cluster
+	}
+	return st
+}
+
+// Close stopt de workers; kopieën die nog wachten, gaan verloren (ze zijn
+// best-effort). Vóór de producers sluiten.
+func (s *Shadow) Close() {
+	close(s.closed)
+	s.wg.Wait()
+}
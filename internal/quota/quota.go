@@ -0,0 +1,205 @@
+package quota
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits per client; 0 betekent onbeperkt.
+type Limits struct {
+	HourlyEvents int64 `mapstructure:"hourlyEvents" json:"hourlyEvents"`
+	DailyEvents  int64 `mapstructure:"dailyEvents" json:"dailyEvents"`
+	HourlyBytes  int64 `mapstructure:"hourlyBytes" json:"hourlyBytes"`
+	DailyBytes   int64 `mapstructure:"dailyBytes" json:"dailyBytes"`
+}
+
+// Window is het verbruik binnen één vast tijdvenster (uur of dag, UTC).
+type Window struct {
+	Start       time.Time `json:"start"`
+	Reset       time.Time `json:"reset"`
+	Events      int64     `json:"events"`
+	Bytes       int64     `json:"bytes"`
+	EventsLimit int64     `json:"eventsLimit"`
+	BytesLimit  int64     `json:"bytesLimit"`
+}
+
+type Usage struct {
+	Client string `json:"client"`
+	Hourly Window `json:"hourly"`
+	Daily  Window `json:"daily"`
+}
+
+// ExceededError geeft aan welk quotum overschreden zou worden.
+type ExceededError struct {
+	Window     string // "hourly" of "daily"
+	Unit       string // "events" of "bytes"
+	Limit      int64
+	RetryAfter time.Duration
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s %s quota of %d exceeded", e.Window, e.Unit, e.Limit)
+}
+
+type counters struct {
+	hourStart, dayStart   time.Time
+	hourEvents, hourBytes int64
+	dayEvents, dayBytes   int64
+}
+
+// Tracker houdt het verbruik per client in het geheugen bij.
+type Tracker struct {
+	mu       sync.Mutex
+	defaults Limits
+	clients  map[string]Limits    // lowercased client → limits
+	usage    map[string]*counters // lowercased client → verbruik
+	now      func() time.Time
+}
+
+func New(defaults Limits, clients map[string]Limits) *Tracker {
+	t := &Tracker{
+		usage: make(map[string]*counters),
+		now:   time.Now,
+	}
+	t.SetLimits(defaults, clients)
+	return t
+}
+
+// SetLimits vervangt de limieten (bv. na een config reload); het verbruik
+// binnen de lopende vensters blijft behouden.
+func (t *Tracker) SetLimits(defaults Limits, clients map[string]Limits) {
+	lower := make(map[string]Limits, len(clients))
+	for k, v := range clients {
+		lower[strings.ToLower(k)] = v // viper lowercased map keys
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.defaults = defaults
+	t.clients = lower
+}
+
+func (t *Tracker) limits(client string) Limits {
+	if l, ok := t.clients[strings.ToLower(client)]; ok {
+		return l
+	}
+	return t.defaults
+}
+
+// counters geeft de (zo nodig gerolde) tellers voor client terug; mu moet
+// vast zijn. Net als de limieten hoofdletterongevoelig, zodat "EverESSt" en
+// "everesst" samen tellen.
+func (t *Tracker) counters(client string) *counters {
+	now := t.now().UTC()
+	hour, day := now.Truncate(time.Hour), now.Truncate(24*time.Hour)
+
+	key := strings.ToLower(client)
+	c, ok := t.usage[key]
+	if !ok {
+		c = &counters{hourStart: hour, dayStart: day}
+		t.usage[key] = c
+	}
+	if !c.hourStart.Equal(hour) {
+		c.hourStart, c.hourEvents, c.hourBytes = hour, 0, 0
+	}
+	if !c.dayStart.Equal(day) {
+		c.dayStart, c.dayEvents, c.dayBytes = day, 0, 0
+	}
+	return c
+}
+
+// Reserve boekt één event van size bytes op client, of geeft een
+// *ExceededError terug zonder iets te boeken.
+func (t *Tracker) Reserve(client string, size int) error {
+	return t.ReserveBatch(client, 1, size)
+}
+
+// ReserveBatch boekt events events van samen size bytes in één keer, of
+// niets: voor een atomic batch die volledig binnen de quota moet passen.
+// Elk event wordt daarna apart vrijgegeven met Release.
+func (t *Tracker) ReserveBatch(client string, events, size int) error {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l := t.limits(client)
+	c := t.counters(client)
+	now := t.now().UTC()
+	e, n := int64(events), int64(size)
+
+	checks := []struct {
+		window, unit string
+		used, limit  int64
+		reset        time.Time
+	}{
+		{"hourly", "events", c.hourEvents + e, l.HourlyEvents, c.hourStart.Add(time.Hour)},
+		{"hourly", "bytes", c.hourBytes + n, l.HourlyBytes, c.hourStart.Add(time.Hour)},
+		{"daily", "events", c.dayEvents + e, l.DailyEvents, c.dayStart.Add(24 * time.Hour)},
+		{"daily", "bytes", c.dayBytes + n, l.DailyBytes, c.dayStart.Add(24 * time.Hour)},
+	}
+	for _, chk := range checks {
+		if chk.limit > 0 && chk.used > chk.limit {
+			return &ExceededError{
+				Window:     chk.window,
+				Unit:       chk.unit,
+				Limit:      chk.limit,
+				RetryAfter: chk.reset.Sub(now),
+			}
+		}
+	}
+
+	c.hourEvents += e
+	c.dayEvents += e
+	c.hourBytes += n
+	c.dayBytes += n
+	return nil
+}
+
+// Release draait een Reserve terug, bv. als de publish zelf mislukte.
+func (t *Tracker) Release(client string, size int) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.counters(client)
+	n := int64(size)
+	c.hourEvents = max(c.hourEvents-1, 0)
+	c.dayEvents = max(c.dayEvents-1, 0)
+	c.hourBytes = max(c.hourBytes-n, 0)
+	c.dayBytes = max(c.dayBytes-n, 0)
+}
+
+func (t *Tracker) Usage(client string) Usage {
+	u := Usage{Client: client}
+	if t == nil {
+		return u
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l := t.limits(client)
+	c := t.counters(client)
+	u.Hourly = Window{
+		Start:       c.hourStart,
+		Reset:       c.hourStart.Add(time.Hour),
+		Events:      c.hourEvents,
+		Bytes:       c.hourBytes,
+		EventsLimit: l.HourlyEvents,
+		BytesLimit:  l.HourlyBytes,
+	}
+	u.Daily = Window{
+		Start:       c.dayStart,
+		Reset:       c.dayStart.Add(24 * time.Hour),
+		Events:      c.dayEvents,
+		Bytes:       c.dayBytes,
+		EventsLimit: l.DailyEvents,
+		BytesLimit:  l.DailyBytes,
+	}
+	return u
+}
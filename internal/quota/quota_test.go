@@ -0,0 +1,17 @@
+package quota
+
+import "testing"
+
+func TestUsageIsCaseInsensitive(t *testing.T) {
+	tr := New(Limits{}, map[string]Limits{"EverESSt": {HourlyEvents: 1}})
+
+	if err := tr.Reserve("EverESSt", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Reserve("everesst", 10); err == nil {
+		t.Error("second event with another casing was not counted against the quota")
+	}
+	if u := tr.Usage("EVERESST"); u.Hourly.Events != 1 || u.Hourly.Bytes != 10 {
+		t.Errorf("usage = %+v, want 1 event of 10 bytes", u.Hourly)
+	}
+}
@@ -0,0 +1,197 @@
+// Package recent houdt de laatst aanvaarde events in het geheugen bij, om te
+// troubleshooten zonder externe store. Na een herstart is de lijst leeg.
+package recent
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxSubscribers begrenst het aantal open Subscribe's (live tails).
+const MaxSubscribers = 100
+
+// subscriberBuffer: zoveel events mag een subscriber achterlopen voor hij
+// events mist.
+const subscriberBuffer = 64
+
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// Event is een aanvaard event; de payload is geredacteerd.
+type Event struct {
+	Seq           uint64                 `json:"seq"` // oplopend per Add, sinds de start
+	Time          time.Time              `json:"time"`
+	CorrelationID string                 `json:"correlationId"`
+	ClientID      string                 `json:"clientId,omitempty"`
+	EventType     string                 `json:"eventType"`
+	SourceSystem  string                 `json:"sourceSystem"`
+	Topic         string                 `json:"topic"`
+	Status        string                 `json:"status"` // sent, fallback, dry-run of spooled
+	MessageID     string                 `json:"messageId,omitempty"`
+	EventID       string                 `json:"eventId,omitempty"` // envelope.enabled
+	Bytes         int                    `json:"bytes"`
+	Payload       map[string]interface{} `json:"payload,omitempty"`
+}
+
+// Filter beperkt List; lege velden filteren niet.
+type Filter struct {
+	ClientID      string // exact, "" = enkel events zonder client
+	EventType     string // hoofdletters maken niet uit
+	Status        string
+	CorrelationID string
+	After         uint64 // enkel events met een hogere Seq
+	Before        uint64 // enkel events met een lagere Seq, 0 = alles
+	Limit         int    // 0 = alles
+}
+
+func (f Filter) match(e Event) bool {
+	return e.Seq > f.After && (f.Before == 0 || e.Seq < f.Before) && f.ClientID == e.ClientID &&
+		(f.EventType == "" || strings.EqualFold(f.EventType, e.EventType)) &&
+		(f.Status == "" || f.Status == e.Status) &&
+		(f.CorrelationID == "" || f.CorrelationID == e.CorrelationID)
+}
+
+// Buffer is een ring buffer met de laatste size events.
+type Buffer struct {
+	mu     sync.RWMutex
+	events []Event
+	next   int // waar het volgende event komt
+	full   bool
+	seq    uint64
+
+	subs   map[*Subscription]struct{}
+	closed bool // na Close geen subscribers meer
+}
+
+func New(size int) *Buffer {
+	return &Buffer{events: make([]Event, max(size, 1)), subs: map[*Subscription]struct{}{}}
+}
+
+// Resize past de grootte aan (config reload) en houdt de laatste events.
+func (b *Buffer) Resize(size int) {
+	size = max(size, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if size == len(b.events) {
+		return
+	}
+	kept := b.newestFirst(size)
+	b.events = make([]Event, size)
+	for i, e := range kept {
+		b.events[len(kept)-1-i] = e
+	}
+	b.next = len(kept) % size
+	b.full = len(kept) == size
+}
+
+// Add bewaart e (met de volgende Seq) en geeft het aan de subscribers.
+func (b *Buffer) Add(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	e.Seq = b.seq
+	for sub := range b.subs {
+		if sub.filter.match(e) {
+			sub.send(e)
+		}
+	}
+	b.events[b.next] = e
+	b.next = (b.next + 1) % len(b.events)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// List geeft de events die aan f voldoen, nieuwste eerst.
+func (b *Buffer) List(f Filter) []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := []Event{}
+	for _, e := range b.newestFirst(len(b.events)) {
+		if !f.match(e) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// newestFirst geeft max. n events, nieuwste eerst; b.mu moet gelockt zijn.
+func (b *Buffer) newestFirst(n int) []Event {
+	count := b.next
+	if b.full {
+		count = len(b.events)
+	}
+	n = min(n, count)
+	out := make([]Event, 0, n)
+	for i := range n {
+		out = append(out, b.events[(b.next-1-i+len(b.events))%len(b.events)])
+	}
+	return out
+}
+
+// Subscription krijgt de nieuwe events die aan zijn filter voldoen, tot
+// Cancel of Close van de Buffer; dan wordt Events gesloten.
+type Subscription struct {
+	Events <-chan Event
+
+	b      *Buffer
+	ch     chan Event
+	filter Filter
+	missed atomic.Int64
+}
+
+// Subscribe start een Subscription voor f (Limit telt niet); een trage
+// subscriber mist events (zie Missed) in plaats van Add te blokkeren. Na
+// Close of met MaxSubscribers open geeft het ErrTooManySubscribers.
+func (b *Buffer) Subscribe(f Filter) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed || len(b.subs) >= MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: ch, b: b, ch: ch, filter: f}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// send zonder te blokkeren; b.mu moet gelockt zijn.
+func (s *Subscription) send(e Event) {
+	select {
+	case s.ch <- e:
+	default:
+		s.missed.Add(1)
+	}
+}
+
+// Missed is het aantal events dat de subscriber tot nu toe miste.
+func (s *Subscription) Missed() int64 {
+	return s.missed.Load()
+}
+
+func (s *Subscription) Cancel() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if _, ok := s.b.subs[s]; ok {
+		delete(s.b.subs, s)
+		close(s.ch)
+	}
+}
+
+// Close beëindigt alle subscriptions, bv. bij het afsluiten, zodat open live
+// tails de shutdown niet ophouden.
+func (b *Buffer) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
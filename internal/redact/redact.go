@@ -0,0 +1,232 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+)
+
+const (
+	Mask = "***"
+
+	// AllEventTypes is de config key voor paden die voor elk eventType gelden.
+	AllEventTypes = "*"
+)
+
+// Acties voor een gemaskeerd veld; leeg is ActionMask.
+const (
+	ActionMask = "mask" // waarde vervangen door Mask
+	ActionHash = "hash" // waarde vervangen door "hmac-sha256:<hex>", zodat je nog kan correleren
+	ActionDrop = "drop" // veld weglaten
+)
+
+// Rule is één pad met de actie die erop toegepast wordt. In de config mag een
+// rule ook gewoon het pad zijn (actie mask).
+type Rule struct {
+	Path   string `mapstructure:"path"`
+	Action string `mapstructure:"action"`
+}
+
+func (r Rule) Validate() error {
+	if len(fieldpath.Split(r.Path)) == 0 {
+		return fmt.Errorf("path is required")
+	}
+	switch r.Action {
+	case "", ActionMask, ActionHash, ActionDrop:
+		return nil
+	}
+	return fmt.Errorf("unknown action %q (mask, hash or drop)", r.Action)
+}
+
+type rule struct {
+	path   []string
+	action string
+}
+
+// Redactor maskeert payload velden per eventType voor logs, audit output en
+// de echo van het event in responses. Paden zijn relatief t.o.v. de payload,
+// met punten als scheiding (bv. "wage.gross"); arrays onderweg worden element
+// per element gevolgd.
+type Redactor struct {
+	rules   map[string][]rule // lowercased eventType → rules
+	hashKey []byte            // HMAC key van ActionHash
+
+	mu         sync.RWMutex
+	typePrefix string // cloudEvents.typePrefix, volgt een config reload
+}
+
+// New maakt een Redactor; hashKey is de (geheime) HMAC key voor
+// ActionHash, zodat een waarde met weinig mogelijke waarden zoals een
+// rijksregisternummer niet terug te rekenen is zonder de key.
+func New(rules map[string][]Rule, hashKey []byte) *Redactor {
+	r := &Redactor{rules: make(map[string][]rule, len(rules)), hashKey: hashKey}
+	for eventType, list := range rules {
+		key := strings.ToLower(eventType) // viper lowercased map keys
+		for _, rl := range list {
+			if path := fieldpath.Split(rl.Path); path != nil {
+				r.rules[key] = append(r.rules[key], rule{path: path, action: rl.Action})
+			}
+		}
+	}
+	return r
+}
+
+// SetCloudEventsTypePrefix zet de typePrefix waarmee Events het eventType
+// van een CloudEvent bepaalt (ook bij een config reload).
+func (r *Redactor) SetCloudEventsTypePrefix(prefix string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.typePrefix = prefix
+}
+
+// CloudEventType geeft het eventType van een CloudEvents type: zonder prefix,
+// in hoofdletters en met "_" voor "-" en ".". False als type niet met prefix
+// begint.
+func CloudEventType(typ, prefix string) (string, bool) {
+	name, found := strings.CutPrefix(typ, prefix)
+	if !found || name == "" {
+		return "", false
+	}
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name)), true
+}
+
+// Payload geeft een kopie van de payload terug met de geconfigureerde velden
+// gemaskeerd, gehasht of weggelaten. Het origineel (dat naar Pulsar gaat)
+// blijft onaangeroerd.
+func (r *Redactor) Payload(eventType string, payload map[string]interface{}) map[string]interface{} {
+	if r == nil || payload == nil {
+		return payload
+	}
+	all, own := r.rules[AllEventTypes], r.rules[strings.ToLower(eventType)]
+	if len(all)+len(own) == 0 {
+		return payload
+	}
+
+	out := copyMap(payload)
+	for _, rl := range all {
+		out = r.applyPath(out, rl.path, rl.action, false)
+	}
+	for _, rl := range own {
+		out = r.applyPath(out, rl.path, rl.action, false)
+	}
+	return out
+}
+
+// applyPath past de actie toe op één pad; maps worden gekopieerd vóór ze
+// aangepast worden zodat de originele payload niet wijzigt.
+func (r *Redactor) applyPath(m map[string]interface{}, path []string, action string, mustCopy bool) map[string]interface{} {
+	v, ok := m[path[0]]
+	if !ok {
+		return m
+	}
+	if mustCopy {
+		m = copyMap(m)
+	}
+	if len(path) == 1 {
+		switch action {
+		case ActionDrop:
+			delete(m, path[0])
+		case ActionHash:
+			m[path[0]] = r.hash(v)
+		default:
+			m[path[0]] = Mask
+		}
+		return m
+	}
+	m[path[0]] = r.applyValue(v, path[1:], action)
+	return m
+}
+
+func (r *Redactor) applyValue(v interface{}, path []string, action string) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return r.applyPath(t, path, action, true)
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, el := range t {
+			out[i] = r.applyValue(el, path, action)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// hash geeft de HMAC-SHA256 met hashKey van een string rechtstreeks (zodat
+// je met de key de hash van een gekende waarde zelf kan berekenen) en van
+// andere waarden als JSON.
+func (r *Redactor) hash(v interface{}) string {
+	b, ok := v.(string)
+	data := []byte(b)
+	if !ok {
+		data, _ = json.Marshal(v)
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write(data)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// Events maskeert de payload van elk event (een object met eventType en
+// payload, of een CloudEvent met type en data) in een willekeurige JSON
+// structuur, bv. een request body, een batch of een response met een echo
+// van het event. Geeft een kopie terug.
+func (r *Redactor) Events(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := copyMap(t)
+		eventType, payloadKey := r.eventOf(t)
+		for k, el := range t {
+			if payload, ok := el.(map[string]interface{}); ok && k == payloadKey {
+				out[k] = r.Payload(eventType, payload)
+				continue
+			}
+			out[k] = r.Events(el)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, el := range t {
+			out[i] = r.Events(el)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// eventOf herkent een event en geeft zijn eventType en het veld met de
+// payload, anders "", "". Van een CloudEvent met een type buiten de
+// typePrefix is het eventType niet gekend: enkel de regels voor alle
+// eventTypes gelden dan.
+func (r *Redactor) eventOf(m map[string]interface{}) (eventType, payloadKey string) {
+	if t, ok := m["eventType"].(string); ok {
+		return t, "payload"
+	}
+	_, isCloudEvent := m["specversion"]
+	typ, ok := m["type"].(string)
+	if !isCloudEvent || !ok || r == nil {
+		return "", ""
+	}
+	r.mu.RLock()
+	prefix := r.typePrefix
+	r.mu.RUnlock()
+	eventType, _ = CloudEventType(typ, prefix)
+	return eventType, "data"
+}
@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEventsCloudEvents(t *testing.T) {
+	r := New(map[string][]Rule{
+		"WAGE_ERROR":  {{Path: "iban"}},
+		AllEventTypes: {{Path: "nationalNumber", Action: ActionDrop}},
+	}, nil)
+	r.SetCloudEventsTypePrefix("be.acerta.payroll.")
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			"event",
+			`{"eventType":"WAGE_ERROR","payload":{"iban":"BE68539007547034"}}`,
+			`{"eventType":"WAGE_ERROR","payload":{"iban":"***"}}`,
+		},
+		{
+			"cloud event",
+			`{"specversion":"1.0","type":"be.acerta.payroll.wage-error","data":{"iban":"BE68539007547034","nationalNumber":"85073003328"}}`,
+			`{"data":{"iban":"***"},"specversion":"1.0","type":"be.acerta.payroll.wage-error"}`,
+		},
+		{
+			"cloud events batch",
+			`[{"specversion":"1.0","type":"be.acerta.payroll.wage-error","data":{"iban":"BE68539007547034"}},{"specversion":"1.0","type":"be.acerta.payroll.leave-error","data":{"iban":"BE68539007547034"}}]`,
+			`[{"data":{"iban":"***"},"specversion":"1.0","type":"be.acerta.payroll.wage-error"},{"data":{"iban":"BE68539007547034"},"specversion":"1.0","type":"be.acerta.payroll.leave-error"}]`,
+		},
+		{
+			// een type buiten de prefix: enkel de regels voor alle eventTypes
+			"cloud event with another prefix",
+			`{"specversion":"1.0","type":"com.example.wage-error","data":{"iban":"BE68539007547034","nationalNumber":"85073003328"}}`,
+			`{"data":{"iban":"BE68539007547034"},"specversion":"1.0","type":"com.example.wage-error"}`,
+		},
+		{
+			// data zonder specversion is geen CloudEvent
+			"not an event",
+			`{"type":"wage-error","data":{"nationalNumber":"85073003328"}}`,
+			`{"data":{"nationalNumber":"85073003328"},"type":"wage-error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			if err := json.Unmarshal([]byte(tt.body), &v); err != nil {
+				t.Fatal(err)
+			}
+			got, _ := json.Marshal(r.Events(v))
+			if string(got) != tt.want {
+				t.Errorf("Events = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloudEventType(t *testing.T) {
+	if got, ok := CloudEventType("be.acerta.payroll.wage-error.v2", "be.acerta.payroll."); !ok || got != "WAGE_ERROR_V2" {
+		t.Errorf("CloudEventType = %q, %v", got, ok)
+	}
+	if _, ok := CloudEventType("be.acerta.payroll.", "be.acerta.payroll."); ok {
+		t.Error("CloudEventType accepts a type without a name")
+	}
+}
+
+func TestHashIsKeyed(t *testing.T) {
+	rules := map[string][]Rule{"WAGE_ERROR": {{Path: "nationalNumber", Action: ActionHash}}}
+	payload := map[string]interface{}{"nationalNumber": "85073003328"}
+	hashed := func(key string) string {
+		return New(rules, []byte(key)).Payload("WAGE_ERROR", payload)["nationalNumber"].(string)
+	}
+
+	a := hashed("key-a")
+	if !strings.HasPrefix(a, "hmac-sha256:") || a != hashed("key-a") {
+		t.Fatalf("hash = %s, want a stable hmac-sha256", a)
+	}
+	if a == hashed("key-b") {
+		t.Error("hash does not depend on the key")
+	}
+	// zonder de key niet terug te rekenen met een gewone sha256
+	plain := sha256.Sum256([]byte("85073003328"))
+	if strings.HasSuffix(a, hex.EncodeToString(plain[:])) {
+		t.Error("hash is an unkeyed sha256")
+	}
+}
@@ -0,0 +1,349 @@
+// Package routing kiest per event de topic, de message key en extra message
+// properties met geordende regels uit de config. Een regel past op het
+// eventType, het sourceSystem, velden van de payload en headers van de
+// request; de eerste regel die past beslist, anders geldt de route van het
+// eventType.
+package routing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+)
+
+// Rule is één routing regel. Topic, Key en Properties zijn de acties; wat
+// leeg is, komt van de route van het eventType.
+type Rule struct {
+	Name     string `mapstructure:"name"`     // in logs en traces, leeg = routing[<index>]
+	Priority int    `mapstructure:"priority"` // hoger = eerst geëvalueerd, gelijk = volgorde in de config
+	Match    Match  `mapstructure:"match"`
+
+	Topic      string     `mapstructure:"topic"`      // mag {tenant} bevatten, leeg = de topic van de route
+	Key        string     `mapstructure:"key"`        // message key met {pad} uit de payload, bv. {employerId}
+	Properties []Property `mapstructure:"properties"` // extra message properties
+
+	FanOut []string `mapstructure:"fanOut"` // extra topics die een kopie krijgen, bv. een firehose; mogen {tenant} bevatten
+}
+
+// Match bevat de voorwaarden van een regel; ze moeten allemaal kloppen, een
+// lege lijst klopt altijd.
+type Match struct {
+	EventTypes    []string          `mapstructure:"eventTypes"`    // case-insensitief
+	SourceSystems []string          `mapstructure:"sourceSystems"` // case-insensitief
+	Fields        []FieldCondition  `mapstructure:"fields"`
+	Headers       []HeaderCondition `mapstructure:"headers"`
+}
+
+// FieldCondition verwijst naar een veld van de payload (een pad met punten,
+// zoals bij rules): het moet Equals zijn, met Prefix beginnen, één van In
+// zijn, op Pattern passen of (met Present) al dan niet aanwezig zijn. Een
+// getal wordt als tekst vergeleken, bv. prefix "9" past op errorCode 9001.
+// Bij een array onderweg volstaat één element.
+type FieldCondition struct {
+	Field   string        `mapstructure:"field"`
+	Equals  interface{}   `mapstructure:"equals"`
+	Prefix  string        `mapstructure:"prefix"`
+	In      []interface{} `mapstructure:"in"`
+	Pattern string        `mapstructure:"pattern"`
+	Present *bool         `mapstructure:"present"`
+}
+
+// HeaderCondition verwijst naar een header van de request, zoals
+// FieldCondition naar een veld.
+type HeaderCondition struct {
+	Name    string   `mapstructure:"name"`
+	Equals  string   `mapstructure:"equals"`
+	Prefix  string   `mapstructure:"prefix"`
+	In      []string `mapstructure:"in"`
+	Pattern string   `mapstructure:"pattern"`
+	Present *bool    `mapstructure:"present"`
+}
+
+// Property is een message property; de naam staat in de waarde omdat viper
+// map keys lowercased.
+type Property struct {
+	Name  string `mapstructure:"name"`
+	Value string `mapstructure:"value"` // met {pad} uit de payload
+}
+
+var (
+	placeholder = regexp.MustCompile(`\{([^{}]+)\}`)
+	headerName  = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+)
+
+func (r Rule) Validate() error {
+	var errs []error
+	for i, et := range r.Match.EventTypes {
+		if strings.TrimSpace(et) == "" {
+			errs = append(errs, fmt.Errorf("match.eventTypes[%d] must not be empty", i))
+		}
+	}
+	for i, s := range r.Match.SourceSystems {
+		if strings.TrimSpace(s) == "" {
+			errs = append(errs, fmt.Errorf("match.sourceSystems[%d] must not be empty", i))
+		}
+	}
+	for i, f := range r.Match.Fields {
+		if len(fieldpath.Split(f.Field)) == 0 {
+			errs = append(errs, fmt.Errorf("match.fields[%d].field is required", i))
+		}
+		if err := checkCondition(f.Pattern, f.Equals != nil, f.Prefix != "", len(f.In) > 0, f.Present != nil); err != nil {
+			errs = append(errs, fmt.Errorf("match.fields[%d]: %w", i, err))
+		}
+	}
+	for i, h := range r.Match.Headers {
+		if !headerName.MatchString(h.Name) {
+			errs = append(errs, fmt.Errorf("match.headers[%d].name %q is not a valid header name", i, h.Name))
+		}
+		if err := checkCondition(h.Pattern, h.Equals != "", h.Prefix != "", len(h.In) > 0, h.Present != nil); err != nil {
+			errs = append(errs, fmt.Errorf("match.headers[%d]: %w", i, err))
+		}
+	}
+	if r.Topic == "" && r.Key == "" && len(r.Properties) == 0 && len(r.FanOut) == 0 {
+		errs = append(errs, errors.New("needs at least one of topic, key, properties or fanOut"))
+	}
+	for i, t := range r.FanOut {
+		switch {
+		case strings.TrimSpace(t) == "":
+			errs = append(errs, fmt.Errorf("fanOut[%d] must not be empty", i))
+		case t == r.Topic || slices.Index(r.FanOut, t) < i:
+			errs = append(errs, fmt.Errorf("fanOut[%d]: duplicate topic %q", i, t))
+		}
+	}
+	if r.Key != "" && !placeholder.MatchString(r.Key) {
+		errs = append(errs, fmt.Errorf("key %q needs at least one {field} from the payload", r.Key))
+	}
+	seen := map[string]bool{}
+	for i, p := range r.Properties {
+		switch {
+		case strings.TrimSpace(p.Name) == "":
+			errs = append(errs, fmt.Errorf("properties[%d].name is required", i))
+		case seen[p.Name]:
+			errs = append(errs, fmt.Errorf("properties[%d]: duplicate property %q", i, p.Name))
+		}
+		seen[p.Name] = true
+	}
+	return errors.Join(errs...)
+}
+
+// checkCondition: een voorwaarde heeft precies één van equals, prefix, in,
+// pattern of present; set zegt welke van de andere dan pattern gezet zijn.
+func checkCondition(pattern string, set ...bool) error {
+	n := 0
+	if pattern != "" {
+		n++
+	}
+	for _, s := range set {
+		if s {
+			n++
+		}
+	}
+	if n != 1 {
+		return errors.New("needs exactly one of equals, prefix, in, pattern or present")
+	}
+	if pattern != "" {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+	}
+	return nil
+}
+
+// Event is wat een regel van een event te zien krijgt.
+type Event struct {
+	EventType    string
+	SourceSystem string
+	Payload      map[string]interface{}
+	Header       http.Header
+}
+
+// Decision is het resultaat van de regel die paste.
+type Decision struct {
+	Rule       string            // Name, of routing[<index>]
+	Topic      string            // "" = de topic van de route
+	Key        string            // "" = geen key
+	Properties map[string]string // nil = geen extra properties
+	FanOut     []string          // extra topics, zie Rule.FanOut
+}
+
+type condition struct {
+	path    []string // veld
+	header  string   // of header
+	equals  *string
+	prefix  string
+	in      []string
+	pattern *regexp.Regexp
+	present *bool
+}
+
+type rule struct {
+	Rule
+	name          string
+	eventTypes    []string
+	sourceSystems []string
+	conditions    []condition
+}
+
+// Rules bevat de regels in de volgorde waarin ze geëvalueerd worden.
+type Rules struct {
+	rules []rule
+}
+
+// New compileert de regels en sorteert ze op priority; ongeldige regels
+// (zie Rule.Validate, dat de config validatie al doet) worden overgeslagen.
+func New(rules []Rule) *Rules {
+	rs := &Rules{}
+	for i, r := range rules {
+		if r.Validate() != nil {
+			continue
+		}
+		compiled := rule{Rule: r, name: r.Name}
+		if compiled.name == "" {
+			compiled.name = fmt.Sprintf("routing[%d]", i)
+		}
+		for _, et := range r.Match.EventTypes {
+			compiled.eventTypes = append(compiled.eventTypes, strings.ToLower(strings.TrimSpace(et)))
+		}
+		for _, s := range r.Match.SourceSystems {
+			compiled.sourceSystems = append(compiled.sourceSystems, strings.ToLower(strings.TrimSpace(s)))
+		}
+		for _, f := range r.Match.Fields {
+			c := condition{path: fieldpath.Split(f.Field), prefix: f.Prefix, present: f.Present}
+			if f.Equals != nil {
+				want := text(f.Equals)
+				c.equals = &want
+			}
+			for _, v := range f.In {
+				c.in = append(c.in, text(v))
+			}
+			if f.Pattern != "" {
+				c.pattern = regexp.MustCompile(f.Pattern)
+			}
+			compiled.conditions = append(compiled.conditions, c)
+		}
+		for _, h := range r.Match.Headers {
+			c := condition{header: h.Name, prefix: h.Prefix, in: h.In, present: h.Present}
+			if h.Equals != "" {
+				c.equals = &h.Equals
+			}
+			if h.Pattern != "" {
+				c.pattern = regexp.MustCompile(h.Pattern)
+			}
+			compiled.conditions = append(compiled.conditions, c)
+		}
+		rs.rules = append(rs.rules, compiled)
+	}
+	slices.SortStableFunc(rs.rules, func(a, b rule) int { return b.Priority - a.Priority })
+	return rs
+}
+
+// Route evalueert de regels op e; false als er geen past. Een {pad} in de
+// key of een property dat in de payload ontbreekt, laat die key of property
+// weg.
+func (rs *Rules) Route(e Event) (Decision, bool) {
+	if rs == nil {
+		return Decision{}, false
+	}
+	for _, r := range rs.rules {
+		if !r.matches(e) {
+			continue
+		}
+		d := Decision{Rule: r.name, Topic: r.Topic, FanOut: r.FanOut}
+		if key, ok := fill(r.Key, e.Payload); ok {
+			d.Key = key
+		}
+		for _, p := range r.Properties {
+			if v, ok := fill(p.Value, e.Payload); ok {
+				if d.Properties == nil {
+					d.Properties = make(map[string]string, len(r.Properties))
+				}
+				d.Properties[p.Name] = v
+			}
+		}
+		return d, true
+	}
+	return Decision{}, false
+}
+
+func (r rule) matches(e Event) bool {
+	if len(r.eventTypes) > 0 && !slices.Contains(r.eventTypes, strings.ToLower(e.EventType)) {
+		return false
+	}
+	if len(r.sourceSystems) > 0 && !slices.Contains(r.sourceSystems, strings.ToLower(e.SourceSystem)) {
+		return false
+	}
+	for _, c := range r.conditions {
+		if !c.holds(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds meldt of de voorwaarde klopt voor e.
+func (c condition) holds(e Event) bool {
+	var values []string
+	if c.header != "" {
+		values = e.Header.Values(c.header)
+	} else {
+		var found []interface{}
+		fieldpath.Lookup(e.Payload, c.path, &found)
+		for _, v := range found {
+			values = append(values, text(v))
+		}
+	}
+	if c.present != nil {
+		return (len(values) > 0) == *c.present
+	}
+	for _, v := range values {
+		switch {
+		case c.equals != nil && v == *c.equals,
+			c.prefix != "" && strings.HasPrefix(v, c.prefix),
+			c.in != nil && slices.Contains(c.in, v),
+			c.pattern != nil && c.pattern.MatchString(v):
+			return true
+		}
+	}
+	return false
+}
+
+// fill vult de {pad}s in template in met waarden uit payload (zonder
+// arrays); false als er een ontbreekt of null is.
+func fill(template string, payload map[string]interface{}) (string, bool) {
+	if template == "" {
+		return "", false
+	}
+	missing := false
+	s := placeholder.ReplaceAllStringFunc(template, func(m string) string {
+		var v interface{} = payload
+		for _, key := range fieldpath.Split(m[1 : len(m)-1]) {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = obj[key]
+		}
+		if v == nil {
+			missing = true
+			return ""
+		}
+		return text(v)
+	})
+	return s, !missing
+}
+
+// text geeft een JSON waarde als string; getallen zonder exponent, zodat een
+// id als 12345678 niet 1.2345678e+07 wordt.
+func text(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
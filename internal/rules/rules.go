@@ -0,0 +1,199 @@
+// Package rules controleert payloads met eenvoudige regels uit de config
+// (verplichte velden, regex, numerieke grenzen, voorwaarden op een ander
+// veld), voor eventTypes waar een volledig JSON Schema te veel is.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+	"github.com/rubenclaes/pulsar-api/internal/schema"
+)
+
+// AllEventTypes is de config key voor regels die voor elk eventType gelden.
+const AllEventTypes = "*"
+
+// Rule is één controle op een veld van de payload. Field is een pad met
+// punten (bv. "employee.ssn"); een array onderweg wordt element per element
+// gevolgd.
+type Rule struct {
+	Field    string     `mapstructure:"field"`
+	Required bool       `mapstructure:"required"`
+	Pattern  string     `mapstructure:"pattern"` // regex voor een string waarde
+	Min      *float64   `mapstructure:"min"`
+	Max      *float64   `mapstructure:"max"`
+	When     *Condition `mapstructure:"when"`    // de regel geldt enkel als de conditie klopt
+	Message  string     `mapstructure:"message"` // i.p.v. de standaard foutboodschap
+}
+
+// Condition verwijst naar een ander veld: het moet Equals zijn, of (met
+// Present) al dan niet aanwezig zijn.
+type Condition struct {
+	Field   string      `mapstructure:"field"`
+	Equals  interface{} `mapstructure:"equals"`
+	Present *bool       `mapstructure:"present"`
+}
+
+func (r Rule) Validate() error {
+	var errs []error
+	if len(fieldpath.Split(r.Field)) == 0 {
+		errs = append(errs, errors.New("field is required"))
+	}
+	if r.Pattern != "" {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			errs = append(errs, fmt.Errorf("pattern: %w", err))
+		}
+	}
+	if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+		errs = append(errs, fmt.Errorf("min %v is larger than max %v", *r.Min, *r.Max))
+	}
+	if !r.Required && r.Pattern == "" && r.Min == nil && r.Max == nil {
+		errs = append(errs, errors.New("needs at least one of required, pattern, min or max"))
+	}
+	if c := r.When; c != nil {
+		if len(fieldpath.Split(c.Field)) == 0 {
+			errs = append(errs, errors.New("when.field is required"))
+		}
+		if (c.Equals == nil) == (c.Present == nil) {
+			errs = append(errs, errors.New("when needs either equals or present"))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type rule struct {
+	Rule
+	path    []string
+	pattern *regexp.Regexp
+	when    []string
+}
+
+// Set bevat de regels per eventType (lowercase).
+type Set struct {
+	rules map[string][]rule
+}
+
+// New compileert de regels; ongeldige regels (zie Rule.Validate, dat de
+// config validatie al doet) worden overgeslagen.
+func New(rules map[string][]Rule) *Set {
+	s := &Set{rules: make(map[string][]rule, len(rules))}
+	for eventType, list := range rules {
+		key := strings.ToLower(eventType) // viper lowercased map keys
+		for _, r := range list {
+			if r.Validate() != nil {
+				continue
+			}
+			compiled := rule{Rule: r, path: fieldpath.Split(r.Field)}
+			if r.Pattern != "" {
+				compiled.pattern = regexp.MustCompile(r.Pattern)
+			}
+			if r.When != nil {
+				compiled.when = fieldpath.Split(r.When.Field)
+			}
+			s.rules[key] = append(s.rules[key], compiled)
+		}
+	}
+	return s
+}
+
+// Check geeft alle problemen van payload, in de vorm van de JSON Schema
+// validatie.
+func (s *Set) Check(eventType string, payload map[string]interface{}) []schema.Problem {
+	if s == nil {
+		return nil
+	}
+	var problems []schema.Problem
+	for _, list := range [][]rule{s.rules[AllEventTypes], s.rules[strings.ToLower(eventType)]} {
+		for _, r := range list {
+			if r.When != nil && !r.holds(payload) {
+				continue
+			}
+			r.check(payload, r.path, "/payload", &problems)
+		}
+	}
+	return problems
+}
+
+// holds meldt of de when conditie klopt; bij een array onderweg volstaat
+// één element.
+func (r rule) holds(payload map[string]interface{}) bool {
+	var values []interface{}
+	found := fieldpath.Lookup(payload, r.when, &values)
+	if r.When.Present != nil {
+		return found == *r.When.Present
+	}
+	want := fmt.Sprint(r.When.Equals)
+	for _, v := range values {
+		if fmt.Sprint(v) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func (r rule) check(v interface{}, path []string, loc string, out *[]schema.Problem) {
+	switch m := v.(type) {
+	case []interface{}:
+		for i, item := range m {
+			r.check(item, path, schema.Pointer(loc, i), out)
+		}
+		return
+	case map[string]interface{}:
+		child, ok := m[path[0]]
+		if !ok {
+			if r.Required {
+				r.fail(schema.Pointer(loc, path[0]), "required", out, "missing property '%s'", path[0])
+			}
+			return
+		}
+		if len(path) > 1 {
+			r.check(child, path[1:], schema.Pointer(loc, path[0]), out)
+			return
+		}
+		r.checkValue(child, schema.Pointer(loc, path[0]), out)
+	default:
+		// null of een scalar onderweg: een verplicht veld kan er niet in zitten
+		if r.Required {
+			r.fail(loc, "type", out, "got %s, want object", fieldpath.JSONType(v))
+		}
+	}
+}
+
+func (r rule) checkValue(v interface{}, loc string, out *[]schema.Problem) {
+	if r.pattern != nil {
+		if s, ok := v.(string); !ok {
+			r.fail(loc, "type", out, "got %s, want string", fieldpath.JSONType(v))
+		} else if !r.pattern.MatchString(s) {
+			r.fail(loc, "pattern", out, "'%s' does not match pattern '%s'", s, r.Pattern)
+		}
+	}
+	if r.Min == nil && r.Max == nil {
+		return
+	}
+	n, ok := fieldpath.Number(v)
+	switch {
+	case !ok:
+		r.fail(loc, "type", out, "got %s, want number", fieldpath.JSONType(v))
+	case r.Min != nil && n < *r.Min:
+		r.fail(loc, "minimum", out, "must be >= %v but found %v", *r.Min, v)
+	case r.Max != nil && n > *r.Max:
+		r.fail(loc, "maximum", out, "must be <= %v but found %v", *r.Max, v)
+	}
+}
+
+func (r rule) fail(loc, keyword string, out *[]schema.Problem, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if r.Message != "" {
+		msg = r.Message
+	} else if c := r.When; c != nil {
+		if c.Present != nil {
+			msg += fmt.Sprintf(" (when %s present is %v)", c.Field, *c.Present)
+		} else {
+			msg += fmt.Sprintf(" (when %s is %v)", c.Field, c.Equals)
+		}
+	}
+	*out = append(*out, schema.Problem{Path: loc, Rule: keyword, Message: msg})
+}
@@ -0,0 +1,35 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRequiredThroughNullOrScalar(t *testing.T) {
+	s := New(map[string][]Rule{"WAGE_ERROR": {{Field: "employee.ssn", Required: true}}})
+
+	for _, tt := range []struct {
+		payload, path, rule string
+	}{
+		{`{"employee": {}}`, "/payload/employee/ssn", "required"},
+		{`{"employee": null}`, "/payload/employee", "type"},
+		{`{"employee": "ABC-123"}`, "/payload/employee", "type"},
+		{`{"employee": [{"ssn": "1"}, null]}`, "/payload/employee/1", "type"},
+		{`{"employee": {"ssn": "1"}}`, "", ""},
+	} {
+		var payload map[string]interface{}
+		if err := json.Unmarshal([]byte(tt.payload), &payload); err != nil {
+			t.Fatal(err)
+		}
+		problems := s.Check("WAGE_ERROR", payload)
+		if tt.rule == "" {
+			if len(problems) != 0 {
+				t.Errorf("%s: problems = %+v, want none", tt.payload, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 || problems[0].Path != tt.path || problems[0].Rule != tt.rule {
+			t.Errorf("%s: problems = %+v, want %s at %s", tt.payload, problems, tt.rule, tt.path)
+		}
+	}
+}
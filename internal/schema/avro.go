@@ -0,0 +1,167 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/hamba/avro/v2"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+)
+
+// checkAvro vergelijkt een JSON waarde met de Avro definitie van een JSON
+// schema uit de Pulsar schema registry en voegt elk probleem toe aan out,
+// zoals collect, met loc als JSON Pointer.
+func checkAvro(s avro.Schema, v interface{}, loc string, out *[]Problem) {
+	bad := func(rule, format string, args ...interface{}) {
+		*out = append(*out, Problem{Path: loc, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	switch s := s.(type) {
+	case *avro.RefSchema:
+		checkAvro(s.Schema(), v, loc, out)
+	case *avro.NullSchema:
+		if v != nil {
+			bad("type", "got %s, want null", jsonType(v))
+		}
+	case *avro.PrimitiveSchema:
+		switch s.Type() {
+		case avro.Boolean:
+			if _, ok := v.(bool); !ok {
+				bad("type", "got %s, want boolean", jsonType(v))
+			}
+		case avro.Int, avro.Long:
+			n, ok := fieldpath.Number(v)
+			if !ok || n != math.Trunc(n) {
+				bad("type", "got %s, want integer", jsonType(v))
+			} else if s.Type() == avro.Int && (n < math.MinInt32 || n > math.MaxInt32) {
+				bad("type", "%v does not fit in an int (32 bit)", v)
+			}
+		case avro.Float, avro.Double:
+			if _, ok := fieldpath.Number(v); !ok {
+				bad("type", "got %s, want number", jsonType(v))
+			}
+		default: // string, bytes
+			if _, ok := v.(string); !ok {
+				bad("type", "got %s, want string", jsonType(v))
+			}
+		}
+	case *avro.FixedSchema:
+		if _, ok := v.(string); !ok {
+			bad("type", "got %s, want string", jsonType(v))
+		}
+	case *avro.EnumSchema:
+		str, ok := v.(string)
+		if !ok {
+			bad("type", "got %s, want string", jsonType(v))
+		} else if !slices.Contains(s.Symbols(), str) {
+			bad("enum", "value must be one of %s", quoteAll(s.Symbols()))
+		}
+	case *avro.ArraySchema:
+		items, ok := v.([]interface{})
+		if !ok {
+			bad("type", "got %s, want array", jsonType(v))
+			return
+		}
+		for i, item := range items {
+			checkAvro(s.Items(), item, Pointer(loc, i), out)
+		}
+	case *avro.MapSchema:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			bad("type", "got %s, want object", jsonType(v))
+			return
+		}
+		for _, k := range sortedKeys(m) {
+			checkAvro(s.Values(), m[k], Pointer(loc, k), out)
+		}
+	case *avro.RecordSchema:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			bad("type", "got %s, want object", jsonType(v))
+			return
+		}
+		for _, f := range s.Fields() {
+			fv, present := m[f.Name()]
+			if !present {
+				if !f.HasDefault() && !nullable(f.Type()) {
+					*out = append(*out, Problem{Path: Pointer(loc, f.Name()), Rule: "required", Message: fmt.Sprintf("missing property '%s'", f.Name())})
+				}
+				continue
+			}
+			checkAvro(f.Type(), fv, Pointer(loc, f.Name()), out)
+		}
+	case *avro.UnionSchema:
+		var nonNull []avro.Schema
+		for _, t := range s.Types() {
+			var problems []Problem
+			checkAvro(t, v, loc, &problems)
+			if len(problems) == 0 {
+				return
+			}
+			if t.Type() != avro.Null {
+				nonNull = append(nonNull, t)
+			}
+		}
+		// bij een optioneel veld zijn de problemen van het type zelf duidelijker
+		if len(nonNull) == 1 {
+			checkAvro(nonNull[0], v, loc, out)
+			return
+		}
+		names := make([]string, 0, len(s.Types()))
+		for _, t := range s.Types() {
+			names = append(names, typeName(t))
+		}
+		bad("type", "got %s, want one of %s", jsonType(v), strings.Join(names, ", "))
+	default:
+		bad("type", "unsupported Avro type %s", s.Type())
+	}
+}
+
+// nullable meldt of een ontbrekend veld als null gedecodeerd wordt.
+func nullable(s avro.Schema) bool {
+	switch s := s.(type) {
+	case *avro.NullSchema:
+		return true
+	case *avro.UnionSchema:
+		return s.Nullable() || slices.ContainsFunc(s.Types(), func(t avro.Schema) bool { return t.Type() == avro.Null })
+	}
+	return false
+}
+
+func typeName(s avro.Schema) string {
+	if n, ok := s.(avro.NamedSchema); ok {
+		return n.Name()
+	}
+	if r, ok := s.(*avro.RefSchema); ok {
+		return r.Schema().Name()
+	}
+	return string(s.Type())
+}
+
+// jsonType geeft het JSON type van v, in de woorden van jsonschema, met
+// "integer" voor een getal zonder decimalen.
+func jsonType(v interface{}) string {
+	if n, ok := fieldpath.Number(v); ok && n == math.Trunc(n) {
+		return "integer"
+	}
+	return fieldpath.JSONType(v)
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "'" + v + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
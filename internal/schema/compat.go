@@ -0,0 +1,248 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/rubenclaes/pulsar-api/internal/fieldpath"
+)
+
+// Compatibiliteit tussen twee versies van een schema, zoals in de Confluent
+// schema registry.
+const (
+	CompatNone     = "none"
+	CompatBackward = "backward" // het nieuwe schema aanvaardt alles wat het vorige aanvaardde
+	CompatForward  = "forward"  // het vorige schema aanvaardt alles wat het nieuwe aanvaardt
+	CompatFull     = "full"     // backward en forward
+)
+
+// Incompatibilities vergelijkt de vorige en de nieuwe versie van een JSON
+// Schema (zoals jsonschema.UnmarshalJSON ze geeft) volgens mode en geeft elke
+// wijziging die niet mag, met de locatie in de payload. Vergeleken worden
+// type, enum/const, required, properties, additionalProperties, items, de
+// grenzen (minimum, maxLength, ...) en pattern, ook via lokale $ref's;
+// allOf/anyOf/oneOf en externe $ref's niet.
+func Incompatibilities(mode string, previous, next any) []string {
+	var out []string
+	if mode == CompatBackward || mode == CompatFull {
+		c := compat{prevRoot: previous, nextRoot: next, out: &out, path: map[schemaPair]bool{}}
+		c.check(previous, next, "payload")
+	}
+	if mode == CompatForward || mode == CompatFull {
+		c := compat{prevRoot: previous, nextRoot: next, forward: true, out: &out, path: map[schemaPair]bool{}}
+		c.check(previous, next, "payload")
+	}
+	return out
+}
+
+// compat controleert dat b alles aanvaardt wat a aanvaardt: backward is a
+// het vorige schema, forward het nieuwe.
+type compat struct {
+	prevRoot, nextRoot any
+	forward            bool
+	out                *[]string
+	path               map[schemaPair]bool // de paren boven de huidige locatie
+}
+
+// schemaPair is een (vorig, nieuw) paar van schema objecten, op adres.
+type schemaPair [2]uintptr
+
+func (c compat) report(loc, format string, args ...any) {
+	mode := CompatBackward
+	if c.forward {
+		mode = CompatForward
+	}
+	*c.out = append(*c.out, fmt.Sprintf("%s: %s (%s)", loc, fmt.Sprintf(format, args...), mode))
+}
+
+func (c compat) check(prevSchema, nextSchema any, loc string) {
+	prev, _ := resolveRef(c.prevRoot, prevSchema).(map[string]any)
+	next, _ := resolveRef(c.nextRoot, nextSchema).(map[string]any)
+	if prev == nil || next == nil {
+		return
+	}
+	// een recursief schema ($ref naar een schema erboven): dit paar wordt al
+	// vergeleken
+	pair := schemaPair{reflect.ValueOf(prev).Pointer(), reflect.ValueOf(next).Pointer()}
+	if c.path[pair] {
+		return
+	}
+	c.path[pair] = true
+	defer delete(c.path, pair)
+	a, b := prev, next // b moet alles aanvaarden wat a aanvaardt
+	if c.forward {
+		a, b = next, prev
+	}
+
+	if tb := types(b); len(tb) > 0 {
+		ta := types(a)
+		if len(ta) == 0 || slices.ContainsFunc(ta, func(t string) bool { return !covers(tb, t) }) {
+			c.report(loc, "type changed from %s to %s", typeList(prev), typeList(next))
+		}
+	}
+	if eb, ok := enum(b); ok {
+		ea, ok := enum(a)
+		switch {
+		case !ok && c.forward:
+			c.report(loc, "enum (or const) was removed")
+		case !ok:
+			c.report(loc, "enum (or const) was added")
+		}
+		for _, v := range ea {
+			if !slices.ContainsFunc(eb, func(w any) bool { return sameValue(v, w) }) {
+				if c.forward {
+					c.report(loc, "value %v was added", jsonValue(v))
+				} else {
+					c.report(loc, "value %v is not allowed anymore", jsonValue(v))
+				}
+			}
+		}
+	}
+	for _, name := range strs(b["required"]) {
+		if !slices.Contains(strs(a["required"]), name) {
+			if c.forward {
+				c.report(loc, "property '%s' is not required anymore", name)
+			} else {
+				c.report(loc, "property '%s' became required", name)
+			}
+		}
+	}
+
+	if closed(b) && !closed(a) {
+		if c.forward {
+			c.report(loc, "additional properties are allowed now")
+		} else {
+			c.report(loc, "additional properties are not allowed anymore")
+		}
+	}
+	pa, _ := a["properties"].(map[string]any)
+	pb, _ := b["properties"].(map[string]any)
+	for _, name := range sortedKeys(pa) {
+		if _, ok := pb[name]; ok {
+			continue
+		}
+		if closed(b) {
+			if c.forward {
+				c.report(loc, "property '%s' was added, the previous schema does not allow it", name)
+			} else {
+				c.report(loc, "property '%s' was removed and additional properties are not allowed", name)
+			}
+		}
+	}
+	for _, name := range sortedKeys(pb) {
+		if _, ok := pa[name]; ok {
+			if c.forward {
+				c.check(pb[name], pa[name], loc+"/"+name)
+			} else {
+				c.check(pa[name], pb[name], loc+"/"+name)
+			}
+		}
+	}
+	if ia, ib := prev["items"], next["items"]; ia != nil && ib != nil {
+		c.check(ia, ib, loc+"/*")
+	}
+
+	// ondergrenzen mogen in b niet hoger liggen, bovengrenzen niet lager
+	for _, kw := range []string{"minimum", "exclusiveMinimum", "minLength", "minItems", "minProperties"} {
+		if nb, ok := fieldpath.Number(b[kw]); ok {
+			if na, ok := fieldpath.Number(a[kw]); !ok || nb > na {
+				c.report(loc, "%s changed from %s to %s", kw, jsonValue(prev[kw]), jsonValue(next[kw]))
+			}
+		}
+	}
+	for _, kw := range []string{"maximum", "exclusiveMaximum", "maxLength", "maxItems", "maxProperties"} {
+		if nb, ok := fieldpath.Number(b[kw]); ok {
+			if na, ok := fieldpath.Number(a[kw]); !ok || nb < na {
+				c.report(loc, "%s changed from %s to %s", kw, jsonValue(prev[kw]), jsonValue(next[kw]))
+			}
+		}
+	}
+	if p, ok := b["pattern"].(string); ok && p != a["pattern"] {
+		c.report(loc, "pattern changed from %s to %s", jsonValue(prev["pattern"]), jsonValue(next["pattern"]))
+	}
+}
+
+// resolveRef volgt lokale $ref's ("#/$defs/...") in root.
+func resolveRef(root, s any) any {
+	for range 32 { // tegen een $ref lus
+		m, ok := s.(map[string]any)
+		if !ok {
+			return s
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return s
+		}
+		s = root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+			part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+			next, _ := s.(map[string]any)
+			s = next[part]
+		}
+	}
+	return nil
+}
+
+func types(s map[string]any) []string {
+	switch t := s["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		return strs(t)
+	}
+	return nil
+}
+
+// covers meldt of types t aanvaardt; number aanvaardt ook integer.
+func covers(types []string, t string) bool {
+	return slices.Contains(types, t) || t == "integer" && slices.Contains(types, "number")
+}
+
+func typeList(s map[string]any) string {
+	t := types(s)
+	if len(t) == 0 {
+		return "any"
+	}
+	return "[" + strings.Join(t, " ") + "]"
+}
+
+func enum(s map[string]any) ([]any, bool) {
+	if v, ok := s["const"]; ok {
+		return []any{v}, true
+	}
+	e, ok := s["enum"].([]any)
+	return e, ok
+}
+
+func closed(s map[string]any) bool {
+	return s["additionalProperties"] == false || s["unevaluatedProperties"] == false
+}
+
+func strs(v any) []string {
+	list, _ := v.([]any)
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func sameValue(a, b any) bool {
+	return jsonValue(a) == jsonValue(b)
+}
+
+func jsonValue(v any) string {
+	if v == nil {
+		return "none"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
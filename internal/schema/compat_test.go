@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decodeSchema(t *testing.T, s string) any {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestIncompatibilitiesRecursiveSchema(t *testing.T) {
+	const tree = `{
+		"type": "object",
+		"properties": {"root": {"$ref": "#/$defs/node"}},
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string"%s},
+					"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}
+				}
+			}
+		}
+	}`
+	prev := decodeSchema(t, strings.Replace(tree, "%s", "", 1))
+	if got := Incompatibilities(CompatFull, prev, prev); len(got) != 0 {
+		t.Errorf("Incompatibilities(same schema) = %v", got)
+	}
+
+	next := decodeSchema(t, strings.Replace(tree, "%s", `, "maxLength": 10`, 1))
+	got := Incompatibilities(CompatBackward, prev, next)
+	if len(got) != 1 || !strings.HasPrefix(got[0], "payload/root/name: maxLength") {
+		t.Errorf("Incompatibilities = %v, want one maxLength change on payload/root/name", got)
+	}
+}
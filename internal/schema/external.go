@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ExternalOptions komen uit de registry config sectie.
+type ExternalOptions struct {
+	Provider string // confluent of apicurio
+	URL      string // bv. http://registry:8081 of http://apicurio:8080/apis/registry/v2
+	Group    string // apicurio
+	Username string // basic auth, bv. de API key en secret van Confluent Cloud
+	Password string
+	Token    string // bearer token, i.p.v. username/password
+	CacheTTL time.Duration
+	Timeout  time.Duration
+}
+
+// Document is een schema uit de registry, om mee te compileren in Load.
+type Document struct {
+	Location string // URL in de registry, de basis voor relatieve $ref's
+	Data     any
+}
+
+// External haalt de JSON Schema's van eventTypes uit een centrale schema
+// registry (Confluent of een compatibele, zoals de ccompat API van
+// Apicurio, of de eigen API van Apicurio). Elk schema wordt CacheTTL lang
+// gecached; lukt het ophalen daarna niet, dan blijft de vorige versie gelden.
+type External struct {
+	opts   ExternalOptions
+	client *http.Client
+	fetch  func(ctx context.Context, subject string) (location string, raw []byte, err error)
+
+	mu      sync.Mutex
+	cache   map[string]*externalDoc // subject → laatste versie
+	version int                     // telt de gewijzigde schema's, zie Refresh
+}
+
+type externalDoc struct {
+	fetched  time.Time
+	location string
+	raw      []byte
+}
+
+func NewExternal(opts ExternalOptions) (*External, error) {
+	e := &External{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		cache:  map[string]*externalDoc{},
+	}
+	switch opts.Provider {
+	case "confluent":
+		e.fetch = e.fetchConfluent
+	case "apicurio":
+		e.fetch = e.fetchApicurio
+	default:
+		return nil, fmt.Errorf("unknown schema registry provider %q (confluent or apicurio)", opts.Provider)
+	}
+	return e, nil
+}
+
+// Schemas geeft eventType → schema voor subjects (eventType → subject of
+// artifactId). Alle fouten worden samen teruggegeven.
+func (e *External) Schemas(ctx context.Context, subjects map[string]string) (map[string]Document, error) {
+	out, _, err := e.schemas(ctx, subjects, false)
+	return out, err
+}
+
+// schemas haalt met force elk schema opnieuw op, ook als het nog in de cache
+// zit. stale zijn de fouten waarvoor de vorige versie gebruikt werd.
+func (e *External) schemas(ctx context.Context, subjects map[string]string, force bool) (_ map[string]Document, stale, err error) {
+	out := make(map[string]Document, len(subjects))
+	var errs, staleErrs []error
+	for _, eventType := range sortedSubjects(subjects) {
+		subject := subjects[eventType]
+		doc, err := e.get(ctx, subject, force)
+		if err != nil {
+			err = fmt.Errorf("schema %s (registry subject %s): %w", eventType, subject, err)
+			if doc == nil {
+				errs = append(errs, err)
+				continue
+			}
+			staleErrs = append(staleErrs, err)
+		}
+		data, err := jsonschema.UnmarshalJSON(bytes.NewReader(doc.raw))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schema %s (%s): %w", eventType, doc.location, err))
+			continue
+		}
+		out[strings.ToLower(eventType)] = Document{Location: doc.location, Data: data}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+	return out, errors.Join(staleErrs...), nil
+}
+
+// get geeft bij een fout de vorige versie mee, als die er is.
+func (e *External) get(ctx context.Context, subject string, force bool) (*externalDoc, error) {
+	e.mu.Lock()
+	cached := e.cache[subject]
+	e.mu.Unlock()
+	if cached != nil && !force && time.Since(cached.fetched) < e.opts.CacheTTL {
+		return cached, nil
+	}
+
+	location, raw, fetchErr := e.fetch(ctx, subject)
+	if fetchErr != nil {
+		if cached == nil {
+			return nil, fetchErr
+		}
+		// de vorige versie, en pas na CacheTTL opnieuw proberen
+		location, raw = cached.location, cached.raw
+	}
+	doc := &externalDoc{fetched: time.Now(), location: location, raw: raw}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if cached == nil || !bytes.Equal(cached.raw, raw) {
+		e.version++
+	}
+	e.cache[subject] = doc
+	return doc, fetchErr
+}
+
+// Refresh haalt de schema's van subjects() elke CacheTTL opnieuw op en roept
+// changed aan als er een gewijzigd is, tot ctx afloopt. Fouten komen bij
+// onError; de vorige versies blijven dan gelden.
+func (e *External) Refresh(ctx context.Context, subjects func() map[string]string, changed func(), onError func(error)) {
+	t := time.NewTicker(e.opts.CacheTTL)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			e.mu.Lock()
+			before := e.version
+			e.mu.Unlock()
+			if _, stale, err := e.schemas(ctx, subjects(), true); err != nil {
+				onError(err)
+			} else if stale != nil {
+				onError(stale)
+			}
+			e.mu.Lock()
+			after := e.version
+			e.mu.Unlock()
+			if after != before {
+				changed()
+			}
+		}
+	}
+}
+
+// fetchConfluent: GET /subjects/{subject}/versions/latest, enkel schemaType JSON.
+func (e *External) fetchConfluent(ctx context.Context, subject string) (string, []byte, error) {
+	u := strings.TrimSuffix(e.opts.URL, "/") + "/subjects/" + url.PathEscape(subject) + "/versions/latest"
+	body, err := e.do(ctx, u, "application/vnd.schemaregistry.v1+json")
+	if err != nil {
+		return "", nil, err
+	}
+	var latest struct {
+		Version    int    `json:"version"`
+		SchemaType string `json:"schemaType"` // leeg = AVRO
+		Schema     string `json:"schema"`
+	}
+	if err := json.Unmarshal(body, &latest); err != nil {
+		return "", nil, fmt.Errorf("GET %s: %w", u, err)
+	}
+	if latest.SchemaType != "JSON" {
+		return "", nil, fmt.Errorf("subject %s is not a JSON schema (schemaType %q)", subject, latest.SchemaType)
+	}
+	return strings.TrimSuffix(u, "latest") + fmt.Sprint(latest.Version), []byte(latest.Schema), nil
+}
+
+// fetchApicurio: GET /groups/{group}/artifacts/{artifactId}, de laatste versie (API v2).
+func (e *External) fetchApicurio(ctx context.Context, artifactID string) (string, []byte, error) {
+	group := e.opts.Group
+	if group == "" {
+		group = "default"
+	}
+	u := strings.TrimSuffix(e.opts.URL, "/") + "/groups/" + url.PathEscape(group) + "/artifacts/" + url.PathEscape(artifactID)
+	body, err := e.do(ctx, u, "application/json")
+	return u, body, err
+}
+
+func (e *External) do(ctx context.Context, u, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	switch {
+	case e.opts.Token != "":
+		req.Header.Set("Authorization", "Bearer "+e.opts.Token)
+	case e.opts.Username != "":
+		req.SetBasicAuth(e.opts.Username, e.opts.Password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func sortedSubjects(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,168 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// ErrRegistryUnavailable: het schema van de topic kon niet opgehaald worden
+// en er is ook geen vorige versie in de cache.
+var ErrRegistryUnavailable = errors.New("pulsar schema registry unavailable")
+
+// PulsarAdmin is de admin API van een cluster, bv. http://pulsar:8080.
+type PulsarAdmin struct {
+	URL   string
+	Token func() (string, error) // nil = zonder auth
+}
+
+// TopicSchemas valideert payloads tegen het schema dat in de schema registry
+// van Pulsar op de topic geregistreerd is. Elk schema wordt ttl lang
+// gecached; lukt het ophalen daarna niet, dan blijft de vorige versie gelden.
+//
+// De API publiceert JSON: een topic zonder schema of met een BYTES, STRING
+// of NONE schema wordt niet gevalideerd, een JSON schema (een Avro
+// definitie) wel, en elk ander type (AVRO, PROTOBUF, ...) weigert alles.
+type TopicSchemas struct {
+	admins map[string]PulsarAdmin // cluster → admin API
+	ttl    time.Duration
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*topicSchema // cluster + " " + topic
+}
+
+type topicSchema struct {
+	fetched time.Time
+	kind    string      // type uit de registry, "" = geen schema
+	avro    avro.Schema // enkel voor JSON
+}
+
+func NewTopicSchemas(admins map[string]PulsarAdmin, ttl, timeout time.Duration) *TopicSchemas {
+	return &TopicSchemas{
+		admins: admins,
+		ttl:    ttl,
+		client: &http.Client{Timeout: timeout},
+		cache:  map[string]*topicSchema{},
+	}
+}
+
+// Validate controleert payload tegen het schema van topic op cluster. Een
+// ongeldige payload geeft een *ValidationError met alle problemen.
+func (t *TopicSchemas) Validate(ctx context.Context, cluster, topic string, payload map[string]interface{}) error {
+	ts, err := t.schema(ctx, cluster, topic)
+	if err != nil {
+		return err
+	}
+	var problems []Problem
+	switch ts.kind {
+	case "", "NONE", "BYTES", "STRING":
+		return nil
+	case "JSON":
+		checkAvro(ts.avro, payload, "/payload", &problems)
+	default:
+		problems = append(problems, Problem{Path: "/payload", Rule: "schemaType", Message: fmt.Sprintf("topic %s has schema type %s, the API only publishes JSON", topic, ts.kind)})
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func (t *TopicSchemas) schema(ctx context.Context, cluster, topic string) (*topicSchema, error) {
+	key := cluster + " " + topic
+	t.mu.Lock()
+	cached := t.cache[key]
+	t.mu.Unlock()
+	if cached != nil && time.Since(cached.fetched) < t.ttl {
+		return cached, nil
+	}
+
+	ts, err := t.fetch(ctx, cluster, topic)
+	if err != nil {
+		if cached == nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrRegistryUnavailable, topic, err)
+		}
+		// de vorige versie, en pas na ttl opnieuw proberen
+		ts = &topicSchema{kind: cached.kind, avro: cached.avro}
+	}
+	ts.fetched = time.Now()
+	t.mu.Lock()
+	t.cache[key] = ts
+	t.mu.Unlock()
+	return ts, nil
+}
+
+// fetch haalt de laatste versie van het schema op via
+// GET /admin/v2/schemas/{tenant}/{namespace}/{topic}/schema.
+func (t *TopicSchemas) fetch(ctx context.Context, cluster, topic string) (*topicSchema, error) {
+	admin, ok := t.admins[cluster]
+	if !ok {
+		return nil, fmt.Errorf("no admin URL for cluster %q", cluster)
+	}
+	u := strings.TrimSuffix(admin.URL, "/") + "/admin/v2/schemas/" + topicPath(topic) + "/schema"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if admin.Token != nil {
+		token, err := admin.Token()
+		if err != nil {
+			return nil, fmt.Errorf("auth token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound: // topic zonder schema
+		return &topicSchema{}, nil
+	default:
+		return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+
+	var body struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", u, err)
+	}
+	ts := &topicSchema{kind: strings.ToUpper(body.Type)}
+	if ts.kind == "JSON" {
+		// eigen cache: dezelfde recordnaam kan op twee topics anders zijn
+		ts.avro, err = avro.ParseWithCache(body.Data, "", &avro.SchemaCache{})
+		if err != nil {
+			return nil, fmt.Errorf("JSON schema of %s: %w", topic, err)
+		}
+	}
+	return ts, nil
+}
+
+// topicPath maakt van persistent://tenant/ns/topic of een korte naam het
+// pad in de admin API.
+func topicPath(topic string) string {
+	if _, rest, ok := strings.Cut(topic, "://"); ok {
+		topic = rest
+	}
+	if !strings.Contains(topic, "/") {
+		topic = "public/default/" + topic
+	}
+	parts := strings.Split(topic, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
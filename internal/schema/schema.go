@@ -0,0 +1,275 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var printer = message.NewPrinter(language.English)
+
+// Registry bevat de gecompileerde JSON Schema's per eventType (lowercase).
+// Een eventType zonder schema wordt niet gevalideerd.
+type Registry struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// Load compileert elk <eventType>.json bestand uit dir, de schema's uit een
+// externe registry (external, zie External) en de expliciete eventType →
+// bestand (of URL) mapping uit files; bij een conflict wint de laatste. Alle
+// fouten worden samen teruggegeven.
+func Load(dir string, files map[string]string, external map[string]Document) (*Registry, error) {
+	paths := map[string]string{}
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			eventType := strings.TrimSuffix(filepath.Base(m), ".json")
+			paths[strings.ToLower(eventType)] = m
+		}
+	}
+	docs := make(map[string]Document, len(external))
+	for eventType, doc := range external {
+		delete(paths, eventType)
+		docs[eventType] = doc
+	}
+	for eventType, path := range files {
+		paths[strings.ToLower(eventType)] = path
+		delete(docs, strings.ToLower(eventType))
+	}
+
+	r := &Registry{
+		schemas: make(map[string]*jsonschema.Schema, len(paths)),
+	}
+	c := newCompiler()
+	var errs []error
+	for eventType, doc := range docs {
+		sch, err := compileDocument(c, doc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schema %s (%s): %w", eventType, doc.Location, err))
+			continue
+		}
+		r.schemas[eventType] = sch
+	}
+	for eventType, path := range paths {
+		if IsURL(path) {
+			sch, err := c.Compile(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("schema %s (%s): %w", eventType, path, err))
+				continue
+			}
+			r.schemas[eventType] = sch
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			_, err = os.Stat(abs)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schema %s: %w", eventType, err))
+			continue
+		}
+		sch, err := c.Compile(abs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("schema %s (%s): %w", eventType, path, err))
+			continue
+		}
+		r.schemas[eventType] = sch
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func newCompiler() *jsonschema.Compiler {
+	c := jsonschema.NewCompiler()
+	// schema's zonder $schema zijn draft 2020-12; "format" is een controle,
+	// geen annotatie (bv. "format": "date-time" of "email")
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.AssertFormat()
+	c.UseLoader(jsonschema.SchemeURLLoader{
+		"file":  jsonschema.FileLoader{},
+		"http":  httpLoader{},
+		"https": httpLoader{},
+	})
+	return c
+}
+
+// Compile controleert of doc compileert zoals in Load, met location (een
+// bestand of URL) als basis voor relatieve $ref's.
+func Compile(location string, doc any) error {
+	if !IsURL(location) {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return err
+		}
+		location = abs
+	}
+	_, err := compileDocument(newCompiler(), Document{Location: location, Data: doc})
+	return err
+}
+
+func compileDocument(c *jsonschema.Compiler, doc Document) (*jsonschema.Schema, error) {
+	if err := c.AddResource(doc.Location, doc.Data); err != nil {
+		return nil, err
+	}
+	return c.Compile(doc.Location)
+}
+
+// IsURL meldt of een schema locatie een http(s) URL is in plaats van een bestand,
+// bv. een Consul key: http://consul:8500/v1/kv/pulsar-api/schemas/wage_error?raw
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// httpLoader haalt schema's (en hun $ref's) op via HTTP.
+type httpLoader struct{}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func (httpLoader) Load(url string) (any, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return jsonschema.UnmarshalJSON(resp.Body)
+}
+
+// EventTypes geeft de eventTypes met een schema, gesorteerd.
+func (r *Registry) EventTypes() []string {
+	if r == nil {
+		return nil
+	}
+	out := make([]string, 0, len(r.schemas))
+	for et := range r.schemas {
+		out = append(out, et)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Has meldt of eventType een schema heeft.
+func (r *Registry) Has(eventType string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.schemas[strings.ToLower(eventType)]
+	return ok
+}
+
+// Validate controleert payload tegen het schema van eventType. De fout bevat
+// per probleem de locatie in de request, bv. /payload/dossierId. Met
+// strict zijn properties die het schema niet kent ook een fout (zie
+// unknownProperties).
+func (r *Registry) Validate(eventType string, payload map[string]interface{}, strict bool) error {
+	if r == nil {
+		return nil
+	}
+	sch, ok := r.schemas[strings.ToLower(eventType)]
+	if !ok {
+		return nil
+	}
+
+	var problems []Problem
+	err := sch.Validate(map[string]interface{}(payload))
+	var ve *jsonschema.ValidationError
+	if errors.As(err, &ve) {
+		collect(ve, &problems)
+	} else if err != nil {
+		return err
+	}
+	if strict {
+		unknownProperties(sch, map[string]interface{}(payload), "/payload", &problems)
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ValidationError bevat alle problemen van een payload.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.String()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Problem is één probleem van een payload, zodat een UI het veld kan aanduiden.
+type Problem struct {
+	Path    string `json:"path"` // JSON Pointer in de request, bv. /payload/errorCode
+	Rule    string `json:"rule"` // het keyword dat faalde, bv. required, type of pattern
+	Message string `json:"message"`
+}
+
+// String geeft het probleem als "payload/errorCode: ..."; bij een ontbrekend
+// of onbekend veld staat de locatie van het object ervoor, zoals jsonschema.
+func (p Problem) String() string {
+	loc := p.Path
+	if p.Rule == "required" || p.Rule == "additionalProperties" {
+		loc = loc[:strings.LastIndex(loc, "/")]
+	}
+	if loc == "" {
+		return p.Message // op het hoogste niveau, bv. een attribuut van een CloudEvent
+	}
+	return strings.TrimPrefix(loc, "/") + ": " + p.Message
+}
+
+// Pointer voegt een property of index toe aan een JSON Pointer (RFC 6901).
+func Pointer(loc string, token any) string {
+	return loc + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(fmt.Sprint(token))
+}
+
+// collect verzamelt de bladeren van de foutboom; die beschrijven de echte
+// problemen. Een ontbrekend of onbekend veld wordt een probleem per veld.
+func collect(ve *jsonschema.ValidationError, out *[]Problem) {
+	if len(ve.Causes) == 0 {
+		loc := "/payload"
+		for _, token := range ve.InstanceLocation {
+			loc = Pointer(loc, token)
+		}
+		switch k := ve.ErrorKind.(type) {
+		case *kind.Required:
+			for _, name := range k.Missing {
+				*out = append(*out, Problem{Path: Pointer(loc, name), Rule: "required", Message: fmt.Sprintf("missing property '%s'", name)})
+			}
+			return
+		case *kind.AdditionalProperties:
+			for _, name := range k.Properties {
+				*out = append(*out, Problem{Path: Pointer(loc, name), Rule: "additionalProperties", Message: fmt.Sprintf("additional properties '%s' not allowed", name)})
+			}
+			return
+		}
+		var rule string
+		if kw := ve.ErrorKind.KeywordPath(); len(kw) > 0 {
+			rule = kw[len(kw)-1]
+		}
+		*out = append(*out, Problem{Path: loc, Rule: rule, Message: ve.ErrorKind.LocalizedString(printer)})
+		return
+	}
+	for _, c := range ve.Causes {
+		collect(c, out)
+	}
+}
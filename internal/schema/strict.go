@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// unknownProperties voegt aan out de properties van v toe die het schema
+// niet kent, ook in geneste objecten en arrays (strict mode, alsof overal
+// additionalProperties: false staat). Enkel objecten waarvan het schema
+// properties opsomt, worden gecontroleerd; een schema dat zelf
+// additionalProperties of unevaluatedProperties zet, beslist zelf.
+func unknownProperties(s *jsonschema.Schema, v interface{}, loc string, out *[]Problem) {
+	schemas := applicable(s, map[*jsonschema.Schema]bool{}, nil)
+	switch v := v.(type) {
+	case map[string]interface{}:
+		declared, open := false, false
+		for _, sch := range schemas {
+			declared = declared || len(sch.Properties) > 0 || len(sch.PatternProperties) > 0
+			open = open || sch.AdditionalProperties != nil || sch.UnevaluatedProperties != nil
+		}
+		for _, k := range sortedKeys(v) {
+			if declared && !open && len(propertySchemas(schemas, k)) == 0 {
+				*out = append(*out, Problem{Path: Pointer(loc, k), Rule: "additionalProperties", Message: fmt.Sprintf("additional properties '%s' not allowed (strict)", k)})
+			}
+		}
+		for _, k := range sortedKeys(v) {
+			for _, sub := range propertySchemas(schemas, k) {
+				unknownProperties(sub, v[k], Pointer(loc, k), out)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			for _, sub := range itemSchemas(schemas, i) {
+				unknownProperties(sub, item, Pointer(loc, i), out)
+			}
+		}
+	}
+}
+
+// applicable geeft s met alle schema's die mee op dezelfde waarde gelden:
+// $ref, allOf, anyOf, oneOf en if/then/else.
+func applicable(s *jsonschema.Schema, seen map[*jsonschema.Schema]bool, out []*jsonschema.Schema) []*jsonschema.Schema {
+	if s == nil || seen[s] {
+		return out
+	}
+	seen[s] = true
+	out = append(out, s)
+	out = applicable(s.Ref, seen, out)
+	out = applicable(s.RecursiveRef, seen, out)
+	if s.DynamicRef != nil {
+		out = applicable(s.DynamicRef.Ref, seen, out)
+	}
+	for _, group := range [][]*jsonschema.Schema{s.AllOf, s.AnyOf, s.OneOf, {s.If, s.Then, s.Else}} {
+		for _, sub := range group {
+			out = applicable(sub, seen, out)
+		}
+	}
+	return out
+}
+
+func propertySchemas(schemas []*jsonschema.Schema, name string) []*jsonschema.Schema {
+	var out []*jsonschema.Schema
+	for _, sch := range schemas {
+		if sub, ok := sch.Properties[name]; ok {
+			out = append(out, sub)
+		}
+		for re, sub := range sch.PatternProperties {
+			if re.MatchString(name) {
+				out = append(out, sub)
+			}
+		}
+	}
+	return out
+}
+
+func itemSchemas(schemas []*jsonschema.Schema, i int) []*jsonschema.Schema {
+	var out []*jsonschema.Schema
+	for _, sch := range schemas {
+		switch {
+		case i < len(sch.PrefixItems):
+			out = append(out, sch.PrefixItems[i])
+		case sch.Items2020 != nil:
+			out = append(out, sch.Items2020)
+		}
+		switch items := sch.Items.(type) { // drafts vóór 2020-12
+		case *jsonschema.Schema:
+			out = append(out, items)
+		case []*jsonschema.Schema:
+			if i < len(items) {
+				out = append(out, items[i])
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package schema
+
+import (
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// maxTemplateDepth begrenst de nesting van een template, voor recursieve
+// schema's.
+const maxTemplateDepth = 8
+
+// Template is een voorbeeld payload die het schema van een eventType
+// volgt, zodat een client (zoals de tester UI) niet van nul moet beginnen.
+type Template struct {
+	Payload  map[string]interface{} `json:"payload"`
+	Required []string               `json:"required"` // JSON Pointers van de verplichte velden, bv. /payload/errorCode
+}
+
+// Template geeft een voorbeeld payload voor eventType (of een versie key,
+// zie Validate); false zonder schema. Een waarde komt uit const, examples,
+// default of enum van het schema, anders een waarde van het juiste type
+// binnen minLength, maxLength en minimum. Een pattern of format (behalve
+// date-time, date, email, uuid en uri) wordt niet gevolgd.
+func (r *Registry) Template(eventType string) (Template, bool) {
+	if r == nil {
+		return Template{}, false
+	}
+	sch, ok := r.schemas[strings.ToLower(eventType)]
+	if !ok {
+		return Template{}, false
+	}
+	tp := templater{required: []string{}, path: map[*jsonschema.Schema]bool{}}
+	payload, _ := tp.example(sch, "/payload", 0).(map[string]interface{})
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	return Template{Payload: payload, Required: tp.required}, true
+}
+
+type templater struct {
+	required []string
+	path     map[*jsonschema.Schema]bool // schema's van de objecten boven de huidige waarde
+}
+
+// example geeft een voorbeeldwaarde voor s op loc en voegt de verplichte
+// velden daaronder toe aan required. Een optioneel veld met een schema dat
+// al boven hem staat (recursie) wordt weggelaten.
+func (tp *templater) example(s *jsonschema.Schema, loc string, depth int) interface{} {
+	schemas := applicable(s, map[*jsonschema.Schema]bool{}, nil)
+	for _, sch := range schemas {
+		switch {
+		case sch.Const != nil:
+			return *sch.Const
+		case len(sch.Examples) > 0:
+			return sch.Examples[0]
+		case sch.Default != nil:
+			return *sch.Default
+		case sch.Enum != nil && len(sch.Enum.Values) > 0:
+			return sch.Enum.Values[0]
+		}
+	}
+
+	switch templateType(schemas) {
+	case "object":
+		obj := map[string]interface{}{}
+		if depth >= maxTemplateDepth {
+			return obj
+		}
+		var names, mandatory []string
+		for _, sch := range schemas {
+			for name := range sch.Properties {
+				if !slices.Contains(names, name) {
+					names = append(names, name)
+				}
+			}
+			mandatory = append(mandatory, sch.Required...)
+		}
+		slices.Sort(names)
+		var added []*jsonschema.Schema
+		for _, sch := range schemas {
+			if !tp.path[sch] {
+				tp.path[sch] = true
+				added = append(added, sch)
+			}
+		}
+		for _, name := range names {
+			prop := propertySchemas(schemas, name)[0]
+			if !slices.Contains(mandatory, name) {
+				if slices.ContainsFunc(applicable(prop, map[*jsonschema.Schema]bool{}, nil), func(s *jsonschema.Schema) bool { return tp.path[s] }) {
+					continue
+				}
+			} else {
+				tp.required = append(tp.required, Pointer(loc, name))
+			}
+			obj[name] = tp.example(prop, Pointer(loc, name), depth+1)
+		}
+		for _, sch := range added {
+			delete(tp.path, sch)
+		}
+		return obj
+	case "array":
+		items := itemSchemas(schemas, 0)
+		if len(items) == 0 || depth >= maxTemplateDepth {
+			return []interface{}{}
+		}
+		return []interface{}{tp.example(items[0], Pointer(loc, 0), depth+1)}
+	case "string":
+		return exampleString(schemas)
+	case "integer", "number":
+		for _, sch := range schemas {
+			switch {
+			case sch.Minimum != nil:
+				n, _ := sch.Minimum.Float64()
+				return math.Ceil(n)
+			case sch.ExclusiveMinimum != nil:
+				n, _ := sch.ExclusiveMinimum.Float64()
+				return math.Floor(n) + 1
+			}
+		}
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// templateTypes: bij meer toegelaten types de eerste uit deze lijst, bv.
+// een string voor ["string", "integer"].
+var templateTypes = []string{"object", "array", "string", "integer", "number", "boolean"}
+
+// templateType kiest het type van de voorbeeldwaarde (zie templateTypes), of
+// object als er enkel properties zijn.
+func templateType(schemas []*jsonschema.Schema) string {
+	for _, sch := range schemas {
+		if sch.Types == nil {
+			continue
+		}
+		types := sch.Types.ToStrings()
+		for _, t := range templateTypes {
+			if slices.Contains(types, t) {
+				return t
+			}
+		}
+	}
+	for _, sch := range schemas {
+		if len(sch.Properties) > 0 {
+			return "object"
+		}
+	}
+	return ""
+}
+
+func exampleString(schemas []*jsonschema.Schema) string {
+	s := "string"
+	for _, sch := range schemas {
+		if sch.Format != nil {
+			switch sch.Format.Name {
+			case "date-time":
+				return "2026-01-01T00:00:00Z"
+			case "date":
+				return "2026-01-01"
+			case "email":
+				return "user@example.com"
+			case "uuid":
+				return "00000000-0000-0000-0000-000000000000"
+			case "uri":
+				return "https://example.com"
+			}
+		}
+	}
+	for _, sch := range schemas {
+		if sch.MinLength != nil && len(s) < *sch.MinLength {
+			s += strings.Repeat("x", *sch.MinLength-len(s))
+		}
+		if sch.MaxLength != nil && len(s) > *sch.MaxLength {
+			s = s[:*sch.MaxLength]
+		}
+	}
+	return s
+}
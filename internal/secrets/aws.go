@@ -0,0 +1,148 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// KMSPrefix markeert een KMS ciphertext als secret path:
+// "secret:kms:<base64 ciphertext>#plaintext".
+const KMSPrefix = "kms:"
+
+// AWSOptions komt uit de secrets.aws config sectie.
+type AWSOptions struct {
+	Region   string // leeg → AWS_REGION / AWS_DEFAULT_REGION
+	Endpoint string // optioneel, bv. LocalStack; geldt voor Secrets Manager en KMS
+}
+
+// AWS leest secrets uit AWS Secrets Manager en ontsleutelt KMS ciphertexts
+// (bv. encryption keys), met credentials van de pod (IRSA of Pod Identity).
+type AWS struct {
+	opts   AWSOptions
+	client *http.Client
+	creds  *awsCredentialProvider
+}
+
+func NewAWS(ctx context.Context, opts AWSOptions) (*AWS, error) {
+	if opts.Region == "" {
+		opts.Region = os.Getenv("AWS_REGION")
+	}
+	if opts.Region == "" {
+		opts.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if opts.Region == "" {
+		return nil, errors.New("secrets.aws.region (or AWS_REGION) is required")
+	}
+	opts.Endpoint = strings.TrimSuffix(opts.Endpoint, "/")
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	creds, err := newAWSCredentials(client, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+	// meteen ophalen, zodat een fout in de setup bij het opstarten zichtbaar is
+	if _, err := creds.get(ctx); err != nil {
+		return nil, fmt.Errorf("aws credentials: %w", err)
+	}
+	return &AWS{opts: opts, client: client, creds: creds}, nil
+}
+
+// Read geeft de velden van een Secrets Manager secret (SecretString als JSON
+// object, anders één veld "value"), of voor een kms:<ciphertext> path het
+// ontsleutelde resultaat in "plaintext" en "base64".
+func (a *AWS) Read(ctx context.Context, path string) (map[string]string, error) {
+	if blob, ok := strings.CutPrefix(path, KMSPrefix); ok {
+		return a.decrypt(ctx, blob)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary []byte `json:"SecretBinary"`
+	}
+	if err := a.call(ctx, "secretsmanager", "secretsmanager.GetSecretValue", map[string]string{"SecretId": path}, &out); err != nil {
+		return nil, err
+	}
+	if out.SecretString == "" {
+		return map[string]string{"value": string(out.SecretBinary)}, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return map[string]string{"value": out.SecretString}, nil
+	}
+	data := make(map[string]string, len(fields))
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			data[k] = s
+			continue
+		}
+		data[k] = fmt.Sprint(v)
+	}
+	return data, nil
+}
+
+func (a *AWS) decrypt(ctx context.Context, blob string) (map[string]string, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return nil, fmt.Errorf("kms ciphertext is not valid base64: %w", err)
+	}
+	var out struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	in := map[string][]byte{"CiphertextBlob": ciphertext}
+	if err := a.call(ctx, "kms", "TrentService.Decrypt", in, &out); err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"plaintext": string(out.Plaintext),
+		"base64":    base64.StdEncoding.EncodeToString(out.Plaintext),
+	}, nil
+}
+
+// call voert een AWS JSON 1.1 API call uit (Secrets Manager en KMS gebruiken die allebei).
+func (a *AWS) call(ctx context.Context, service, target string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := a.opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, a.opts.Region)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	creds, err := a.creds.get(ctx)
+	if err != nil {
+		return fmt.Errorf("aws credentials: %w", err)
+	}
+	signV4(req, body, creds, a.opts.Region, service, time.Now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("aws %s: %s %s %s", target, resp.Status, e.Type, e.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
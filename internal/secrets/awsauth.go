@@ -0,0 +1,229 @@
+package secrets
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// credentials worden zoveel vroeger vernieuwd dan hun vervaldatum
+const awsCredentialsMargin = 5 * time.Minute
+
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time // zero = verloopt niet
+}
+
+// awsCredentialProvider cachet credentials en haalt nieuwe op voor ze vervallen.
+type awsCredentialProvider struct {
+	fetch func(ctx context.Context) (awsCredentials, error)
+
+	mu     sync.Mutex
+	cached awsCredentials
+}
+
+func (p *awsCredentialProvider) get(ctx context.Context) (awsCredentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cached.AccessKeyID != "" && (p.cached.Expires.IsZero() || time.Until(p.cached.Expires) > awsCredentialsMargin) {
+		return p.cached, nil
+	}
+	c, err := p.fetch(ctx)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	p.cached = c
+	return c, nil
+}
+
+// newAWSCredentials volgt dezelfde volgorde als de AWS SDK voor de bronnen die
+// op EKS voorkomen: statische env credentials, IRSA (web identity) en EKS Pod
+// Identity / ECS container credentials.
+func newAWSCredentials(client *http.Client, region string) (*awsCredentialProvider, error) {
+	switch {
+	case os.Getenv("AWS_ACCESS_KEY_ID") != "":
+		c := awsCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+		return &awsCredentialProvider{fetch: func(context.Context) (awsCredentials, error) { return c, nil }}, nil
+
+	case os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE") != "":
+		return &awsCredentialProvider{fetch: func(ctx context.Context) (awsCredentials, error) {
+			return assumeRoleWithWebIdentity(ctx, client, region)
+		}}, nil
+
+	case os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "":
+		return &awsCredentialProvider{fetch: func(ctx context.Context) (awsCredentials, error) {
+			return containerCredentials(ctx, client)
+		}}, nil
+	}
+	return nil, errors.New("no AWS credentials found (AWS_ACCESS_KEY_ID, IRSA web identity or EKS Pod Identity)")
+}
+
+// assumeRoleWithWebIdentity ruilt het service account token (IRSA) in voor
+// tijdelijke credentials. Deze STS call is zelf niet gesigneerd.
+func assumeRoleWithWebIdentity(ctx context.Context, client *http.Client, region string) (awsCredentials, error) {
+	token, err := os.ReadFile(os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"))
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("read web identity token: %w", err)
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = "pulsar-api"
+	}
+	q := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {os.Getenv("AWS_ROLE_ARN")},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	endpoint := fmt.Sprintf("https://sts.%s.amazonaws.com/?%s", region, q.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return awsCredentials{}, fmt.Errorf("sts AssumeRoleWithWebIdentity: %s", resp.Status)
+	}
+
+	var out struct {
+		Credentials struct {
+			AccessKeyID     string    `xml:"AccessKeyId"`
+			SecretAccessKey string    `xml:"SecretAccessKey"`
+			SessionToken    string    `xml:"SessionToken"`
+			Expiration      time.Time `xml:"Expiration"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return awsCredentials{}, fmt.Errorf("sts AssumeRoleWithWebIdentity: %w", err)
+	}
+	c := out.Credentials
+	return awsCredentials{c.AccessKeyID, c.SecretAccessKey, c.SessionToken, c.Expiration}, nil
+}
+
+// containerCredentials haalt credentials bij de EKS Pod Identity agent (of ECS).
+func containerCredentials(ctx context.Context, client *http.Client) (awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if endpoint == "" {
+		endpoint = "http://169.254.170.2" + os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if f := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); f != "" {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return awsCredentials{}, fmt.Errorf("read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return awsCredentials{}, fmt.Errorf("container credentials: %s", resp.Status)
+	}
+	var out struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return awsCredentials{}, fmt.Errorf("container credentials: %w", err)
+	}
+	return awsCredentials{out.AccessKeyID, out.SecretAccessKey, out.Token, out.Expiration}, nil
+}
+
+// signV4 signeert req volgens AWS Signature Version 4.
+func signV4(req *http.Request, body []byte, c awsCredentials, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// RefPrefix markeert een config waarde die uit de secret provider komt:
+// "secret:<path>#<field>", bv. "secret:secret/data/pulsar-api#pulsarToken".
+const RefPrefix = "secret:"
+
+// Source leest alle velden van het secret op path.
+type Source interface {
+	Read(ctx context.Context, path string) (map[string]string, error)
+}
+
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// ParseRef splitst een referentie in path en field.
+func ParseRef(ref string) (path, field string, err error) {
+	path, field, ok := strings.Cut(strings.TrimPrefix(ref, RefPrefix), "#")
+	if !IsRef(ref) || !ok || path == "" || field == "" {
+		return "", "", fmt.Errorf("invalid secret reference %q, expected secret:<path>#<field>", ref)
+	}
+	return path, field, nil
+}
+
+// Resolver vertaalt secret referenties naar hun waarde. Een nil Resolver
+// (geen provider geconfigureerd) laat gewone waarden door en weigert referenties.
+type Resolver struct {
+	src Source
+}
+
+func NewResolver(src Source) *Resolver {
+	if src == nil {
+		return nil
+	}
+	return &Resolver{src: src}
+}
+
+// Resolve geeft value terug, of de secret waarde als value een referentie is.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	path, field, err := ParseRef(value)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", fmt.Errorf("%s: no secrets provider configured", value)
+	}
+	data, err := r.src.Read(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("read secret %s: %w", path, err)
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %q", path, field)
+	}
+	return v, nil
+}
+
+// Read leest alle velden van path.
+func (r *Resolver) Read(ctx context.Context, path string) (map[string]string, error) {
+	if r == nil {
+		return nil, errors.New("no secrets provider configured")
+	}
+	return r.src.Read(ctx, path)
+}
+
+// Supplier geeft een functie die value telkens opnieuw resolvet, zodat een
+// geroteerd secret opgepikt wordt. Is de provider onbereikbaar, dan blijft de
+// laatst gekende waarde in gebruik.
+func (r *Resolver) Supplier(ctx context.Context, value string) func() (string, error) {
+	var mu sync.Mutex
+	var last string
+	return func() (string, error) {
+		v, err := r.Resolve(ctx, value)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if last != "" {
+				return last, nil
+			}
+			return "", err
+		}
+		last = v
+		return v, nil
+	}
+}
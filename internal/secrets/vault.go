@@ -0,0 +1,286 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultJWTFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	loginRetry     = 30 * time.Second
+)
+
+// VaultOptions komt uit de secrets.vault config sectie.
+type VaultOptions struct {
+	Address   string // leeg → VAULT_ADDR
+	Namespace string
+	Auth      string // "token" (default) of "kubernetes"
+	Token     string // leeg → VAULT_TOKEN
+	Role      string // kubernetes auth role
+	Mount     string // kubernetes auth mount, default "kubernetes"
+	JWTFile   string // service account token, default het Kubernetes pad
+	CAFile    string // CA bundle voor de Vault server
+}
+
+// Vault leest secrets via de Vault HTTP API (KV v1 en v2) en houdt het eigen
+// token geldig: renewable tokens worden verlengd, bij kubernetes auth wordt
+// opnieuw ingelogd als verlengen niet (meer) kan.
+type Vault struct {
+	opts   VaultOptions
+	client *http.Client
+	log    *zap.Logger
+
+	mu        sync.RWMutex
+	token     string
+	ttl       time.Duration
+	renewable bool
+}
+
+func NewVault(ctx context.Context, opts VaultOptions, log *zap.Logger) (*Vault, error) {
+	if opts.Address == "" {
+		opts.Address = os.Getenv("VAULT_ADDR")
+	}
+	if opts.Address == "" {
+		return nil, errors.New("secrets.vault.address (or VAULT_ADDR) is required")
+	}
+	opts.Address = strings.TrimSuffix(opts.Address, "/")
+	if opts.Auth == "" {
+		opts.Auth = "token"
+	}
+	if opts.Mount == "" {
+		opts.Mount = "kubernetes"
+	}
+	if opts.JWTFile == "" {
+		opts.JWTFile = defaultJWTFile
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read vault CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in vault CA bundle %s", opts.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	v := &Vault{
+		opts:   opts,
+		client: &http.Client{Transport: transport, Timeout: 10 * time.Second},
+		log:    log,
+	}
+	if err := v.login(ctx); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type vaultAuth struct {
+	ClientToken   string `json:"client_token"`
+	LeaseDuration int    `json:"lease_duration"`
+	Renewable     bool   `json:"renewable"`
+}
+
+// login haalt een token op (kubernetes) of controleert het gegeven token.
+func (v *Vault) login(ctx context.Context) error {
+	var resp struct {
+		Auth *vaultAuth `json:"auth"`
+		Data *struct {
+			TTL       int  `json:"ttl"`
+			Renewable bool `json:"renewable"`
+		} `json:"data"`
+	}
+
+	switch v.opts.Auth {
+	case "token":
+		token := v.opts.Token
+		if token == "" {
+			token = os.Getenv("VAULT_TOKEN")
+		}
+		if token == "" {
+			return errors.New("secrets.vault.token (or VAULT_TOKEN) is required for token auth")
+		}
+		v.setToken(token, 0, false)
+		if err := v.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &resp); err != nil {
+			return fmt.Errorf("vault token lookup: %w", err)
+		}
+		if resp.Data != nil {
+			v.setToken(token, time.Duration(resp.Data.TTL)*time.Second, resp.Data.Renewable)
+		}
+	case "kubernetes":
+		jwt, err := os.ReadFile(v.opts.JWTFile)
+		if err != nil {
+			return fmt.Errorf("read service account token: %w", err)
+		}
+		body := map[string]string{"role": v.opts.Role, "jwt": strings.TrimSpace(string(jwt))}
+		if err := v.do(ctx, http.MethodPost, "auth/"+v.opts.Mount+"/login", body, &resp); err != nil {
+			return fmt.Errorf("vault kubernetes login: %w", err)
+		}
+		if resp.Auth == nil || resp.Auth.ClientToken == "" {
+			return errors.New("vault kubernetes login returned no token")
+		}
+		v.setAuth(resp.Auth)
+	default:
+		return fmt.Errorf("unknown vault auth method %q (token or kubernetes)", v.opts.Auth)
+	}
+	return nil
+}
+
+func (v *Vault) setAuth(a *vaultAuth) {
+	v.setToken(a.ClientToken, time.Duration(a.LeaseDuration)*time.Second, a.Renewable)
+}
+
+func (v *Vault) setToken(token string, ttl time.Duration, renewable bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.token, v.ttl, v.renewable = token, ttl, renewable
+}
+
+// Read leest het secret op path, bv. "secret/data/pulsar-api" (KV v2) of
+// "secret/pulsar-api" (KV v1).
+func (v *Vault) Read(ctx context.Context, path string) (map[string]string, error) {
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, strings.TrimPrefix(path, "/"), nil, &resp); err != nil {
+		return nil, err
+	}
+
+	data := resp.Data
+	// KV v2 nest de velden onder data.data, naast data.metadata
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+	out := make(map[string]string, len(data))
+	for k, val := range data {
+		if s, ok := val.(string); ok {
+			out[k] = s
+			continue
+		}
+		out[k] = fmt.Sprint(val)
+	}
+	return out, nil
+}
+
+// Run houdt het token geldig tot ctx afloopt. Tokens zonder TTL (bv. root
+// tokens) hoeven niet verlengd te worden.
+func (v *Vault) Run(ctx context.Context) {
+	var retry time.Duration
+	for {
+		wait := retry
+		if wait == 0 {
+			v.mu.RLock()
+			ttl := v.ttl
+			v.mu.RUnlock()
+			if ttl <= 0 {
+				return
+			}
+			wait = ttl * 2 / 3 // verlengen na 2/3 van de TTL
+		}
+		retry = 0
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if v.renew(ctx) {
+			continue
+		}
+		if v.opts.Auth != "kubernetes" {
+			v.log.Error("Vault token expires and cannot be renewed, secrets will stop refreshing")
+			return
+		}
+		if err := v.login(ctx); err != nil {
+			v.log.Error("Vault re-login failed, retrying", zap.Error(err), zap.Duration("retryIn", loginRetry))
+			retry = loginRetry
+			continue
+		}
+		v.log.Info("Vault token refreshed via kubernetes login")
+	}
+}
+
+// renew verlengt een renewable token; false als dat niet kan of mislukt.
+func (v *Vault) renew(ctx context.Context) bool {
+	v.mu.RLock()
+	renewable := v.renewable
+	v.mu.RUnlock()
+	if !renewable {
+		return false
+	}
+
+	var resp struct {
+		Auth *vaultAuth `json:"auth"`
+	}
+	if err := v.do(ctx, http.MethodPost, "auth/token/renew-self", map[string]string{}, &resp); err != nil || resp.Auth == nil {
+		v.log.Warn("Vault token renewal failed", zap.Error(err))
+		return false
+	}
+	v.setAuth(resp.Auth)
+	v.log.Debug("Vault token renewed", zap.Duration("ttl", time.Duration(resp.Auth.LeaseDuration)*time.Second))
+	return true
+}
+
+func (v *Vault) currentToken() string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.token
+}
+
+func (v *Vault) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.opts.Address+"/v1/"+path, r)
+	if err != nil {
+		return err
+	}
+	if t := v.currentToken(); t != "" {
+		req.Header.Set("X-Vault-Token", t)
+	}
+	if v.opts.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.opts.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return fmt.Errorf("vault %s %s: %s %s", method, path, resp.Status, strings.Join(e.Errors, "; "))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,109 @@
+// Package sentry rapporteert panics en 5xx responses aan Sentry of GlitchTip
+// (zelfde protocol), zodat nieuwe fouten een alert geven.
+package sentry
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	sentrygo "github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+
+	"github.com/rubenclaes/pulsar-api/internal/middleware"
+)
+
+const hubKey = "sentryHub"
+
+type Options struct {
+	DSN         string // leeg = uit
+	Environment string
+	Release     string
+	SampleRate  float64
+}
+
+// Init zet de globale Sentry client op; zonder DSN doet Middleware niets.
+func Init(opts Options) error {
+	if opts.DSN == "" {
+		return nil
+	}
+	return sentrygo.Init(sentrygo.ClientOptions{
+		Dsn:         opts.DSN,
+		Environment: opts.Environment,
+		Release:     opts.Release,
+		SampleRate:  opts.SampleRate,
+		// geen headers, cookies of IP's: die kunnen API keys of signatures bevatten
+		SendDefaultPII: false,
+	})
+}
+
+// Flush wacht tot de events in de wachtrij verstuurd zijn, bv. bij het stoppen.
+func Flush(timeout time.Duration) {
+	sentrygo.Flush(timeout)
+}
+
+// Middleware rapporteert panics (en geeft ze door aan gin.Recovery, die er
+// dus vóór moet staan) en responses met een 5xx status, met correlationId,
+// route, clientId en de tags van SetTag. 503 (maintenance, concurrency
+// limiet) is een bewuste weigering en wordt niet gerapporteerd.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sentrygo.CurrentHub().Client() == nil {
+			c.Next()
+			return
+		}
+		hub := sentrygo.CurrentHub().Clone()
+		c.Set(hubKey, hub)
+		c.Request = c.Request.WithContext(sentrygo.SetHubOnContext(c.Request.Context(), hub))
+
+		defer func() {
+			if err := recover(); err != nil {
+				scope(c, hub, http.StatusInternalServerError)
+				hub.RecoverWithContext(c.Request.Context(), err)
+				panic(err)
+			}
+		}()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		if status < http.StatusInternalServerError || status == http.StatusServiceUnavailable {
+			return
+		}
+		scope(c, hub, status)
+		if err := c.Errors.Last(); err != nil {
+			hub.CaptureException(err.Err)
+		} else {
+			hub.CaptureMessage(fmt.Sprintf("%d %s", status, http.StatusText(status)))
+		}
+	}
+}
+
+// SetTag zet een tag (bv. eventType, topic) op het Sentry event van deze
+// request, als er een gerapporteerd wordt.
+func SetTag(c *gin.Context, key, value string) {
+	if v, ok := c.Get(hubKey); ok {
+		v.(*sentrygo.Hub).Scope().SetTag(key, value)
+	}
+}
+
+func scope(c *gin.Context, hub *sentrygo.Hub, status int) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	s := hub.Scope()
+	transaction := c.Request.Method + " " + route
+	s.AddEventProcessor(func(e *sentrygo.Event, _ *sentrygo.EventHint) *sentrygo.Event {
+		e.Transaction = transaction
+		return e
+	})
+	s.SetTag("correlationId", middleware.GetCorrelationID(c))
+	s.SetTag("route", route)
+	s.SetTag("method", c.Request.Method)
+	s.SetTag("status", strconv.Itoa(status))
+	if id := middleware.GetClientID(c); id != "" {
+		s.SetUser(sentrygo.User{ID: id})
+	}
+}
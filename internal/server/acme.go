@@ -0,0 +1,54 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// ACMEOptions komt uit api.tls.acme; certificaten worden automatisch bij
+// Let's Encrypt (of een andere ACME CA) aangevraagd en vernieuwd.
+type ACMEOptions struct {
+	Hosts        []string // hostnames waarvoor certificaten aangevraagd mogen worden
+	CacheDir     string   // opslag van account key en certificaten
+	Email        string
+	DirectoryURL string // leeg = Let's Encrypt productie
+}
+
+func NewACMEManager(opts ACMEOptions) (*autocert.Manager, error) {
+	if len(opts.Hosts) == 0 {
+		return nil, errors.New("api.tls.acme.hosts must list at least one hostname")
+	}
+	if opts.CacheDir == "" {
+		return nil, errors.New("api.tls.acme.cacheDir is required")
+	}
+
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(opts.Hosts...),
+		Cache:      autocert.DirCache(opts.CacheDir),
+		Email:      opts.Email,
+	}
+	if opts.DirectoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: opts.DirectoryURL}
+	}
+	return m, nil
+}
+
+// ACMETLSConfig is de autocert config, optioneel met verplichte client
+// certificaten (mTLS) uit clientCAFile.
+func ACMETLSConfig(m *autocert.Manager, clientCAFile string) (*tls.Config, error) {
+	cfg := m.TLSConfig()
+	cfg.MinVersion = tls.VersionTLS12
+	if clientCAFile != "" {
+		pool, err := loadCertPool(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
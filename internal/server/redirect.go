@@ -0,0 +1,22 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// RedirectHandler stuurt elke plain HTTP request door naar dezelfde URL over
+// HTTPS op httpsPort.
+func RedirectHandler(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// reloadDebounce groepeert de reeks fs events van één certificaat rotatie.
+const reloadDebounce = 500 * time.Millisecond
+
+// CertReloader houdt het server certificaat (en de client CA bundle) in het
+// geheugen en leest ze opnieuw in wanneer de bestanden wijzigen, zonder restart.
+type CertReloader struct {
+	opts     TLSOptions
+	log      *zap.Logger
+	load     func() (*tlsMaterial, error)
+	material atomic.Pointer[tlsMaterial]
+}
+
+func NewCertReloader(opts TLSOptions, log *zap.Logger) (*CertReloader, error) {
+	return newCertReloader(opts, log, func() (*tlsMaterial, error) {
+		return loadTLSMaterial(opts)
+	})
+}
+
+// NewSecretCertReloader haalt certificaat en key via fetch op (bv. uit Vault)
+// in plaats van uit bestanden; de client CA bundle blijft een bestand.
+// Gebruik Refresh om rotaties op te pikken.
+func NewSecretCertReloader(fetch func() (certPEM, keyPEM []byte, err error), clientCAFile string, log *zap.Logger) (*CertReloader, error) {
+	opts := TLSOptions{ClientCAFile: clientCAFile}
+	return newCertReloader(opts, log, func() (*tlsMaterial, error) {
+		certPEM, keyPEM, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return tlsMaterialFromPEM(certPEM, keyPEM, clientCAFile)
+	})
+}
+
+func newCertReloader(opts TLSOptions, log *zap.Logger, load func() (*tlsMaterial, error)) (*CertReloader, error) {
+	m, err := load()
+	if err != nil {
+		return nil, err
+	}
+	r := &CertReloader{opts: opts, log: log, load: load}
+	r.material.Store(m)
+	return r, nil
+}
+
+// TLSConfig geeft een server config die bij elke handshake het laatst
+// ingelezen certificaat en client CA bundle gebruikt. Het blijft één config
+// (via GetCertificate en VerifyConnection, geen GetConfigForClient), zodat de
+// NextProtos (h2) en session ticket keys die net/http erop zet behouden blijven.
+func (r *CertReloader) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.material.Load().cert, nil
+		},
+	}
+	if r.material.Load().clientCAs != nil {
+		// de verificatie gebeurt in VerifyConnection, tegen de actuele bundle;
+		// ook bij session resumption
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = r.verifyClient
+	}
+	return cfg
+}
+
+// verifyClient controleert het client certificaat tegen de laatst ingelezen
+// client CA bundle, zoals tls.RequireAndVerifyClientCert dat zou doen.
+func (r *CertReloader) verifyClient(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("client certificate required")
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         r.material.Load().clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("verify client certificate: %w", err)
+	}
+	return nil
+}
+
+func (r *CertReloader) reload() {
+	m, err := r.load()
+	if err != nil {
+		// oude certificaten blijven actief
+		r.log.Error("TLS reload failed, keeping current certificates", zap.Error(err))
+		return
+	}
+	if !sameCert(r.material.Swap(m), m) {
+		r.log.Info("TLS certificates reloaded", zap.String("certFile", r.opts.CertFile))
+	}
+}
+
+func sameCert(a, b *tlsMaterial) bool {
+	return a != nil && len(a.cert.Certificate) > 0 && len(b.cert.Certificate) > 0 &&
+		bytes.Equal(a.cert.Certificate[0], b.cert.Certificate[0])
+}
+
+// Refresh leest het materiaal elke interval opnieuw in tot ctx afloopt.
+func (r *CertReloader) Refresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.reload()
+		}
+	}
+}
+
+// Watch volgt de mappen van de cert, key en CA bestanden (zodat ook atomische
+// renames en Kubernetes secret updates opgepikt worden) tot ctx afloopt.
+func (r *CertReloader) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	dirs := map[string]bool{}
+	for _, f := range []string{r.opts.CertFile, r.opts.KeyFile, r.opts.ClientCAFile} {
+		if f == "" {
+			continue
+		}
+		if d := filepath.Dir(f); !dirs[d] {
+			dirs[d] = true
+			if err := w.Add(d); err != nil {
+				return err
+			}
+		}
+	}
+
+	timer := time.NewTimer(reloadDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Events:
+			timer.Reset(reloadDebounce)
+		case err := <-w.Errors:
+			r.log.Warn("TLS file watcher error", zap.Error(err))
+		case <-timer.C:
+			r.reload()
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions bevat de paden uit de api.tls config sectie.
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string // indien gezet → mTLS, client cert verplicht
+}
+
+func (o TLSOptions) Enabled() bool {
+	return o.CertFile != "" || o.KeyFile != ""
+}
+
+// tlsMaterial is alles wat bij een reload opnieuw ingelezen wordt.
+type tlsMaterial struct {
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func loadTLSMaterial(opts TLSOptions) (*tlsMaterial, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("api.tls.certFile and api.tls.keyFile are both required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+
+	m := &tlsMaterial{cert: &cert}
+	if opts.ClientCAFile != "" {
+		if m.clientCAs, err = loadCertPool(opts.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func tlsMaterialFromPEM(certPEM, keyPEM []byte, clientCAFile string) (*tlsMaterial, error) {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse server certificate: %w", err)
+	}
+
+	m := &tlsMaterial{cert: &cert}
+	if clientCAFile != "" {
+		if m.clientCAs, err = loadCertPool(clientCAFile); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA bundle %s", path)
+	}
+	return pool, nil
+}
@@ -0,0 +1,276 @@
+// Package spool bewaart events op schijf als Pulsar onbereikbaar is en
+// publiceert ze opnieuw zodra de broker terug is (outbox), zodat producers
+// geen eigen retry queue moeten bouwen.
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/rubenclaes/pulsar-api/internal/deadletter"
+	"github.com/rubenclaes/pulsar-api/internal/pulsar"
+)
+
+// ErrFull: de spool zit aan maxBytes.
+var ErrFull = errors.New("spool is full")
+
+// failedDir bevat berichten die Pulsar definitief weigerde (bv. een
+// verwijderde topic) als er geen DeadLetter store is; die worden niet
+// opnieuw geprobeerd.
+const failedDir = "failed"
+
+// Message is een event zoals het naar Pulsar moest.
+type Message struct {
+	Topic      string            `json:"topic"`
+	Cluster    string            `json:"cluster,omitempty"` // leeg = default
+	Key        string            `json:"key,omitempty"`     // message key, leeg = geen
+	Payload    []byte            `json:"payload"`
+	Properties map[string]string `json:"properties,omitempty"`
+	SpooledAt  time.Time         `json:"spooledAt"`
+}
+
+// Spool bewaart elk bericht als een apart bestand in dir, in volgorde van
+// aankomst. Een bestand wordt pas na een geslaagde send verwijderd, dus een
+// bericht kan (na een crash of een timeout) twee keer aankomen.
+type Spool struct {
+	dir      string
+	maxBytes int64 // 0 = onbeperkt
+
+	mu    sync.Mutex
+	size  int64
+	count int
+	seq   uint64
+
+	DeadLetter *deadletter.Store // nil = geweigerde berichten naar failed/
+}
+
+// New opent (of maakt) de spool in dir. Berichten van een vorige run blijven
+// staan en worden door Run verstuurd.
+func New(dir string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Join(dir, failedDir), 0o700); err != nil {
+		return nil, err
+	}
+	s := &Spool{dir: dir, maxBytes: maxBytes}
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if fi, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			s.size += fi.Size()
+			s.count++
+		}
+	}
+	// half geschreven bestanden van een crash
+	tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	for _, t := range tmp {
+		_ = os.Remove(t)
+	}
+	return s, nil
+}
+
+// Len geeft het aantal berichten dat nog verstuurd moet worden.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Size geeft het aantal bytes in de spool en het maximum (0 = onbeperkt).
+func (s *Spool) Size() (size, maxBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size, s.maxBytes
+}
+
+// Put schrijft m naar schijf (fsync, daarna pas zichtbaar voor Run).
+func (s *Spool) Put(m Message) error {
+	m.SpooledAt = time.Now().UTC()
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if s.maxBytes > 0 && s.size+int64(len(data)) > s.maxBytes {
+		s.mu.Unlock()
+		return ErrFull
+	}
+	s.size += int64(len(data))
+	s.count++
+	s.seq++
+	name := fmt.Sprintf("%020d-%010d.json", m.SpooledAt.UnixNano(), s.seq)
+	s.mu.Unlock()
+
+	if err := writeFile(filepath.Join(s.dir, name), data); err != nil {
+		s.removed(int64(len(data)))
+		return err
+	}
+	return nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+	}
+	return err
+}
+
+func (s *Spool) removed(size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size -= size
+	s.count--
+}
+
+// list geeft de bestandsnamen van de berichten, oudste eerst.
+func (s *Spool) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Run verstuurt de berichten via pub, meteen en daarna om de interval, tot
+// ctx afloopt. Zolang Pulsar onbereikbaar is, blijven ze staan.
+func (s *Spool) Run(ctx context.Context, pub pulsar.Publisher, interval time.Duration, log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.drain(ctx, pub, log)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Spool) drain(ctx context.Context, pub pulsar.Publisher, log *zap.Logger) {
+	names, err := s.list()
+	if err != nil {
+		log.Error("Failed to read spool", zap.Error(err))
+		return
+	}
+	for _, name := range names {
+		if ctx.Err() != nil {
+			return
+		}
+		path := filepath.Join(s.dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Error("Failed to read spooled event", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			s.fail(name, int64(len(data)), err, log)
+			continue
+		}
+
+		id, err := pub.Send(ctx, m.Topic, m.Payload, pulsar.SendOptions{Properties: m.Properties, Cluster: m.Cluster, Key: m.Key})
+		if err != nil {
+			if pulsar.IsUnavailable(err) || ctx.Err() != nil {
+				// broker nog niet terug: de rest ook niet proberen
+				log.Debug("Pulsar still unavailable, keeping spooled events", zap.Int("events", s.Len()), zap.Error(err))
+				return
+			}
+			s.fail(name, int64(len(data)), err, log)
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Error("Failed to remove relayed event from spool", zap.String("file", name), zap.Error(err))
+			continue
+		}
+		s.removed(int64(len(data)))
+		log.Info("Spooled event sent to Pulsar",
+			zap.String("correlationId", m.Properties[pulsar.CorrelationIDProperty]),
+			zap.String("topic", m.Topic),
+			zap.String("messageId", string(id)),
+			zap.Duration("delay", time.Since(m.SpooledAt)),
+		)
+	}
+}
+
+// fail verplaatst een bericht dat Pulsar weigert naar de DeadLetter store of
+// naar failed/.
+func (s *Spool) fail(name string, size int64, err error, log *zap.Logger) {
+	if s.DeadLetter != nil && s.deadLetter(name, err, log) {
+		s.removed(size)
+		return
+	}
+	log.Error("Spooled event rejected, moved to "+failedDir, zap.String("file", name), zap.Error(err))
+	if err := os.Rename(filepath.Join(s.dir, name), filepath.Join(s.dir, failedDir, name)); err != nil {
+		log.Error("Failed to move spooled event", zap.String("file", name), zap.Error(err))
+		return
+	}
+	s.removed(size)
+}
+
+// deadLetter zet een geweigerd bericht in de DeadLetter store, met de payload
+// (het oorspronkelijke event) als request.
+func (s *Spool) deadLetter(name string, sendErr error, log *zap.Logger) bool {
+	path := filepath.Join(s.dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Error("Failed to read spooled event", zap.String("file", name), zap.Error(err))
+		return false
+	}
+	e := deadletter.Entry{Reason: sendErr.Error()}
+	var m Message
+	if json.Unmarshal(data, &m) == nil && json.Valid(m.Payload) {
+		var req struct {
+			EventType string `json:"eventType"`
+		}
+		_ = json.Unmarshal(m.Payload, &req)
+		e.CorrelationID = m.Properties[pulsar.CorrelationIDProperty]
+		e.EventType, e.Cluster, e.Topic, e.Request = req.EventType, m.Cluster, m.Topic, m.Payload
+	} else {
+		// onleesbaar bestand: als string bewaren
+		e.Request, _ = json.Marshal(string(data))
+	}
+	id, err := s.DeadLetter.Put(e)
+	if err != nil {
+		log.Error("Failed to dead-letter spooled event", zap.String("file", name), zap.Error(err))
+		return false
+	}
+	if err := os.Remove(path); err != nil {
+		log.Error("Failed to remove dead-lettered event from spool", zap.String("file", name), zap.Error(err))
+		return false
+	}
+	log.Error("Spooled event rejected, dead-lettered", zap.String("file", name), zap.String("deadLetterId", id), zap.Error(sendErr))
+	return true
+}
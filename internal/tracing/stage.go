@@ -0,0 +1,25 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Stage start een child span "event.<name>" voor een stap van de publish
+// pipeline (bind, validate, enrich, marshal, send), zodat zichtbaar is welke
+// stap de latency veroorzaakt. Sluit af met End.
+func Stage(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, "event."+name, trace.WithAttributes(attrs...))
+}
+
+// End sluit een span af, met err als fout (nil = geslaagd).
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
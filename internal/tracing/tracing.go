@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/rubenclaes/pulsar-api"
+
+// Options komt uit de tracing config sectie.
+type Options struct {
+	Enabled     bool
+	Endpoint    string // OTLP/HTTP collector, bv. "otel-collector:4318"
+	Insecure    bool   // plain HTTP naar de collector
+	ServiceName string
+	SampleRatio float64 // 0..1, enkel voor root spans
+}
+
+// Init zet de globale tracer provider en W3C propagator op. Zonder tracing
+// blijft de no-op provider actief, maar inkomende traceparent headers worden
+// wel doorgegeven. De teruggegeven functie flusht en stopt de exporter.
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if !opts.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	clientOpts := []otlptracehttp.Option{}
+	if opts.Endpoint != "" {
+		clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+	}
+	if opts.Insecure {
+		clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := Resource(opts.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
+
+// Resource beschrijft de service, gedeeld door traces en logs.
+func Resource(serviceName string) (*resource.Resource, error) {
+	return resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}